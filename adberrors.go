@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Exit codes for classified adb failures. Other adb failures exit with
+// adb's own exit status.
+const (
+	ExitError            = 1  // Generic failure
+	ExitADBNotFound      = 10 // adb is not installed or not on PATH
+	ExitNoDevices        = 11 // No device or emulator is attached
+	ExitUnauthorized     = 12 // The device has not authorized this computer
+	ExitPermissionDenied = 13 // Access to the device or its logs was denied
+	ExitMultipleDevices  = 14 // Several devices are attached and none was selected
+	ExitDeviceOffline    = 15 // The device is attached but offline
+)
+
+// ADBError is a classified adb failure with an actionable hint
+type ADBError struct {
+	Message  string // What went wrong
+	Hint     string // What the user can do about it
+	ExitCode int    // Process exit code to report
+	Err      error  // Underlying error
+}
+
+func (e *ADBError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *ADBError) Unwrap() error { return e.Err }
+
+// adbFailures maps stderr substrings to failure classes, checked in order
+var adbFailures = []struct {
+	marker   string
+	message  string
+	hint     string
+	exitCode int
+}{
+	{"no devices", "no device connected", "Connect a device or start an emulator, then check `adb devices`.", ExitNoDevices},
+	{"no emulators", "no emulator running", "Start an emulator, then check `adb devices`.", ExitNoDevices},
+	{"not found", "device not found", "Check the serial with `adb devices -l`.", ExitNoDevices},
+	{"unauthorized", "device unauthorized", "Accept the USB debugging prompt on the device; if it does not appear, revoke USB debugging authorizations and reconnect.", ExitUnauthorized},
+	{"insufficient permissions", "insufficient permissions for the USB device", "Add a udev rule for the device or join the plugdev group, then reconnect.", ExitPermissionDenied},
+	{"permission denied", "logcat permission denied", "The device refused access to its logs; check that USB debugging is enabled and the build allows logcat access.", ExitPermissionDenied},
+	{"more than one", "more than one device attached", "Select a device with -d SERIAL, -e or -transport.", ExitMultipleDevices},
+	{"offline", "device offline", "Reconnect the device or run `adb reconnect`.", ExitDeviceOffline},
+}
+
+// classifyADBError turns an error from running adb, together with the lines
+// adb wrote to stderr, into an ADBError
+func classifyADBError(err error, stderr []string) *ADBError {
+	if errors.Is(err, exec.ErrNotFound) {
+		return &ADBError{
+			Message:  "adb not found",
+			Hint:     "Install the Android SDK platform-tools and make sure adb is on your PATH.",
+			ExitCode: ExitADBNotFound,
+			Err:      err,
+		}
+	}
+
+	output := strings.ToLower(strings.Join(stderr, "\n"))
+	for _, f := range adbFailures {
+		if strings.Contains(output, f.marker) {
+			return &ADBError{Message: f.message, Hint: f.hint, ExitCode: f.exitCode, Err: err}
+		}
+	}
+
+	exitCode := ExitError
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		exitCode = exitErr.ExitCode()
+	}
+	return &ADBError{Message: "adb failed", ExitCode: exitCode, Err: err}
+}
+
+// reportError prints err to stderr, with a hint for classified adb
+// failures, and returns the exit code to use
+func reportError(err error) int {
+	var adbErr *ADBError
+	if errors.As(err, &adbErr) {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error: %s\n", adbErr.Message))
+		if adbErr.Hint != "" {
+			fmt.Fprint(os.Stderr, LogLevelColors["W"]("%s\n", adbErr.Hint))
+		}
+		return adbErr.ExitCode
+	}
+	fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error %v\n", err))
+	return ExitError
+}
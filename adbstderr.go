@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// ADBNoticeColor is the color function for adb daemon notices
+var ADBNoticeColor = color.New(color.FgCyan).SprintfFunc()
+
+// adbErrorMarkers are substrings marking adb stderr lines as errors
+var adbErrorMarkers = []string{
+	"error:",
+	"offline",
+	"unauthorized",
+	"no devices",
+	"no emulators",
+	"not found",
+	"more than one",
+	"insufficient permissions",
+	"failed",
+	"cannot connect",
+	"protocol fault",
+}
+
+// colorizeADBMessage colors a line adb wrote to stderr by what it reports
+func colorizeADBMessage(line string) string {
+	lower := strings.ToLower(line)
+	switch {
+	case strings.HasPrefix(line, "* daemon") || strings.Contains(lower, "adb server"):
+		return ADBNoticeColor("%s", line)
+	case strings.Contains(lower, "waiting for"):
+		return LogLevelColors["W"]("%s", line)
+	}
+	for _, marker := range adbErrorMarkers {
+		if strings.Contains(lower, marker) {
+			return LogLevelColors["E"]("%s", line)
+		}
+	}
+	return line
+}
+
+// maxStderrLines is how many recent stderr lines are kept for classifying failures
+const maxStderrLines = 20
+
+// stderrCapture forwards a command's stderr and keeps its last lines
+type stderrCapture struct {
+	done  chan struct{}
+	lines []string
+}
+
+// pipeStderr forwards the command's stderr to ours with colorized adb
+// diagnostics. It must be called before the command starts, and Wait must be
+// called before the command's Wait.
+func pipeStderr(cmd *exec.Cmd) (*stderrCapture, error) {
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &stderrCapture{done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			line := scanner.Text()
+			fmt.Fprintln(os.Stderr, colorizeADBMessage(line))
+			c.lines = append(c.lines, line)
+			if len(c.lines) > maxStderrLines {
+				c.lines = c.lines[1:]
+			}
+		}
+	}()
+	return c, nil
+}
+
+// Wait waits until stderr is drained and returns its last lines
+func (c *stderrCapture) Wait() []string {
+	<-c.done
+	return c.lines
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// benchTags are the tags of synthetic benchmark lines
+var benchTags = []string{"ActivityManager", "WindowManager", "art", "OpenGLRenderer", "SurfaceFlinger", "chatty", "MyApp", "NetworkSecurityConfig"}
+
+// benchWords fill the messages of synthetic benchmark lines
+var benchWords = strings.Fields("the quick brown fox jumps over lazy dog start stop bind service window focus frame 0x7f3a2c user=10 pid=1234 took 16ms")
+
+// benchLevels weight the levels of synthetic benchmark lines like a
+// typical device
+const benchLevels = "VVDDDDDIIIIIIIIWWE"
+
+// syntheticLine returns a threadtime line logged at t with a message of
+// about length bytes
+func syntheticLine(rng *rand.Rand, t time.Time, length int) string {
+	var msg strings.Builder
+	for msg.Len() < length {
+		if msg.Len() > 0 {
+			msg.WriteByte(' ')
+		}
+		msg.WriteString(benchWords[rng.IntN(len(benchWords))])
+	}
+	pid := 1000 + rng.IntN(50)
+	return fmt.Sprintf("%s %5d %5d %c %-8s: %s", t.Format("01-02 15:04:05.000"), pid, pid+rng.IntN(3),
+		benchLevels[rng.IntN(len(benchLevels))], benchTags[rng.IntN(len(benchTags))], msg.String())
+}
+
+// throttledReader delivers the lines of r at a fixed rate
+type throttledReader struct {
+	r     *bytes.Reader
+	start time.Time
+	rate  int // Lines per second
+	lines int
+}
+
+// Read returns one line at a time, waiting until it is due
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if t.start.IsZero() {
+		t.start = time.Now()
+	}
+	if due := t.start.Add(time.Duration(t.lines) * time.Second / time.Duration(t.rate)); time.Now().Before(due) {
+		time.Sleep(time.Until(due))
+	}
+	n := 0
+	for n < len(p) {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		p[n] = b
+		n++
+		if b == '\n' {
+			t.lines++
+			break
+		}
+	}
+	return n, nil
+}
+
+// runBench measures how fast synthetic lines are parsed, filtered and
+// rendered, with the other flags applied as they would be to a device
+func runBench(ctx context.Context, opts *LogcatOptions) error {
+	if opts.BenchLines <= 0 || opts.BenchLength <= 0 {
+		return fmt.Errorf("-bench-lines and -bench-length must be positive")
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	var input bytes.Buffer
+	t := time.Date(0, 4, 19, 12, 0, 0, 0, time.UTC)
+	for range opts.BenchLines {
+		t = t.Add(time.Duration(rng.IntN(5000)) * time.Microsecond)
+		input.WriteString(syntheticLine(rng, t, opts.BenchLength))
+		input.WriteByte('\n')
+	}
+	size := input.Len()
+
+	var r io.Reader = bytes.NewReader(input.Bytes())
+	if opts.BenchRate > 0 {
+		r = &throttledReader{r: bytes.NewReader(input.Bytes()), rate: opts.BenchRate}
+	}
+
+	// Render with colors to nowhere so the terminal does not limit the speed
+	noColor := color.NoColor
+	color.NoColor = false
+	opts.Output = io.Discard
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	err := colorizeLines(r, opts, nil)
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	color.NoColor = noColor
+	if err != nil {
+		return err
+	}
+
+	n := float64(opts.BenchLines)
+	fmt.Printf("%s %d (average %d bytes)\n", BannerLabelColor("%-11s", "Lines:"), opts.BenchLines, size/opts.BenchLines)
+	fmt.Printf("%s %v\n", BannerLabelColor("%-11s", "Elapsed:"), elapsed.Round(time.Millisecond))
+	fmt.Printf("%s %.0f lines/s, %.1f MB/s\n", BannerLabelColor("%-11s", "Throughput:"), n/elapsed.Seconds(), float64(size)/elapsed.Seconds()/1e6)
+	fmt.Printf("%s %v, %.1f allocations, %.0f bytes allocated\n", BannerLabelColor("%-11s", "Per line:"),
+		time.Duration(float64(elapsed)/n), float64(after.Mallocs-before.Mallocs)/n, float64(after.TotalAlloc-before.TotalAlloc)/n)
+	return nil
+}
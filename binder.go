@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// BinderColor highlights binder failures
+var BinderColor = color.New(color.FgHiWhite, color.BgBlue).SprintfFunc()
+
+// binderFailure matches the messages of failed binder calls, capturing the
+// kind of failure
+var binderFailure = regexp.MustCompile(`(!!! FAILED BINDER TRANSACTION !!!|DeadObjectException|TransactionTooLargeException)`)
+
+// parcelSize matches the parcel size given with a failed transaction, as in
+// "(parcel size = 1048756)" or "data parcel size 1048756 bytes"
+var parcelSize = regexp.MustCompile(`parcel size (?:= )?(\d+)`)
+
+// binderLimit is the size of a process's binder transaction buffer, which
+// a parcel must fit in
+const binderLimit = 1 << 20
+
+// binderKind returns the kind of binder failure a message reports, if any,
+// and the parcel size it gives, or 0
+func binderKind(message string) (string, int) {
+	m := binderFailure.FindStringSubmatch(message)
+	if m == nil {
+		return "", 0
+	}
+	size := 0
+	if s := parcelSize.FindStringSubmatch(message); s != nil {
+		size, _ = strconv.Atoi(s[1])
+	}
+	return strings.Trim(m[1], "! "), size
+}
+
+// describeParcel describes a failed transaction's parcel size relative to
+// the binder buffer
+func describeParcel(size int) string {
+	text := fmt.Sprintf("parcel %.1f KB", float64(size)/1024)
+	if size >= binderLimit {
+		text = fmt.Sprintf("parcel %.2f MB", float64(size)/binderLimit)
+	}
+	switch {
+	case size > binderLimit:
+		text += ", over the 1 MB binder buffer"
+	case size >= binderLimit/2:
+		text += fmt.Sprintf(", %d%% of the 1 MB binder buffer", size*100/binderLimit)
+	}
+	return text
+}
+
+// BinderStats counts binder failures for the summary printed at exit
+type BinderStats struct {
+	mu      sync.Mutex
+	counts  map[string]int
+	largest int // Largest parcel size seen
+}
+
+// newBinderStats returns empty statistics
+func newBinderStats() *BinderStats {
+	return &BinderStats{counts: make(map[string]int)}
+}
+
+// Observe counts line if it reports a binder failure
+func (s *BinderStats) Observe(line string) {
+	entry, ok := parseLogLine(line)
+	if !ok {
+		return
+	}
+	kind, size := binderKind(entry.Message)
+	if kind == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[kind]++
+	s.largest = max(s.largest, size)
+}
+
+// Write prints the counts, if there were any failures
+func (s *BinderStats) Write(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.counts) == 0 {
+		return
+	}
+	total := 0
+	var kinds []string
+	for _, kind := range slices.Sorted(maps.Keys(s.counts)) {
+		total += s.counts[kind]
+		kinds = append(kinds, fmt.Sprintf("%d %s", s.counts[kind], kind))
+	}
+	summary := fmt.Sprintf("%d binder failures: %s", total, strings.Join(kinds, ", "))
+	if s.largest > 0 {
+		summary += "; largest " + describeParcel(s.largest)
+	}
+	fmt.Fprintln(w, BinderColor(" %s ", summary))
+}
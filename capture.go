@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Magic numbers identifying compressed captures
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// multiCloser closes a compressor and then the file under it
+type multiCloser struct {
+	io.Writer
+	closers []io.Closer
+}
+
+// Close closes each closer in order, returning the first error
+func (m multiCloser) Close() error {
+	var first error
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// CaptureFile is a capture being written, which starts with a metadata
+// header written along with the first line
+type CaptureFile struct {
+	w       io.WriteCloser
+	started bool
+}
+
+// WriteLine writes a raw line to the capture
+func (c *CaptureFile) WriteLine(line string, opts LogcatOptions) error {
+	if !c.started {
+		c.started = true
+		if err := writeCaptureHeader(c.w, opts); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(c.w, line+"\n")
+	return err
+}
+
+// Close finishes and closes the capture
+func (c *CaptureFile) Close() error {
+	return c.w.Close()
+}
+
+// createCapture creates a capture file, compressing it with gzip or zstd
+// when the name ends in .gz or .zst
+func createCapture(path string) (*CaptureFile, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		zw := gzip.NewWriter(f)
+		return &CaptureFile{w: multiCloser{zw, []io.Closer{zw, f}}}, nil
+	case strings.HasSuffix(path, ".zst"):
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &CaptureFile{w: multiCloser{zw, []io.Closer{zw, f}}}, nil
+	}
+	return &CaptureFile{w: f}, nil
+}
+
+// decompress returns a reader that transparently decompresses r if it
+// starts with a gzip or zstd header
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(4)
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		// Concatenated gzip members, as produced by appending, are read in turn
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(header, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return br, nil
+}
+
+// expandInputs replaces directories and glob patterns in names with the
+// files they contain, ordering each group by the first timestamp in each
+// file so that rotated captures are read oldest first
+func expandInputs(names []string) ([]string, error) {
+	var expanded []string
+	for _, name := range names {
+		var group []string
+		if info, err := os.Stat(name); err == nil && info.IsDir() {
+			entries, err := os.ReadDir(name)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") {
+					group = append(group, filepath.Join(name, e.Name()))
+				}
+			}
+		} else if name != "-" && strings.ContainsAny(name, "*?[") {
+			matches, err := filepath.Glob(name)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", name)
+			}
+			group = matches
+		} else {
+			expanded = append(expanded, name)
+			continue
+		}
+
+		starts := make(map[string]time.Time, len(group))
+		for _, path := range group {
+			starts[path] = firstTimestamp(path)
+		}
+		sort.SliceStable(group, func(i, j int) bool { return starts[group[i]].Before(starts[group[j]]) })
+		expanded = append(expanded, group...)
+	}
+	return expanded, nil
+}
+
+// firstTimestamp returns the time of the first timestamped line in a
+// capture, or the zero time if it has none
+func firstTimestamp(path string) time.Time {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		return time.Time{}
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if t, err := parseTimestamp(scanner.Text()); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
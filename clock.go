@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// clockSamples is the number of date readings taken to measure a device's
+// clock, keeping the one with the shortest round trip
+const clockSamples = 3
+
+// measureClockOffset returns how far the device's clock is ahead of the
+// host's, measured with adb shell date
+func measureClockOffset(opts LogcatOptions) (time.Duration, error) {
+	var best time.Duration
+	bestRTT := time.Duration(-1)
+	for range clockSamples {
+		start := time.Now()
+		out, err := adbCommand(opts, "shell", "date", "+%s.%N").Output()
+		rtt := time.Since(start)
+		if err != nil {
+			return 0, err
+		}
+		device, err := parseEpoch(strings.TrimSpace(string(out)))
+		if err != nil {
+			return 0, err
+		}
+		if bestRTT < 0 || rtt < bestRTT {
+			bestRTT = rtt
+			best = device.Sub(start.Add(rtt / 2))
+		}
+	}
+	return best, nil
+}
+
+// parseEpoch parses the seconds since the epoch printed by date +%s.%N.
+// Devices whose date lacks %N print it literally, leaving whole seconds.
+func parseEpoch(s string) (time.Time, error) {
+	secs, frac, _ := strings.Cut(s, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected date output %q", s)
+	}
+	nsec := int64(0)
+	if n, err := strconv.ParseInt((frac + "000000000")[:9], 10, 64); err == nil {
+		nsec = n
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// formatOffset formats a clock offset with an explicit sign, rounded to
+// milliseconds
+func formatOffset(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	if d < 0 {
+		return d.String()
+	}
+	return "+" + d.String()
+}
+
+// shiftTimestamp moves the timestamp of a log line by d, keeping its
+// precision. Lines without a timestamp are returned unchanged.
+func shiftTimestamp(line string, d time.Duration) string {
+	if d == 0 {
+		return line
+	}
+	t, err := parseTimestamp(line)
+	if err != nil {
+		return line
+	}
+	fields := strings.Fields(line)
+	end := strings.Index(line, fields[1]) + len(fields[1])
+	layout := "01-02 15:04:05"
+	if _, frac, ok := strings.Cut(fields[1], "."); ok {
+		layout += "." + strings.Repeat("0", len(frac))
+	}
+	start := strings.Index(line, fields[0])
+	return line[:start] + t.Add(d).Format(layout) + line[end:]
+}
+
+// shiftLines returns a reader producing the lines of r with their
+// timestamps moved by d
+func shiftLines(r io.Reader, d time.Duration) io.Reader {
+	if d == 0 {
+		return r
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		w := bufio.NewWriter(pw)
+		for scanner.Scan() {
+			w.WriteString(shiftTimestamp(scanner.Text(), d))
+			w.WriteByte('\n')
+		}
+		if err := scanner.Err(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(w.Flush())
+	}()
+	return pr
+}
+
+// headerClockOffset returns the device clock offset recorded in a capture
+// header, if any
+func headerClockOffset(header [][2]string) (time.Duration, bool) {
+	for _, field := range header {
+		if field[0] == "clock-offset" {
+			d, err := time.ParseDuration(field[1])
+			return d, err == nil
+		}
+	}
+	return 0, false
+}
+
+// markerTime returns the time of the first line of r whose message matches
+// the sync marker
+func markerTime(r io.Reader, marker *regexp.Regexp) (time.Time, bool) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if entry, ok := parseLogLine(scanner.Text()); ok && marker.MatchString(entry.Message) {
+			return entry.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// markerOffsets finds the -sync-marker event in each capture and returns
+// how far each one's clock is ahead of the first's, assuming the markers
+// happened at the same moment. Captures without the marker are not moved.
+func markerOffsets(opts *LogcatOptions) ([]time.Duration, error) {
+	readers, closeAll, err := openInputs(opts.Args, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer closeAll()
+
+	offsets := make([]time.Duration, len(readers))
+	var reference time.Time
+	for i, r := range readers {
+		t, ok := markerTime(r, opts.SyncMarker)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Input %d has no line matching -sync-marker; leaving its times unchanged\n", i+1)
+			continue
+		}
+		if reference.IsZero() {
+			reference = t
+		}
+		offsets[i] = t.Sub(reference)
+	}
+	return offsets, nil
+}
+
+// clockCheckInterval is how often -host-time re-measures the device clock
+const clockCheckInterval = time.Minute
+
+// clockDriftReport is how far the device clock must drift from the last
+// reported offset before the new offset is reported
+const clockDriftReport = 100 * time.Millisecond
+
+// HostClock converts device timestamps to host time for -host-time,
+// re-measuring the device clock periodically
+type HostClock struct {
+	zone  time.Duration // Host UTC offset minus the device's
+	shift atomic.Int64  // Added to device times to give host times
+}
+
+// startHostClock measures the device clock and time zone and keeps the
+// measurement current while streaming
+func startHostClock(opts LogcatOptions) (*HostClock, error) {
+	offset, err := measureClockOffset(opts)
+	if err != nil {
+		return nil, err
+	}
+	out, err := adbCommand(opts, "shell", "date", "+%z").Output()
+	if err != nil {
+		return nil, err
+	}
+	device, err := time.Parse("-0700", strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected date output %q", strings.TrimSpace(string(out)))
+	}
+	_, deviceZone := device.Zone()
+	_, hostZone := time.Now().Zone()
+
+	c := &HostClock{zone: time.Duration(hostZone-deviceZone) * time.Second}
+	c.shift.Store(int64(c.zone - offset))
+	go func() {
+		reported := offset
+		for range time.Tick(clockCheckInterval) {
+			offset, err := measureClockOffset(opts)
+			if err != nil {
+				continue
+			}
+			c.shift.Store(int64(c.zone - offset))
+			if (offset - reported).Abs() >= clockDriftReport {
+				fmt.Fprintf(os.Stderr, "Device clock is now %s from the host\n", formatOffset(offset))
+				reported = offset
+			}
+		}
+	}()
+	return c, nil
+}
+
+// Convert rewrites the timestamp of a device log line in host time
+func (c *HostClock) Convert(line string) string {
+	return shiftTimestamp(line, time.Duration(c.shift.Load()))
+}
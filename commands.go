@@ -0,0 +1,652 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// command is a logcatcolor subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, opts *LogcatOptions) error
+}
+
+// commands lists the subcommands; running without one is the same as watch
+var commands = []command{
+	{"watch", "Stream and colorize logcat from a device (default)", runWatch},
+	{"dump", "Colorize the current log buffers and exit", runDump},
+	{"record", "Stream like watch while saving a session directory (-session NAME)", runRecord},
+	{"replay", "Colorize saved captures (or stdin)", runReplay},
+	{"merge", "Interleave several captures in timestamp order", runMerge},
+	{"query", "Print lines from captures matching -t, -l and -grep", runQuery},
+	{"export", "Export captures as a standalone HTML page with a filter bar (-html PATH)", runExport},
+	{"diff", "Show the lines present in only one of two captures", runDiff},
+	{"index", "Index captures so query and replay -from skip what cannot match", runIndex},
+	{"bench", "Measure parsing and rendering speed on synthetic lines (-bench-lines, -bench-rate)", runBench},
+	{"demo", "Colorize a realistic fake logcat stream, no device needed (-demo-rate)", runDemo},
+	{"daemon", "Capture all devices to rotated files in the background (daemon [start|status|stop], -detach)", runDaemon},
+	{"service", "Capture like daemon as a Windows service (service install|uninstall|start|stop)", runServiceCommand},
+	{"devices", "List attached devices and their states", runDevices},
+	{"init", "Sample the log, show the busiest tags and levels, and build and save a filter profile", runInit},
+	{"history", "List recent invocations; history N [flags] runs one again, like --last for the newest", runHistory},
+	{"presets", "List the built-in and configured presets, or print one (presets show NAME)", runPresets},
+	{"dumpsys", "Colorize adb shell dumpsys [service] (-section, -seek to page)", runDumpsys},
+	{"stats-device", "Show logd buffer sizes and statistics (logcat -g and -S)", runStatsDevice},
+	{"bugreport", "Colorize the logs in a bugreport zip/txt, or capture a new one", runBugreport},
+}
+
+// findCommand returns the subcommand with the given name
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// runWatch streams logcat from the device, restarting it with -k
+func runWatch(ctx context.Context, opts *LogcatOptions) error {
+	if len(opts.Devices) > 0 {
+		return runMultiWatch(ctx, opts)
+	}
+
+	configChanged := mergeChanges(watchConfig(ctx, opts.ConfigPath), readPresetToggles(*opts))
+
+	if opts.AVD != "" {
+		serial, err := bootAVD(ctx, *opts, opts.AVD)
+		if err != nil {
+			return err
+		}
+		opts.Device, opts.Transport = serial, ""
+	}
+
+	if opts.Wait {
+		if err := waitForDevice(ctx, *opts); err != nil {
+			return err
+		}
+	}
+
+	// Pick one of several attached devices when none was given
+	selection := ""
+	if opts.Device == "" && opts.Transport == "" {
+		transport, reason, err := selectDevice(*opts, opts.Selection)
+		if err != nil {
+			return err
+		}
+		opts.Transport, selection = transport, reason
+	}
+
+	// Describe the device before streaming, and to -output, which records its
+	// serial; failures surface from logcat itself
+	if opts.Banner || opts.Sinks != nil || opts.Tray != nil {
+		if info, err := queryDeviceInfo(*opts); err == nil {
+			info.Selection = selection
+			opts.DeviceInfo = &info
+			if opts.Banner {
+				// An -output on stdout must get only its records
+				w := io.Writer(os.Stdout)
+				if opts.SinksOnly {
+					w = os.Stderr
+				}
+				printBanner(w, info)
+			}
+		}
+	}
+
+	if opts.HostTime {
+		if clock, err := startHostClock(*opts); err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["W"]("Showing device times; measuring the device clock failed: %v\n", err))
+		} else {
+			opts.HostClock = clock
+		}
+	}
+
+	if opts.RootCapture {
+		enableRootCapture(opts)
+	}
+
+	if opts.Session != nil {
+		opts.Session.Start(*opts)
+	}
+
+	if opts.UIDColumn {
+		prepareUIDColumn(opts)
+	}
+
+	if opts.Split != nil {
+		device := *opts
+		opts.Split.ADB = func(args ...string) *exec.Cmd { return adbCommand(device, args...) }
+	}
+
+	// Let the device drop non-matching lines when its logcat can, unless
+	// they are recorded or counted first; matching still happens here too,
+	// so older devices simply send everything
+	if opts.Grep != nil && canPushDownGrep(opts.Grep.String()) && !opts.seesUnfilteredLines() {
+		opts.GrepOnDevice = supportsRegexFilter(*opts)
+	}
+
+	if opts.BufferSize != "" {
+		if err := resizeBuffers(*opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.TraceSync {
+		startTraceSync(ctx, *opts)
+	}
+
+	clear := opts.Clear
+	for {
+		// Start each session with empty buffers when asked to
+		if clear {
+			if err := clearBuffers(*opts); err != nil {
+				return err
+			}
+			clear = opts.ClearEach
+		}
+
+		// Start adb logcat
+		stream, err := adbSource{*opts}.Open(ctx)
+		if err != nil {
+			return err
+		}
+		if opts.Tray != nil {
+			opts.Tray.SetDevice(cmp.Or(deviceSerial(*opts), "device"))
+		}
+
+		// Read and display logs in real-time
+		if err := colorizeLines(stream, opts, configChanged); err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error reading logcat output: %v\n", err))
+		}
+
+		// Wait for adb to finish, reporting any failure
+		if opts.Tray != nil {
+			opts.Tray.SetDevice("")
+		}
+		if err := stream.Close(); err != nil {
+			if !opts.KeepGoing || opts.Dump {
+				return err
+			}
+			reportError(err)
+		}
+
+		// Exit if keep-going is not enabled or we were interrupted
+		if !opts.KeepGoing || opts.Dump || ctx.Err() != nil {
+			return nil
+		}
+
+		// Add a small delay before restarting to prevent rapid restart loops
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+		if opts.Wait {
+			if err := waitForDevice(ctx, *opts); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "adb logcat exited, restarting...\n")
+	}
+}
+
+// runDump colorizes the existing log buffers and exits
+func runDump(ctx context.Context, opts *LogcatOptions) error {
+	opts.Dump = true
+	return runWatch(ctx, opts)
+}
+
+// colorizeLines prints each line read from r in color until EOF, applying
+// configuration changes between lines
+func colorizeLines(r io.Reader, opts *LogcatOptions, configChanged <-chan struct{}) error {
+	var state streamState
+	var dumpRequests <-chan os.Signal
+	if opts.Ring != nil {
+		dumpRequests = opts.Ring.DumpRequests()
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// Apply configuration changes between lines without restarting adb
+		select {
+		case <-configChanged:
+			if reloaded, err := reloadConfig(*opts); err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error reloading config: %v\n", err))
+			} else {
+				*opts = reloaded
+				fmt.Fprintf(os.Stderr, "Configuration reloaded from %s\n", opts.ConfigPath)
+			}
+		default:
+		}
+
+		if opts.Ring != nil {
+			opts.Ring.DumpOnRequest(dumpRequests, opts.OutDir, *opts)
+		}
+		if err := handleLine(scanner.Text(), *opts, &state); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// streamState is what printColoredLog remembers about the previous line of
+// a stream
+type streamState struct {
+	lastTag   string
+	lastTime  time.Time
+	lastOther string
+}
+
+// handleLine passes a line read from a device to the recorders and
+// statistics, then prints it in color
+func handleLine(line string, opts LogcatOptions, state *streamState) error {
+	raw := redact(line, opts.Redactions) // -raw keeps colors and control characters, not secrets
+	line = prepareLine(line, opts)
+	if opts.Capture != nil {
+		if err := opts.Capture.WriteLine(line, opts); err != nil {
+			return fmt.Errorf("writing capture: %w", err)
+		}
+	}
+	if opts.Session != nil {
+		opts.Session.Observe(line)
+	}
+	if opts.Ring != nil {
+		opts.Ring.Add(line)
+		if opts.RingTrigger != nil && opts.RingTrigger.MatchString(line) {
+			opts.Ring.Trigger(opts.OutDir, opts)
+		}
+	}
+	if opts.Incidents != nil {
+		opts.Incidents.Observe(line, opts)
+	}
+	if opts.Latency != nil {
+		opts.Latency.Observe(line)
+	}
+	if opts.Report != nil {
+		opts.Report.Observe(line, opts)
+	}
+	if opts.Exceptions != nil {
+		opts.Exceptions.Observe(line)
+	}
+	if opts.Binder != nil {
+		opts.Binder.Observe(line)
+	}
+	if opts.Stats != nil {
+		opts.Stats.Observe(line, opts)
+	}
+	if opts.Tray != nil {
+		opts.Tray.Observe(line)
+	}
+	if opts.Suggest != nil {
+		opts.Suggest.Observe(line)
+	}
+	if opts.Split != nil {
+		if err := opts.Split.Write(line); err != nil {
+			return fmt.Errorf("writing split files: %w", err)
+		}
+		if opts.SplitOnly {
+			return nil
+		}
+	}
+	if opts.ANRDir != "" && opts.Root.Available() && isANR(line) {
+		go pullANRTraces(opts, opts.ANRDir)
+	}
+	if opts.Quiet {
+		printQuietIncident(line, opts)
+		return nil
+	}
+	if opts.HostClock != nil {
+		line = opts.HostClock.Convert(line)
+	}
+	if opts.Net || opts.Jobs != nil {
+		entry, ok := parseLogLine(line)
+		network, job := ok && opts.Net && isNetworkLine(entry), ok && opts.Jobs != nil && isJobLine(entry)
+		if !network && !job {
+			countLine(opts)
+			return nil
+		}
+		if network {
+			printNetworkEvent(entry, opts)
+		}
+		if job {
+			opts.Jobs.Observe(entry, opts)
+		}
+	}
+	opts.RawLine = raw
+	state.lastTag, state.lastTime, state.lastOther = printColoredLog(line, state.lastTag, state.lastTime, state.lastOther, opts)
+	return nil
+}
+
+// openInputs opens the named capture files, or stdin if there are none or
+// the name is "-", decompressing gzip and zstd captures and skipping their
+// metadata headers, which are described on stderr with -banner. Captures
+// indexed by the index command only read the blocks that may match opts.
+// Directories and globs are expanded as by expandInputs. The returned
+// function closes them. With -skew, times are moved to the host clock using
+// the offset recorded in each header.
+func openInputs(names []string, opts *LogcatOptions) ([]io.Reader, func(), error) {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	names, err := expandInputs(names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var readers []io.Reader
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, name := range names {
+		f := os.Stdin
+		if name != "-" {
+			var err error
+			if f, err = os.Open(name); err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			files = append(files, f)
+		}
+		var r io.Reader = f
+		if idx, ok := loadIndex(name); ok && f != os.Stdin {
+			r = indexedReader(f, idx, *opts)
+		}
+		r, err := decompress(r)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		br := bufio.NewReader(r)
+		header := readCaptureHeader(br)
+		if header != nil && opts.Banner {
+			printCaptureHeader(name, header)
+		}
+		r = br
+		if offset, ok := headerClockOffset(header); ok && opts.Skew {
+			r = shiftLines(br, -offset)
+		}
+		readers = append(readers, r)
+	}
+	return readers, closeAll, nil
+}
+
+// runReplay colorizes saved captures one after another
+func runReplay(ctx context.Context, opts *LogcatOptions) error {
+	readers, closeAll, err := openInputs(opts.Args, opts)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	opts.LocalFilter = true
+	return colorizeCaptures(ctx, io.MultiReader(readers...), opts)
+}
+
+// runMerge colorizes several captures interleaved by timestamp
+func runMerge(ctx context.Context, opts *LogcatOptions) error {
+	var offsets []time.Duration
+	if opts.SyncMarker != nil {
+		var err error
+		if offsets, err = markerOffsets(opts); err != nil {
+			return err
+		}
+	}
+	readers, closeAll, err := openInputs(opts.Args, opts)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+	for i, offset := range offsets {
+		if offset != 0 {
+			fmt.Fprintf(os.Stderr, "Input %d is %s from the first at -sync-marker; correcting\n", i+1, formatOffset(offset))
+			readers[i] = shiftLines(readers[i], -offset)
+		}
+	}
+
+	opts.LocalFilter = true
+	return colorizeCaptures(ctx, mergeLogs(readers), opts)
+}
+
+// colorizeCaptures colorizes saved captures inside the -from/-to window,
+// paging through them with -seek
+func colorizeCaptures(ctx context.Context, r io.Reader, opts *LogcatOptions) error {
+	if !opts.Window.From.IsZero() || !opts.Window.To.IsZero() {
+		r = windowLines(r, opts.Window)
+	}
+	if opts.Trace != "" {
+		events, err := loadTraceEvents(opts.Trace, opts.TraceNames)
+		if err != nil {
+			return err
+		}
+		r = traceAnnotations(r, events)
+	}
+	if opts.Seek {
+		return replaySeek(r, opts)
+	}
+	return colorizeLines(r, opts, watchConfig(ctx, opts.ConfigPath))
+}
+
+// runQuery prints the lines of saved captures matching the tag, level and
+// message filters, each with its full timestamp
+func runQuery(ctx context.Context, opts *LogcatOptions) error {
+	if opts.Tag == "" && opts.Level == "" && opts.Grep == nil && opts.Filter == nil {
+		return fmt.Errorf("query needs at least one of -t, -l, -grep or -filter")
+	}
+	opts.MaxDelta = 0
+	return runMerge(ctx, opts)
+}
+
+// mergeLogs returns a reader producing the lines of readers in timestamp
+// order. Lines without a timestamp stay with the line before them.
+func mergeLogs(readers []io.Reader) io.Reader {
+	type source struct {
+		scanner *bufio.Scanner
+		lines   []string // Next entry: a timestamped line and its continuation lines
+		time    time.Time
+		pending string // Timestamped line read past the current entry
+		done    bool
+	}
+
+	// advance reads the next entry of a source
+	advance := func(s *source) {
+		s.lines = nil
+		if s.pending != "" {
+			s.time, _ = parseTimestamp(s.pending)
+			s.lines = append(s.lines, s.pending)
+			s.pending = ""
+		}
+		for s.scanner.Scan() {
+			line := s.scanner.Text()
+			if t, err := parseTimestamp(line); err == nil {
+				if len(s.lines) > 0 {
+					s.pending = line
+					return
+				}
+				s.time = t
+			}
+			s.lines = append(s.lines, line)
+		}
+		if len(s.lines) == 0 {
+			s.done = true
+		}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		sources := make([]*source, len(readers))
+		for i, r := range readers {
+			s := &source{scanner: bufio.NewScanner(r)}
+			s.scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+			advance(s)
+			sources[i] = s
+		}
+
+		w := bufio.NewWriter(pw)
+		for {
+			var next *source
+			for _, s := range sources {
+				if !s.done && (next == nil || s.time.Before(next.time)) {
+					next = s
+				}
+			}
+			if next == nil {
+				break
+			}
+			for _, line := range next.lines {
+				w.WriteString(line)
+				w.WriteByte('\n')
+			}
+			advance(next)
+		}
+		pw.CloseWithError(w.Flush())
+	}()
+	return pr
+}
+
+// deviceStateColors colors device states reported by adb devices
+var deviceStateColors = map[string]func(format string, a ...any) string{
+	"device":       color.New(color.FgGreen).SprintfFunc(),
+	"host":         color.New(color.FgCyan).SprintfFunc(),
+	"recovery":     color.New(color.FgYellow).SprintfFunc(),
+	"rescue":       color.New(color.FgYellow).SprintfFunc(),
+	"sideload":     color.New(color.FgYellow).SprintfFunc(),
+	"bootloader":   color.New(color.FgYellow).SprintfFunc(),
+	"authorizing":  color.New(color.FgYellow).SprintfFunc(),
+	"connecting":   color.New(color.FgYellow).SprintfFunc(),
+	"unauthorized": color.New(color.FgRed).SprintfFunc(),
+	"offline":      color.New(color.FgRed).SprintfFunc(),
+	"no":           color.New(color.FgRed).SprintfFunc(), // "no permissions"
+	"unknown":      color.New(color.FgRed).SprintfFunc(),
+}
+
+// runDevices lists attached devices with their states colored
+func runDevices(ctx context.Context, opts *LogcatOptions) error {
+	devices, err := listDevices(*opts)
+	if err != nil {
+		return err
+	}
+
+	serialColor := color.New(color.Bold).SprintfFunc()
+	for _, d := range devices {
+		state, _, _ := strings.Cut(d.State, " ")
+		stateColor, ok := deviceStateColors[state]
+		if !ok {
+			stateColor = fmt.Sprintf
+		}
+		fmt.Printf("%-24s %s %s\n", serialColor("%s", d.Serial), stateColor("%-12s", d.State), d.Details)
+	}
+	return nil
+}
+
+// bugreportLogSection matches the dumpstate headers of logcat sections
+var bugreportLogSection = regexp.MustCompile(`^------ .*LOG \(logcat .*\) ------$`)
+
+// runBugreport colorizes the log sections of a bugreport. Without a file
+// argument it captures a new bugreport from the device first.
+func runBugreport(ctx context.Context, opts *LogcatOptions) error {
+	path := ""
+	if len(opts.Args) > 0 {
+		path = opts.Args[0]
+	} else {
+		dir, err := os.MkdirTemp("", "logcatcolor-bugreport")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		fmt.Fprintln(os.Stderr, "Capturing bugreport, this may take a few minutes...")
+		cmd := adbCommand(*opts, "bugreport", dir)
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return classifyADBError(err, nil)
+		}
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.zip"))
+		if len(matches) == 0 {
+			return fmt.Errorf("adb bugreport produced no zip file")
+		}
+		path = matches[0]
+	}
+
+	r, closeReport, err := openBugreport(path)
+	if err != nil {
+		return err
+	}
+	defer closeReport()
+
+	// Pass only the logcat sections through to the colorizer
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		inLog := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case bugreportLogSection.MatchString(line):
+				inLog = true
+			case strings.HasPrefix(line, "------ "):
+				inLog = false
+				continue
+			case !inLog:
+				continue
+			}
+			w.WriteString(line)
+			w.WriteByte('\n')
+		}
+		if err := scanner.Err(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(w.Flush())
+	}()
+
+	opts.LocalFilter = true
+	return colorizeLines(pr, opts, nil)
+}
+
+// openBugreport opens a bugreport text file, or the main text entry of a bugreport zip
+func openBugreport(path string) (io.Reader, func(), error) {
+	if !strings.HasSuffix(strings.ToLower(path), ".zip") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, func() { f.Close() }, nil
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, "bugreport") && strings.HasSuffix(f.Name, ".txt") {
+			rc, err := f.Open()
+			if err != nil {
+				zr.Close()
+				return nil, nil, err
+			}
+			return rc, func() { rc.Close(); zr.Close() }, nil
+		}
+	}
+	zr.Close()
+	return nil, nil, fmt.Errorf("no bugreport text found in %s", path)
+}
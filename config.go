@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/erdichen/logcatcolor/logcat"
+)
+
+// Config is the contents of the JSON configuration file
+type Config struct {
+	Redact []RedactConfig `json:"redact"` // Custom redaction rules, applied in order
+	Remap  []RemapConfig  `json:"remap"`  // Severity remapping rules, first match wins
+	Rules  []RuleConfig   `json:"rules"`  // Highlight rules, first match wins
+
+	Tags    map[string]TagConfig `json:"tags"`    // Per-tag overrides keyed by tag
+	Aliases map[string]string    `json:"aliases"` // Display names keyed by tag
+
+	Levels map[string]StyleConfig `json:"levels"` // Styles keyed by level letter
+
+	Profiles map[string]ProfileConfig `json:"profiles"` // Named profiles activated with -p
+}
+
+// ProfileConfig is a named bundle of flags and settings. Its settings are
+// merged over the top-level ones and its rules take precedence.
+type ProfileConfig struct {
+	Description string   `json:"description"` // One line describing the profile
+	Args        []string `json:"args"`        // Flags applied before those on the command line
+	Config
+}
+
+// RedactConfig is a user-defined redaction rule
+type RedactConfig struct {
+	Pattern string `json:"pattern"` // Regular expression to mask
+	Replace string `json:"replace"` // Replacement template, e.g. "<host>" or "${1}-xxx"
+}
+
+// RemapConfig is a user-defined severity remapping rule
+type RemapConfig struct {
+	Tag   string `json:"tag"`   // Tag to match, empty for any tag
+	Match string `json:"match"` // Message regular expression, empty for any message
+	From  string `json:"from"`  // Level to match, empty for any level
+	To    string `json:"to"`    // Level to assign
+}
+
+// RuleConfig is a user-defined highlight rule
+type RuleConfig struct {
+	Match  string      `json:"match"`  // Message regular expression, empty for any message
+	Tag    string      `json:"tag"`    // Tag to match, empty for any tag
+	Level  string      `json:"level"`  // Level to match, empty for any level
+	Style  StyleConfig `json:"style"`  // Style applied to the message
+	Action string      `json:"action"` // One of "hide", "raise" or "notify"
+	To     string      `json:"to"`     // Level assigned by "raise", default one level higher
+
+	When    string `json:"when"`    // Script that must return true, e.g. `int(group(1)) > 200`
+	Rewrite string `json:"rewrite"` // Script computing the message to display
+}
+
+// defaultConfigPath returns the path of the configuration file used when -config is not given
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "logcatcolor", "config.json")
+}
+
+// LoadConfig reads the configuration file at path. A missing file yields an
+// empty configuration unless required is set.
+func LoadConfig(path string, required bool) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// RedactionRules compiles the user-defined redaction rules
+func (c *Config) RedactionRules() ([]RedactionRule, error) {
+	rules := make([]RedactionRule, 0, len(c.Redact))
+	for _, r := range c.Redact {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", r.Pattern, err)
+		}
+		rules = append(rules, RedactionRule{Pattern: re, Replacement: r.Replace})
+	}
+	return rules, nil
+}
+
+// SeverityRules compiles the severity remapping rules
+func (c *Config) SeverityRules() ([]SeverityRule, error) {
+	rules := make([]SeverityRule, 0, len(c.Remap))
+	for _, r := range c.Remap {
+		rule := SeverityRule{Tag: r.Tag, From: strings.ToUpper(r.From), To: strings.ToUpper(r.To)}
+		if _, ok := LogLevelColors[rule.To]; !ok {
+			return nil, fmt.Errorf("invalid remap level %q", r.To)
+		}
+		if _, ok := LogLevelColors[rule.From]; rule.From != "" && !ok {
+			return nil, fmt.Errorf("invalid remap level %q", r.From)
+		}
+		if r.Match != "" {
+			re, err := regexp.Compile(r.Match)
+			if err != nil {
+				return nil, fmt.Errorf("invalid remap pattern %q: %w", r.Match, err)
+			}
+			rule.Pattern = re
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// HighlightRules compiles the highlight rules
+func (c *Config) HighlightRules() ([]HighlightRule, error) {
+	rules := make([]HighlightRule, 0, len(c.Rules))
+	for _, r := range c.Rules {
+		rule := HighlightRule{
+			Tag:     r.Tag,
+			Level:   strings.ToUpper(r.Level),
+			Action:  RuleAction(strings.ToLower(r.Action)),
+			RaiseTo: strings.ToUpper(r.To),
+		}
+		switch rule.Action {
+		case ActionNone, ActionHide, ActionRaise, ActionNotify:
+		default:
+			return nil, fmt.Errorf("invalid rule action %q", r.Action)
+		}
+		if _, ok := LogLevelColors[rule.RaiseTo]; rule.RaiseTo != "" && !ok {
+			return nil, fmt.Errorf("invalid rule level %q", r.To)
+		}
+		if r.Match != "" {
+			re, err := regexp.Compile(r.Match)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule pattern %q: %w", r.Match, err)
+			}
+			rule.Pattern = re
+		}
+		for _, script := range []struct {
+			src string
+			dst **logcat.Script
+		}{{r.When, &rule.When}, {r.Rewrite, &rule.Rewrite}} {
+			if script.src == "" {
+				continue
+			}
+			compiled, err := logcat.CompileScript(script.src)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule: %w", err)
+			}
+			*script.dst = compiled
+		}
+		style, err := r.Style.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule style: %w", err)
+		}
+		rule.Style = style
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// TagOverrides compiles the per-tag overrides
+func (c *Config) TagOverrides() (map[string]TagOverride, error) {
+	overrides := make(map[string]TagOverride, len(c.Tags))
+	for tag, t := range c.Tags {
+		o := TagOverride{Hide: t.Hide, MinLevel: strings.ToUpper(t.MinLevel)}
+		if _, ok := LogLevelColors[o.MinLevel]; o.MinLevel != "" && !ok {
+			return nil, fmt.Errorf("invalid minLevel %q for tag %s", t.MinLevel, tag)
+		}
+		if t.Color != "" {
+			style, err := StyleConfig{Fg: t.Color}.Compile()
+			if err != nil {
+				return nil, fmt.Errorf("invalid color for tag %s: %w", tag, err)
+			}
+			o.Color = style
+		}
+		overrides[tag] = o
+	}
+	return overrides, nil
+}
+
+// ApplyLevelStyles sets the entries of LogLevelColors from the configured
+// level styles. Styles without a foreground keep the level's default color,
+// and levels without a style are reset to their defaults.
+func (c *Config) ApplyLevelStyles() error {
+	styles := make(map[string]StyleConfig, len(LogLevelColors))
+	for level, style := range c.Levels {
+		level = strings.ToUpper(level)
+		if _, ok := LogLevelColors[level]; !ok {
+			return fmt.Errorf("invalid level %q in levels", level)
+		}
+		styles[level] = style
+	}
+
+	for level := range LogLevelColors {
+		style := styles[level]
+		if style.Fg == "" {
+			style.Fg = defaultLevelFg[level]
+		}
+		colorFunc, err := style.Compile()
+		if err != nil {
+			return fmt.Errorf("invalid style for level %s: %w", level, err)
+		}
+		LogLevelColors[level] = colorFunc
+	}
+	return nil
+}
+
+// ApplyProfile merges a profile's settings into the configuration. The
+// profile's rules come first, in new slices so that its own are not written.
+func (c *Config) ApplyProfile(p ProfileConfig) {
+	c.Redact = append(c.Redact, p.Redact...)
+	c.Remap = slices.Concat(p.Remap, c.Remap)
+	c.Rules = slices.Concat(p.Rules, c.Rules)
+	c.Tags = mergeMap(c.Tags, p.Tags)
+	c.Aliases = mergeMap(c.Aliases, p.Aliases)
+	c.Levels = mergeMap(c.Levels, p.Levels)
+}
+
+// mergeMap copies src over dst, allocating dst if needed
+func mergeMap[V any](dst, src map[string]V) map[string]V {
+	if dst == nil {
+		dst = make(map[string]V, len(src))
+	}
+	maps.Copy(dst, src)
+	return dst
+}
+
+// Apply compiles the configuration into opts and applies level styles
+func (c *Config) Apply(opts *LogcatOptions) error {
+	redactions, err := c.RedactionRules()
+	if err != nil {
+		return err
+	}
+	opts.Redactions = append(opts.Redactions, redactions...)
+
+	if opts.Severities, err = c.SeverityRules(); err != nil {
+		return err
+	}
+	if opts.Rules, err = c.HighlightRules(); err != nil {
+		return err
+	}
+	if opts.Tags, err = c.TagOverrides(); err != nil {
+		return err
+	}
+	opts.Aliases = c.Aliases
+	return c.ApplyLevelStyles()
+}
+
+// editConfig rewrites the configuration file at path with the changes edit
+// makes to its top-level settings, keeping the others as they are
+func editConfig(path string, edit func(settings map[string]json.RawMessage) error) error {
+	settings := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("parsing config %s: %w", path, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	if err := edit(settings); err != nil {
+		return fmt.Errorf("editing config %s: %w", path, err)
+	}
+
+	data, err = json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// editSetting changes the JSON object stored under key in settings with
+// edit, creating it if missing
+func editSetting(settings map[string]json.RawMessage, key string, edit func(object map[string]json.RawMessage) error) error {
+	object := make(map[string]json.RawMessage)
+	if data, ok := settings[key]; ok {
+		if err := json.Unmarshal(data, &object); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if err := edit(object); err != nil {
+		return err
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	settings[key] = data
+	return nil
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// daemonPollInterval is how often the daemon looks for attached devices
+const daemonPollInterval = 5 * time.Second
+
+// daemonSocket and daemonLog are the names of the control socket and of the
+// detached daemon's own log in the capture directory
+const (
+	daemonSocket = "daemon.sock"
+	daemonLog    = "daemon.log"
+)
+
+// DaemonStatus is what the control socket reports
+type DaemonStatus struct {
+	Started time.Time      `json:"started"`
+	PID     int            `json:"pid"`
+	Dir     string         `json:"dir"`
+	Devices []DaemonDevice `json:"devices"`
+}
+
+// DaemonDevice is the capture state of one device
+type DaemonDevice struct {
+	Serial    string    `json:"serial"`
+	State     string    `json:"state"` // "capturing" or "disconnected"
+	Lines     int       `json:"lines"`
+	File      string    `json:"file"`
+	Size      int64     `json:"size"` // Of the current file
+	LastLine  time.Time `json:"lastLine"`
+	Crashes   int       `json:"crashes"`
+	LastCrash string    `json:"lastCrash,omitempty"`
+}
+
+// daemonDevice is a device the daemon captures from
+type daemonDevice struct {
+	DaemonDevice
+	log       *RotatingFile
+	ring      *RingBuffer
+	incidents *IncidentRecorder
+}
+
+// Daemon captures every selected device into rotated files under a
+// directory, extracting crashes and raising alerts, and answers status
+// requests on a control socket
+type Daemon struct {
+	opts    LogcatOptions
+	started time.Time
+	mu      sync.Mutex
+	devices map[string]*daemonDevice
+	wg      sync.WaitGroup
+	lastErr string // Last device listing error, reported once
+}
+
+// newDaemon creates the capture directory
+func newDaemon(opts LogcatOptions) (*Daemon, error) {
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Daemon{opts: opts, started: time.Now(), devices: make(map[string]*daemonDevice)}, nil
+}
+
+// Run captures until ctx is canceled or a stop request arrives
+func (d *Daemon) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ln, err := listenControl(filepath.Join(d.opts.OutDir, daemonSocket))
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	go d.serve(ln, cancel)
+	fmt.Fprintf(os.Stderr, "Daemon capturing to %s (pid %d)\n", d.opts.OutDir, os.Getpid())
+
+	for ctx.Err() == nil {
+		d.startDevices(ctx)
+		select {
+		case <-ctx.Done():
+		case <-time.After(daemonPollInterval):
+		}
+	}
+	d.wg.Wait()
+	for _, dev := range d.devices {
+		if dev.incidents != nil {
+			dev.incidents.Close()
+		}
+		dev.log.Close()
+	}
+	fmt.Fprintln(os.Stderr, "Daemon stopped")
+	return nil
+}
+
+// startDevices starts capturing from selected devices that have attached
+func (d *Daemon) startDevices(ctx context.Context) {
+	attached, err := listDevices(d.opts)
+	if err != nil {
+		if err.Error() != d.lastErr {
+			reportError(err)
+		}
+		d.lastErr = err.Error()
+		return
+	}
+	d.lastErr = ""
+
+	all := len(d.opts.Devices) == 0 || slices.Equal(d.opts.Devices, []string{"all"})
+	for _, a := range attached {
+		if a.State != "device" || (!all && !slices.Contains(d.opts.Devices, a.Serial)) {
+			continue
+		}
+		d.mu.Lock()
+		dev, ok := d.devices[a.Serial]
+		if ok && dev.State == "capturing" {
+			d.mu.Unlock()
+			continue
+		}
+		if !ok {
+			if dev, err = d.newDevice(a.Serial); err != nil {
+				d.mu.Unlock()
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error setting up capture for %s: %v\n", a.Serial, err))
+				continue
+			}
+			d.devices[a.Serial] = dev
+		}
+		dev.State = "capturing"
+		d.mu.Unlock()
+
+		fmt.Fprintf(os.Stderr, "%s Capturing %s to %s\n", time.Now().Format(time.DateTime), a.Serial, dev.File)
+		d.wg.Add(1)
+		go d.capture(ctx, dev)
+	}
+}
+
+// newDevice opens the capture files of a device in a directory named
+// after its serial
+func (d *Daemon) newDevice(serial string) (*daemonDevice, error) {
+	dir := filepath.Join(d.opts.OutDir, strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(serial))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "logcat.log")
+	log, err := openRotating(path, d.opts.RotateSize, d.opts.RotateKeep)
+	if err != nil {
+		return nil, err
+	}
+	dev := &daemonDevice{DaemonDevice: DaemonDevice{Serial: serial, File: path}, log: log}
+	if d.opts.Ring != nil {
+		dev.ring = newRingBuffer(len(d.opts.Ring.lines))
+	}
+	if r := d.opts.Incidents; r != nil {
+		dev.incidents = &IncidentRecorder{BeforeLines: r.BeforeLines, BeforeTime: r.BeforeTime, AfterLines: r.AfterLines, dir: dir}
+	}
+	return dev, nil
+}
+
+// capture streams one device into its files until adb logcat exits
+func (d *Daemon) capture(ctx context.Context, dev *daemonDevice) {
+	defer d.wg.Done()
+
+	opts := d.opts
+	opts.Device, opts.Transport, opts.Ring = dev.Serial, "", dev.ring
+	stream, err := adbSource{opts}.Open(ctx)
+	if err == nil {
+		scanner := bufio.NewScanner(stream)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			d.observe(dev, prepareLine(scanner.Text(), opts), opts)
+		}
+		err = stream.Close()
+	}
+
+	d.mu.Lock()
+	dev.State = "disconnected"
+	d.mu.Unlock()
+	if ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "%s Capture of %s ended", time.Now().Format(time.DateTime), dev.Serial)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, ": %v", err)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// observe saves a line of a device, extracting and alerting on crashes
+func (d *Daemon) observe(dev *daemonDevice, line string, opts LogcatOptions) {
+	if err := dev.log.WriteLine(line); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error writing %s: %v\n", dev.File, err))
+	}
+	if dev.ring != nil {
+		dev.ring.Add(line)
+	}
+	if dev.incidents != nil {
+		dev.incidents.Observe(line, opts)
+	}
+
+	entry, ok := parseLogLine(line)
+	kind := ""
+	if ok {
+		kind = crashKind(entry)
+	}
+	d.mu.Lock()
+	dev.Lines++
+	dev.LastLine = time.Now()
+	if kind != "" {
+		dev.Crashes++
+		dev.LastCrash = fmt.Sprintf("%s %s: %s", time.Now().Format(time.DateTime), kind, entry.Message)
+	}
+	d.mu.Unlock()
+
+	if kind != "" {
+		incident := ""
+		if dev.incidents != nil && dev.incidents.file != nil {
+			incident = dev.incidents.path
+		}
+		d.alert(dev.Serial, kind, entry.Message, incident)
+	}
+}
+
+// alert reports a crash in the daemon log, as a desktop notification and
+// through the -alert-exec command
+func (d *Daemon) alert(serial, kind, summary, incident string) {
+	fmt.Fprintf(os.Stderr, "%s %s on %s: %s\n", time.Now().Format(time.DateTime), kind, serial, summary)
+	notify(fmt.Sprintf("%s on %s", kind, serial), summary)
+
+	fields := strings.Fields(d.opts.AlertExec)
+	if len(fields) == 0 {
+		return
+	}
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Env = append(os.Environ(), "LOGCATCOLOR_SERIAL="+serial, "LOGCATCOLOR_KIND="+kind, "LOGCATCOLOR_SUMMARY="+summary, "LOGCATCOLOR_INCIDENT="+incident)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	go func() {
+		if err := cmd.Run(); err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error running -alert-exec: %v\n", err))
+		}
+	}()
+}
+
+// Status returns the state of the daemon and its devices
+func (d *Daemon) Status() DaemonStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := DaemonStatus{Started: d.started, PID: os.Getpid(), Dir: d.opts.OutDir, Devices: []DaemonDevice{}}
+	for _, dev := range d.devices {
+		s := dev.DaemonDevice
+		s.Size = dev.log.Size()
+		status.Devices = append(status.Devices, s)
+	}
+	slices.SortFunc(status.Devices, func(a, b DaemonDevice) int { return strings.Compare(a.Serial, b.Serial) })
+	return status
+}
+
+// listenControl listens on the control socket, replacing a stale socket
+// left by a daemon that did not exit cleanly
+func listenControl(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already capturing to %s", filepath.Dir(path))
+	}
+	os.Remove(path)
+	return net.Listen("unix", path)
+}
+
+// serve answers control requests, one command per connection: status,
+// which returns a DaemonStatus as JSON, or stop
+func (d *Daemon) serve(ln net.Listener, stop context.CancelFunc) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			command, _ := bufio.NewReader(conn).ReadString('\n')
+			switch strings.TrimSpace(command) {
+			case "status":
+				json.NewEncoder(conn).Encode(d.Status())
+			case "stop":
+				fmt.Fprintln(conn, "stopping")
+				stop()
+			default:
+				fmt.Fprintf(conn, "unknown command %q\n", strings.TrimSpace(command))
+			}
+		}()
+	}
+}
+
+// daemonRequest sends a command to the daemon capturing to dir and returns
+// its answer
+func daemonRequest(dir, command string) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", filepath.Join(dir, daemonSocket), 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("no daemon is capturing to %s", dir)
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(conn)
+}
+
+// printDaemonStatus prints a status answer
+func printDaemonStatus(data []byte) error {
+	var status DaemonStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("invalid status from daemon: %w", err)
+	}
+	fmt.Printf("%s %s (pid %d), capturing to %s\n", BannerLabelColor("Running since"), BannerValueColor("%s", status.Started.Format(time.DateTime)), status.PID, status.Dir)
+	if len(status.Devices) == 0 {
+		fmt.Println("No devices attached yet")
+	}
+	for _, dev := range status.Devices {
+		state := LogLevelColors["I"]("%-12s", dev.State)
+		if dev.State != "capturing" {
+			state = LogLevelColors["W"]("%-12s", dev.State)
+		}
+		fmt.Printf("  %-20s %s %9d lines %8s  %s\n", dev.Serial, state, dev.Lines, fmt.Sprintf("%.1f MB", float64(dev.Size)/1e6), dev.File)
+		if dev.Crashes > 0 {
+			fmt.Printf("  %-20s %s\n", "", LogLevelColors["E"]("%d crashes, last %s", dev.Crashes, dev.LastCrash))
+		}
+	}
+	return nil
+}
+
+// detachDaemon starts the daemon again in the background, without -detach,
+// logging to daemon.log in the capture directory
+func detachDaemon(opts LogcatOptions) error {
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return err
+	}
+	logPath := filepath.Join(opts.OutDir, daemonLog)
+	log, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	args := slices.DeleteFunc(slices.Clone(os.Args[1:]), func(arg string) bool {
+		return slices.Contains([]string{"-detach", "--detach", "-detach=true", "--detach=true"}, arg)
+	})
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Stdout, cmd.Stderr = log, log
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Daemon started (pid %d), logging to %s\n", cmd.Process.Pid, logPath)
+	return cmd.Process.Release()
+}
+
+// runDaemon captures from devices in the background: "daemon" or
+// "daemon start" runs the capture, "daemon status" and "daemon stop" talk to
+// a running daemon through its control socket
+func runDaemon(ctx context.Context, opts *LogcatOptions) error {
+	action := "start"
+	if len(opts.Args) > 0 {
+		action = opts.Args[0]
+	}
+	switch action {
+	case "start":
+		if opts.Detach {
+			return detachDaemon(*opts)
+		}
+		d, err := newDaemon(*opts)
+		if err != nil {
+			return err
+		}
+		return d.Run(ctx)
+	case "status":
+		data, err := daemonRequest(opts.OutDir, "status")
+		if err != nil {
+			return err
+		}
+		return printDaemonStatus(data)
+	case "stop":
+		data, err := daemonRequest(opts.OutDir, "stop")
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(os.Stderr, string(data))
+		return nil
+	}
+	return fmt.Errorf("unknown daemon action %q, must be start, status or stop", action)
+}
+
+// runServiceCommand manages the Windows service, which captures like the
+// daemon: "service install" registers it to start with Windows using the
+// given flags, "service start", "stop" and "uninstall" control it, and
+// "service run" is how Windows starts it. "daemon status" reports on it.
+func runServiceCommand(ctx context.Context, opts *LogcatOptions) error {
+	if len(opts.Args) == 0 {
+		return fmt.Errorf("missing service action, must be install, uninstall, start, stop or run")
+	}
+	switch action := opts.Args[0]; action {
+	case "install":
+		return installService(*opts)
+	case "run":
+		return runService(*opts)
+	case "start", "stop", "uninstall":
+		return controlService(action)
+	default:
+		return fmt.Errorf("unknown service action %q, must be install, uninstall, start, stop or run", action)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/erdichen/logcatcolor/logcat"
+	"github.com/fatih/color"
+)
+
+// NewDayColor is the color of the separator printed when the date changes
+var NewDayColor = color.New(color.FgHiWhite, color.Bold).SprintfFunc()
+
+// lastShownDay tracks the date of the last line printed in each stream, by
+// prefix
+var lastShownDay = make(map[string]time.Time)
+
+// printDayChange prints a separator before a line logged on another day
+// than the line printed before it in the stream, reporting whether it did
+func printDayChange(entry logLine, opts LogcatOptions) bool {
+	day := time.Date(0, entry.Time.Month(), entry.Time.Day(), 0, 0, 0, 0, time.UTC)
+	last, seen := lastShownDay[opts.Prefix]
+	lastShownDay[opts.Prefix] = day
+	if !seen || day.Equal(last) {
+		return false
+	}
+	label := day.Format("01-02")
+	if opts.ShowDate {
+		label = fmt.Sprintf("%d-%s", logcat.InferYear(entry.Time, time.Now()), label)
+	}
+	fmt.Fprintln(opts.out(), opts.Prefix+NewDayColor("── new day: %s ──", label))
+	return true
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// deltaColumnWidth is the width of the -delta-column
+const deltaColumnWidth = 8
+
+// DeltaScale colors the -delta-column by magnitude, from the shortest gaps
+// to the longest
+var DeltaScale = []struct {
+	Below time.Duration
+	Color func(format string, a ...any) string
+}{
+	{10 * time.Millisecond, color.New(color.Faint).SprintfFunc()},
+	{100 * time.Millisecond, color.New(color.FgGreen).SprintfFunc()},
+	{time.Second, color.New(color.FgYellow).SprintfFunc()},
+	{10 * time.Second, color.New(color.FgRed).SprintfFunc()},
+	{1<<63 - 1, color.New(color.FgHiRed, color.Bold).SprintfFunc()},
+}
+
+// formatDeltaColumn formats a gap to fit the -delta-column
+func formatDeltaColumn(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "<0"
+	case d < time.Millisecond:
+		return fmt.Sprintf("+%dµs", d.Microseconds())
+	case d < time.Second:
+		return fmt.Sprintf("+%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("+%.3fs", d.Seconds())
+	case d < time.Hour:
+		return "+" + d.Truncate(time.Second).String()
+	}
+	return ">1h"
+}
+
+// deltaColumn returns the -delta-column for a line: the time since the
+// previous line with the same delta key, right-aligned and colored by size.
+// The first line of each key leaves the column blank.
+func deltaColumn(entry logLine, opts LogcatOptions) string {
+	key := deltaKey(entry, opts)
+	if key == "" {
+		key = "*"
+	}
+	// Streams of different devices are timed separately
+	key = opts.Prefix + key
+
+	last, seen := lastTagTime[key]
+	lastTagTime[key] = entry.Time
+	if !seen {
+		return fmt.Sprintf("%*s", deltaColumnWidth, "")
+	}
+	d := entry.Time.Sub(last)
+	for _, scale := range DeltaScale {
+		if d < scale.Below {
+			return scale.Color("%*s", deltaColumnWidth, formatDeltaColumn(d))
+		}
+	}
+	return formatDeltaColumn(d)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// demoPackage is the app whose life the demo stream follows
+const demoPackage = "com.example.demo"
+
+// demoRoutine are ordinary lines of the demo stream: level, tag and message,
+// in which {pkg}, {n} and {ms} are replaced by the package, a count and a
+// duration
+var demoRoutine = [][3]string{
+	{"V", "WindowManager", "Relayout Window{8c1e2a0 u0 {pkg}/{pkg}.MainActivity}: viewVisibility=0"},
+	{"D", "OpenGLRenderer", "endAllActiveAnimators on 0x7b4c2a1e00 (RippleDrawable) with handle 0x7b3ef1c2a0"},
+	{"D", "ConnectivityService", "NetworkAgentInfo [WIFI () - 101] validation passed"},
+	{"D", "DemoRepository", "Loaded {n} items from cache in {ms}ms"},
+	{"I", "ActivityTaskManager", "Displayed {pkg}/.MainActivity: +{ms}ms"},
+	{"I", "Choreographer", "Skipped {n} frames!  The application may be doing too much work on its main thread."},
+	{"I", "DemoSync", "Sync finished: {n} uploaded, 0 failed"},
+	{"I", "chatty", "uid=10123({pkg}) RenderThread identical {n} lines"},
+	{"W", "DemoNetwork", "Request to https://api.example.com/v1/items took {ms}ms, retrying"},
+	{"W", "System.err", "java.net.SocketTimeoutException: timeout"},
+	{"W", "ActivityManager", "Slow operation: {ms}ms so far, now at startProcess: done updating pids map"},
+	{"E", "DemoNetwork", "Failed to refresh feed: HTTP 503"},
+	{"E", "BluetoothAdapter", "getBondedDevices() failed: service not bound"},
+}
+
+// demoSystemTags are logged by system_server rather than the app
+var demoSystemTags = []string{"ActivityManager", "ActivityTaskManager", "WindowManager", "ConnectivityService"}
+
+// demoEvent writes a burst of related demo lines; app is the app's PID
+type demoEvent func(emit func(level, tag, format string, a ...any), rng *rand.Rand, app int)
+
+// demoEvents are the notable events of the demo stream and their weights
+var demoEvents = []struct {
+	weight   int
+	restarts bool // The app comes back as a new process afterwards
+	event    demoEvent
+}{
+	{40, false, func(emit func(level, tag, format string, a ...any), rng *rand.Rand, app int) {
+		emit("I", "art", "Background concurrent copying GC freed %d(%dMB) AllocSpace objects, %d(%dKB) LOS objects, %d%% free, %dMB/%dMB, paused %dus total %d.%dms",
+			rng.IntN(90000), rng.IntN(20), rng.IntN(50), rng.IntN(900), 30+rng.IntN(40), 5+rng.IntN(20), 30+rng.IntN(30), rng.IntN(900), rng.IntN(200), rng.IntN(10))
+	}},
+	{10, false, func(emit func(level, tag, format string, a ...any), rng *rand.Rand, app int) {
+		emit("I", "ActivityManager", "Start proc %d:%s/u0a123 for activity {%s/%s.MainActivity}", app, demoPackage, demoPackage, demoPackage)
+		emit("I", "ActivityTaskManager", "START u0 {flg=0x10000000 cmp=%s/.MainActivity} from uid 10123", demoPackage)
+	}},
+	{10, false, func(emit func(level, tag, format string, a ...any), rng *rand.Rand, app int) {
+		body := strings.Repeat(`{"id":12345,"title":"Demo item","tags":["alpha","beta"],"updated":"2026-04-19T12:00:00Z"},`, 2+rng.IntN(5))
+		emit("D", "DemoApi", "Response body: [%s]", strings.TrimSuffix(body, ","))
+	}},
+	{2, true, func(emit func(level, tag, format string, a ...any), rng *rand.Rand, app int) {
+		emit("E", "AndroidRuntime", "FATAL EXCEPTION: main")
+		emit("E", "AndroidRuntime", "Process: %s, PID: %d", demoPackage, app)
+		emit("E", "AndroidRuntime", "java.lang.NullPointerException: Attempt to invoke virtual method 'int java.lang.String.length()' on a null object reference")
+		emit("E", "AndroidRuntime", "\tat %s.FeedAdapter.onBindViewHolder(FeedAdapter.kt:42)", demoPackage)
+		emit("E", "AndroidRuntime", "\tat androidx.recyclerview.widget.RecyclerView$Adapter.bindViewHolder(RecyclerView.java:7254)")
+		emit("E", "AndroidRuntime", "\tat android.os.Handler.dispatchMessage(Handler.java:106)")
+		emit("E", "AndroidRuntime", "\tat com.android.internal.os.ZygoteInit.main(ZygoteInit.java:1003)")
+		emit("W", "ActivityManager", "Process %s has crashed too many times, killing!", demoPackage)
+	}},
+	{1, false, func(emit func(level, tag, format string, a ...any), rng *rand.Rand, app int) {
+		emit("E", "ActivityManager", "ANR in %s (%s/.MainActivity)", demoPackage, demoPackage)
+		emit("E", "ActivityManager", "PID: %d", app)
+		emit("E", "ActivityManager", "Reason: Input dispatching timed out (Waiting to send non-key event because the touched window has not finished processing certain input events)")
+		emit("E", "ActivityManager", "CPU usage from 0ms to 5012ms later: 98%% %s: 95%% user + 3%% kernel", demoPackage)
+	}},
+	{1, true, func(emit func(level, tag, format string, a ...any), rng *rand.Rand, app int) {
+		emit("F", "DEBUG", "*** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***")
+		emit("F", "DEBUG", "Build fingerprint: 'google/demo/demo:14/UQ1A.240105.004/11206848:user/release-keys'")
+		emit("F", "DEBUG", "signal 11 (SIGSEGV), code 1 (SEGV_MAPERR), fault addr 0x0")
+		emit("F", "DEBUG", "    #00 pc 000000000004a2c8  /data/app/%s/lib/arm64/libdemo.so (decode_frame+120)", demoPackage)
+	}},
+}
+
+// demoStream returns a reader producing a realistic logcat stream at about
+// rate lines per second, stopping after count lines unless count is 0, or
+// when ctx is canceled
+func demoStream(ctx context.Context, rate, count int) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		rng := rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0))
+		w := bufio.NewWriter(pw)
+		lines := 0
+		systemPid, app, tid := 1400+rng.IntN(200), 4000+rng.IntN(5000), 0
+		emit := func(level, tag, format string, a ...any) {
+			if count > 0 && lines >= count {
+				return
+			}
+			pid := app
+			if slices.Contains(demoSystemTags, tag) {
+				pid = systemPid
+			}
+			fmt.Fprintf(w, "%s %5d %5d %s %-8s: %s\n", time.Now().Format("01-02 15:04:05.000"), pid, pid+tid, level, tag, fmt.Sprintf(format, a...))
+			lines++
+		}
+		total := 0
+		for _, e := range demoEvents {
+			total += e.weight
+		}
+
+		for (count == 0 || lines < count) && ctx.Err() == nil {
+			tid = 0
+			if rng.IntN(3) == 0 {
+				tid = 1 + rng.IntN(40)
+			}
+			if n := rng.IntN(10 * total); n < total {
+				for _, e := range demoEvents {
+					if n -= e.weight; n < 0 {
+						e.event(emit, rng, app)
+						if e.restarts {
+							app = 4000 + rng.IntN(5000)
+						}
+						break
+					}
+				}
+			} else {
+				r := demoRoutine[rng.IntN(len(demoRoutine))]
+				message := strings.NewReplacer("{pkg}", demoPackage, "{n}", strconv.Itoa(1+rng.IntN(60)), "{ms}", strconv.Itoa(5+rng.IntN(900))).Replace(r[2])
+				emit(r[0], r[1], "%s", message)
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+			// Vary the pace around the requested rate
+			time.Sleep(time.Duration(rng.ExpFloat64() * float64(time.Second) / float64(rate)))
+		}
+		pw.CloseWithError(w.Flush())
+	}()
+	return pr
+}
+
+// runDemo colorizes a fake logcat stream for trying out styles and filters
+// without a device
+func runDemo(ctx context.Context, opts *LogcatOptions) error {
+	if opts.DemoRate <= 0 {
+		return fmt.Errorf("-demo-rate must be positive")
+	}
+	return colorizeLines(demoStream(ctx, opts.DemoRate, opts.DemoLines), opts, watchConfig(ctx, opts.ConfigPath))
+}
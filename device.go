@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// DeviceInfo describes the device a session streams from
+type DeviceInfo struct {
+	Serial       string
+	Manufacturer string
+	Model        string
+	Release      string // Android version, e.g. "14"
+	SDK          string // API level, e.g. "34"
+	Fingerprint  string
+	Timezone     string        // Device time zone, e.g. "Europe/London"
+	Battery      int           // Battery level in percent, -1 if unknown
+	ClockOffset  time.Duration // How far the device clock is ahead of the host's
+	ClockSampled bool          // Whether ClockOffset was measured
+	Selection    string        // Why the device was chosen automatically, empty if it was given
+}
+
+// AttachedDevice is a device listed by adb devices -l
+type AttachedDevice struct {
+	Serial      string
+	State       string // e.g. "device", "unauthorized" or "no permissions"
+	TransportID int    // Increases with each connection, 0 if unknown
+	USB         bool   // Connected over USB
+	Emulator    bool
+	Model       string // Model name reported by adb, if any
+	Details     string // The remaining key:value fields
+}
+
+// BannerLabelColor and BannerValueColor style the device banner
+var (
+	BannerLabelColor = color.New(color.FgCyan).SprintfFunc()
+	BannerValueColor = color.New(color.Bold).SprintfFunc()
+)
+
+// getpropPattern matches the "[key]: [value]" lines printed by getprop
+var getpropPattern = regexp.MustCompile(`^\[([^\]]+)\]: \[(.*)\]$`)
+
+// batteryLevelPattern matches the level line of dumpsys battery
+var batteryLevelPattern = regexp.MustCompile(`(?m)^\s*level: (\d+)`)
+
+// queryDeviceInfo reads the properties and battery level of the selected device
+func queryDeviceInfo(opts LogcatOptions) (DeviceInfo, error) {
+	out, err := adbCommand(opts, "shell", "getprop").Output()
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+	props := parseGetprop(string(out))
+	if len(props) == 0 {
+		return DeviceInfo{}, fmt.Errorf("getprop returned no properties")
+	}
+
+	info := DeviceInfo{
+		Serial:       props["ro.serialno"],
+		Manufacturer: props["ro.product.manufacturer"],
+		Model:        props["ro.product.model"],
+		Release:      props["ro.build.version.release"],
+		SDK:          props["ro.build.version.sdk"],
+		Fingerprint:  props["ro.build.fingerprint"],
+		Timezone:     props["persist.sys.timezone"],
+		Battery:      -1,
+	}
+	if info.Serial == "" {
+		if out, err := adbCommand(opts, "get-serialno").Output(); err == nil {
+			info.Serial = strings.TrimSpace(string(out))
+		}
+	}
+	if out, err := adbCommand(opts, "shell", "dumpsys", "battery").Output(); err == nil {
+		if m := batteryLevelPattern.FindSubmatch(out); m != nil {
+			info.Battery, _ = strconv.Atoi(string(m[1]))
+		}
+	}
+	if offset, err := measureClockOffset(opts); err == nil {
+		info.ClockOffset, info.ClockSampled = offset, true
+	}
+	return info, nil
+}
+
+// parseGetprop parses getprop output into a property map
+func parseGetprop(out string) map[string]string {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		if m := getpropPattern.FindStringSubmatch(strings.TrimRight(scanner.Text(), "\r")); m != nil {
+			props[m[1]] = m[2]
+		}
+	}
+	return props
+}
+
+// BannerLines returns the device description as label/value pairs
+func (d DeviceInfo) BannerLines() [][2]string {
+	battery := "unknown"
+	if d.Battery >= 0 {
+		battery = fmt.Sprintf("%d%%", d.Battery)
+	}
+	lines := [][2]string{
+		{"Device", strings.TrimSpace(d.Manufacturer + " " + d.Model)},
+		{"Android", fmt.Sprintf("%s (SDK %s)", d.Release, d.SDK)},
+		{"Build", d.Fingerprint},
+		{"Serial", d.Serial},
+		{"Battery", battery},
+	}
+	if d.ClockSampled {
+		lines = append(lines, [2]string{"Clock", formatOffset(d.ClockOffset) + " from host"})
+	}
+	if d.Selection != "" {
+		lines = append(lines, [2]string{"Selected", d.Selection})
+	}
+	return lines
+}
+
+// printBanner prints a colored header describing the device to w
+func printBanner(w io.Writer, d DeviceInfo) {
+	for _, line := range d.BannerLines() {
+		fmt.Fprintf(w, "%s %s\n", BannerLabelColor("%-8s", line[0]+":"), BannerValueColor("%s", line[1]))
+	}
+	fmt.Fprintln(w)
+}
+
+// listDevices returns the devices known to the adb server
+func listDevices(opts LogcatOptions) ([]AttachedDevice, error) {
+	out, err := exec.Command("adb", append(serverArgs(opts), "devices", "-l")...).Output()
+	if err != nil {
+		var stderr []string
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = strings.Split(string(exitErr.Stderr), "\n")
+		}
+		return nil, classifyADBError(err, stderr)
+	}
+
+	var devices []AttachedDevice
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(line, "List of devices") || strings.HasPrefix(line, "* ") {
+			continue
+		}
+
+		d := AttachedDevice{Serial: fields[0], State: fields[1]}
+		rest := fields[2:]
+		if d.State == "no" && len(rest) > 0 && rest[0] == "permissions" {
+			d.State = "no permissions"
+		}
+		var details []string
+		for _, f := range rest {
+			key, value, ok := strings.Cut(f, ":")
+			if !ok || strings.Contains(key, "(") {
+				continue
+			}
+			switch key {
+			case "transport_id":
+				d.TransportID, _ = strconv.Atoi(value)
+			case "usb":
+				d.USB = true
+			case "product", "model", "device":
+				if key == "model" {
+					d.Model = value
+				}
+				if strings.HasPrefix(value, "sdk_") || strings.HasPrefix(value, "emu") || value == "sdk" {
+					d.Emulator = true
+				}
+			}
+			details = append(details, f)
+		}
+		if strings.HasPrefix(d.Serial, "emulator-") {
+			d.Emulator = true
+		}
+		d.Details = strings.Join(details, " ")
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// DeviceSelection holds the heuristics for choosing among several devices
+type DeviceSelection struct {
+	Newest       bool // Prefer the most recently connected device
+	USBOnly      bool // Only consider physical devices connected over USB
+	EmulatorOnly bool // Only consider emulators
+}
+
+// selectDevice chooses a device when none was given on the command line.
+// It returns the adb transport ID to use and why that device was chosen,
+// or an empty ID when at most one device is attached and no heuristic is set.
+func selectDevice(opts LogcatOptions, sel DeviceSelection) (string, string, error) {
+	devices, err := listDevices(opts)
+	if err != nil {
+		return "", "", err
+	}
+
+	var candidates []AttachedDevice
+	for _, d := range devices {
+		if d.State != "device" || (sel.USBOnly && d.Emulator) || (sel.EmulatorOnly && !d.Emulator) {
+			continue
+		}
+		candidates = append(candidates, d)
+	}
+	heuristic := sel.Newest || sel.USBOnly || sel.EmulatorOnly
+	if len(candidates) == 0 {
+		if heuristic {
+			return "", "", &ADBError{Message: "no matching device connected", Hint: "Check `adb devices -l` and the -usb-only/-emulator-only flags.", ExitCode: ExitNoDevices}
+		}
+		return "", "", nil
+	}
+	if len(candidates) == 1 && !heuristic {
+		return "", "", nil
+	}
+
+	// Prefer physical devices over emulators unless told otherwise, then the newest connection
+	reason := "newest"
+	if !sel.Newest && !sel.EmulatorOnly && slices.ContainsFunc(candidates, func(d AttachedDevice) bool { return !d.Emulator }) {
+		candidates = slices.DeleteFunc(candidates, func(d AttachedDevice) bool { return d.Emulator })
+		reason = "physical, newest"
+	}
+	best := slices.MaxFunc(candidates, func(a, b AttachedDevice) int { return a.TransportID - b.TransportID })
+	if best.TransportID == 0 {
+		return "", "", &ADBError{Message: "cannot tell attached devices apart", Hint: "Select a device with -d SERIAL, -e or -transport.", ExitCode: ExitMultipleDevices}
+	}
+	return strconv.Itoa(best.TransportID), fmt.Sprintf("%s (%s of %d)", best.Serial, reason, len(candidates)), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// StatsHeadingColor, StatsLabelColor and StatsValueColor style logd statistics
+var (
+	StatsHeadingColor = color.New(color.FgCyan, color.Bold).SprintfFunc()
+	StatsLabelColor   = color.New(color.FgCyan).SprintfFunc()
+	StatsValueColor   = color.New(color.Bold).SprintfFunc()
+)
+
+// bufferSizePattern matches the lines printed by logcat -g, such as
+// "main: ring buffer is 256 KiB (251 KiB consumed), max entry is 5120 B, ..."
+// or "main: ring buffer is 256Kb (253Kb consumed), ..." on older releases
+var bufferSizePattern = regexp.MustCompile(`^(\S+): ring buffer is ([\d.]+) ?(\w+) \(([\d.]+) ?(\w+) consumed\)`)
+
+// statsNumberPattern matches the numbers in logcat -S output
+var statsNumberPattern = regexp.MustCompile(`\b\d[\d/.,]*(?:[KMG]i?B)?\b`)
+
+// runStatsDevice prints the log buffer sizes and the logd statistics
+func runStatsDevice(ctx context.Context, opts *LogcatOptions) error {
+	sizes, err := adbCommand(*opts, "logcat", "-b", "all", "-g").Output()
+	if err != nil {
+		return classifyADBError(err, nil)
+	}
+	fmt.Println(StatsHeadingColor("Log buffers"))
+	printBufferSizes(string(sizes))
+
+	stats, err := adbCommand(*opts, "logcat", "-b", "all", "-S").Output()
+	if err != nil {
+		return classifyADBError(err, nil)
+	}
+	fmt.Println()
+	printLogdStats(string(stats))
+	return nil
+}
+
+// printBufferSizes prints how full each buffer is, warning about buffers
+// that are nearly full and so already dropping old lines
+func printBufferSizes(out string) {
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		m := bufferSizePattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		size, used := parseByteSize(m[2], m[3]), parseByteSize(m[4], m[5])
+
+		usage, status := "", ""
+		if size > 0 {
+			percent := used * 100 / size
+			usage = fmt.Sprintf("%3.0f%%", percent)
+			if percent >= 90 {
+				usage = LogLevelColors["W"]("%s", usage)
+				status = LogLevelColors["W"]("full, oldest lines are being dropped; consider -buffer-size")
+			}
+		}
+		row := fmt.Sprintf("  %s %s of %s %s %s", StatsLabelColor("%-8s", m[1]),
+			StatsValueColor("%8s", m[4]+" "+m[5]), StatsValueColor("%-8s", m[2]+" "+m[3]), usage, status)
+		fmt.Println(strings.TrimRight(row, " "))
+	}
+}
+
+// parseByteSize converts a size such as "256", "KiB" into bytes
+func parseByteSize(value, unit string) float64 {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToUpper(unit[:1]) {
+	case "K":
+		n *= 1 << 10
+	case "M":
+		n *= 1 << 20
+	case "G":
+		n *= 1 << 30
+	}
+	return n
+}
+
+// printLogdStats colors logcat -S output: section headings, row labels and
+// the numbers in each row, with nonzero pruned counts as warnings
+func printLogdStats(out string) {
+	prunedColumn := -1
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			prunedColumn = -1
+			fmt.Println()
+		case strings.HasSuffix(trimmed, ":") || strings.Contains(line, " in ") && strings.Contains(line, "buffer:"):
+			// "Chattiest UIDs in main log buffer:   Size  Pruned"
+			prunedColumn = strings.Index(line, "Pruned")
+			fmt.Println(StatsHeadingColor("%s", line))
+		case strings.HasPrefix(line, "size/num") || strings.HasPrefix(trimmed, "UID") || strings.HasPrefix(trimmed, "PID") || strings.HasPrefix(trimmed, "TAG"):
+			fmt.Println(StatsLabelColor("%s", line))
+		default:
+			fmt.Println(colorStatsRow(line, prunedColumn))
+		}
+	}
+}
+
+// colorStatsRow highlights the numbers in a statistics row, warning about
+// nonzero counts in the pruned column
+func colorStatsRow(line string, prunedColumn int) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range statsNumberPattern.FindAllStringIndex(line, -1) {
+		b.WriteString(line[last:loc[0]])
+		number := line[loc[0]:loc[1]]
+		if prunedColumn >= 0 && loc[1] >= prunedColumn && number != "0" {
+			b.WriteString(LogLevelColors["W"]("%s", number))
+		} else {
+			b.WriteString(StatsValueColor("%s", number))
+		}
+		last = loc[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// diffVolatile matches numbers and hex strings, which differ between runs
+// without the lines meaning anything different
+var diffVolatile = regexp.MustCompile(`0x[0-9a-fA-F]+|\b[0-9a-fA-F]{8,}\b|[0-9]+`)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxDiffEdits bounds the work of aligning two captures. Captures differing
+// in more lines than this are compared by line counts instead.
+const maxDiffEdits = 4000
+
+// diffSeparatorColor is the color of the line between separate changes
+var diffSeparatorColor = color.New(color.FgCyan).SprintfFunc()
+
+// diffMarkers are printed before lines only in the first capture, only in
+// the second and in both
+var diffMarkers = [3]string{
+	color.New(color.FgRed, color.Bold).Sprint("- "),
+	color.New(color.FgGreen, color.Bold).Sprint("+ "),
+	"  ",
+}
+
+// diffKey returns what identifies a line across runs: its level, tag and
+// message with numbers masked, leaving out its time, PID and TID. It
+// returns false for lines excluded by the tag, level and message filters.
+func diffKey(line string, opts LogcatOptions) (string, bool) {
+	entry, ok := parseLogLine(line)
+	if !ok {
+		return diffVolatile.ReplaceAllString(line, "#"), opts.Grep == nil || opts.Grep.MatchString(line)
+	}
+	entry.Level = remapSeverity(entry.Level, entry.Tag, entry.Message, opts.Severities)
+	if !matchesQuery(entry, opts) {
+		return "", false
+	}
+	return entry.Level + " " + entry.Tag + ": " + diffVolatile.ReplaceAllString(entry.Message, "#"), true
+}
+
+// diffSide holds the lines of one capture and their keys as small integers
+type diffSide struct {
+	lines []string
+	ids   []int
+}
+
+// readDiffSide reads the lines of a capture that pass the filters
+func readDiffSide(r io.Reader, ids map[string]int, opts LogcatOptions) (diffSide, error) {
+	var side diffSide
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := prepareLine(scanner.Text(), opts)
+		key, ok := diffKey(line, opts)
+		if !ok {
+			continue
+		}
+		id, seen := ids[key]
+		if !seen {
+			id = len(ids)
+			ids[key] = id
+		}
+		side.lines = append(side.lines, line)
+		side.ids = append(side.ids, id)
+	}
+	return side, scanner.Err()
+}
+
+// diffOp is one line of an alignment: an index into the first capture,
+// the second or both, and which of diffMarkers applies
+type diffOp struct {
+	a, b int
+	kind int
+}
+
+// alignLines aligns two sequences with Myers' algorithm, returning false if
+// they differ in more than maxDiffEdits lines
+func alignLines(a, b []int) ([]diffOp, bool) {
+	n, m := len(a), len(b)
+	offset := maxDiffEdits + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= maxDiffEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackAlignment(trace, n, m), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// backtrackAlignment recovers the alignment from the saved Myers frontiers.
+// trace[d] holds the frontier before step d for diagonals -d to d.
+func backtrackAlignment(trace [][]int, x, y int) []diffOp {
+	var ops []diffOp
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, diffOp{x, y, 2})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{-1, prevY, 1})
+			} else {
+				ops = append(ops, diffOp{prevX, -1, 0})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// countLines compares two captures that are too different to align by
+// marking the lines whose key occurs more often in one than in the other
+func countLines(a, b []int) []diffOp {
+	counts := make(map[int]int)
+	for _, id := range b {
+		counts[id]++
+	}
+	var ops []diffOp
+	for i, id := range a {
+		if counts[id] > 0 {
+			counts[id]--
+			continue
+		}
+		ops = append(ops, diffOp{i, -1, 0})
+	}
+	for _, id := range a {
+		counts[id]--
+	}
+	for j, id := range b {
+		if counts[id] < 0 {
+			counts[id]++
+			continue
+		}
+		ops = append(ops, diffOp{-1, j, 1})
+	}
+	return ops
+}
+
+// runDiff prints the lines present in one of two captures but not the
+// other, aligning them by level, tag and message
+func runDiff(ctx context.Context, opts *LogcatOptions) error {
+	if len(opts.Args) != 2 {
+		return fmt.Errorf("diff needs two capture files")
+	}
+	opts.LocalFilter = true
+	opts.MaxDelta = 0
+
+	ids := make(map[string]int)
+	var sides [2]diffSide
+	for i, name := range opts.Args {
+		readers, closeAll, err := openInputs([]string{name}, opts)
+		if err != nil {
+			return err
+		}
+		sides[i], err = readDiffSide(io.MultiReader(readers...), ids, *opts)
+		closeAll()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+	}
+	a, b := sides[0], sides[1]
+
+	// Only the lines between the common head and tail need aligning
+	head := 0
+	for head < len(a.ids) && head < len(b.ids) && a.ids[head] == b.ids[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(a.ids)-head && tail < len(b.ids)-head && a.ids[len(a.ids)-1-tail] == b.ids[len(b.ids)-1-tail] {
+		tail++
+	}
+	ops, aligned := alignLines(a.ids[head:len(a.ids)-tail], b.ids[head:len(b.ids)-tail])
+	if !aligned {
+		fmt.Fprint(opts.out(), diffSeparatorColor("Captures differ in more than %d lines; showing lines missing from one or the other without alignment\n", maxDiffEdits))
+		ops = countLines(a.ids[head:len(a.ids)-tail], b.ids[head:len(b.ids)-tail])
+		for i := range ops {
+			if ops[i].a >= 0 {
+				ops[i].a += head
+			}
+			if ops[i].b >= 0 {
+				ops[i].b += head
+			}
+		}
+	} else {
+		all := make([]diffOp, 0, head+len(ops)+tail)
+		for i := range head {
+			all = append(all, diffOp{i, i, 2})
+		}
+		for _, op := range ops {
+			if op.a >= 0 {
+				op.a += head
+			}
+			if op.b >= 0 {
+				op.b += head
+			}
+			all = append(all, op)
+		}
+		for i := range tail {
+			all = append(all, diffOp{len(a.ids) - tail + i, len(b.ids) - tail + i, 2})
+		}
+		ops = all
+	}
+
+	// Print the changes with a few unchanged lines around each
+	changed := false
+	last := -1
+	for i, op := range ops {
+		show := op.kind != 2
+		for j := max(i-diffContext, 0); !show && j <= min(i+diffContext, len(ops)-1); j++ {
+			show = ops[j].kind != 2
+		}
+		if !show {
+			continue
+		}
+		if last >= 0 && i > last+1 {
+			fmt.Fprintln(opts.out(), diffSeparatorColor("--"))
+		}
+		last = i
+		changed = changed || op.kind != 2
+		var line string
+		if op.kind == 1 {
+			line = b.lines[op.b]
+		} else {
+			line = a.lines[op.a]
+		}
+		lineOpts := *opts
+		lineOpts.Prefix = diffMarkers[op.kind] + opts.Prefix
+		printColoredLog(line, "", time.Time{}, "", lineOpts)
+	}
+	if !changed {
+		fmt.Fprintln(opts.out(), diffSeparatorColor("No differences"))
+	}
+	return nil
+}
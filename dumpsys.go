@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// dumpsysSection matches the first line of a dumpsys section: the
+// DUMP OF SERVICE separators of a full dump, and unindented headings in
+// capitals or ending with a colon
+var dumpsysSection = regexp.MustCompile(`^(?:DUMP OF SERVICE .*|[A-Z][A-Z0-9 _()/-]{3,}.*|\S.*:)$`)
+
+// dumpsysService matches the heading of a service in a full dump
+var dumpsysService = regexp.MustCompile(`^DUMP OF SERVICE `)
+
+// dumpsysSeparator matches the lines of dashes around sections
+var dumpsysSeparator = regexp.MustCompile(`^-{10,}$`)
+
+// dumpsysKeyValue matches key=value pairs and the key of "Key: value" lines
+var dumpsysKeyValue = regexp.MustCompile(`([\w.$-]+)=(\S*)|^(\s+)([\w ./()-]+?):(\s)`)
+
+// dumpsysError and dumpsysWarning match markers of failures and warnings
+var (
+	dumpsysError   = regexp.MustCompile(`\b(?:ERROR|Error|FAILED|Failed|FATAL|Exception|ANR|[Cc]rash(?:ed|es)?|DEAD|dead|not responding)\b`)
+	dumpsysWarning = regexp.MustCompile(`\b(?:WARNING|Warning|[Tt]imeout|[Tt]imed out|denied|[Ss]low)\b`)
+)
+
+// runDumpsys runs adb shell dumpsys with the given service and arguments on
+// the selected device and colorizes its sections, keys and error markers.
+// -section keeps only the sections whose heading matches, and -seek pages
+// through the output.
+func runDumpsys(ctx context.Context, opts *LogcatOptions) error {
+	if opts.Device == "" && opts.Transport == "" {
+		transport, _, err := selectDevice(*opts, opts.Selection)
+		if err != nil {
+			return err
+		}
+		opts.Transport = transport
+	}
+	out, err := adbCommand(*opts, append([]string{"shell", "dumpsys"}, opts.Args...)...).Output()
+	if err != nil {
+		return classifyADBError(err, nil)
+	}
+
+	// A matching service heading keeps all of the service's sections
+	var lines []string
+	keep, service := opts.Section == nil, false
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := escapeControl(scanner.Text())
+		if opts.Section != nil && dumpsysService.MatchString(line) {
+			service = opts.Section.MatchString(line)
+			keep = service
+		} else if opts.Section != nil && dumpsysSection.MatchString(line) {
+			keep = service || opts.Section.MatchString(line)
+		}
+		if keep {
+			lines = append(lines, colorizeDumpsys(line))
+		}
+	}
+
+	if !opts.Seek {
+		w := bufio.NewWriter(os.Stdout)
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+		return w.Flush()
+	}
+	return pageLines(lines)
+}
+
+// colorizeDumpsys colors one line of dumpsys output
+func colorizeDumpsys(line string) string {
+	switch {
+	case dumpsysSeparator.MatchString(line):
+		return StatsLabelColor("%s", line)
+	case dumpsysSection.MatchString(line):
+		return StatsHeadingColor("%s", line)
+	}
+	if dumpsysError.MatchString(line) {
+		return LogLevelColors["E"]("%s", line)
+	}
+	if dumpsysWarning.MatchString(line) {
+		return LogLevelColors["W"]("%s", line)
+	}
+	return dumpsysKeyValue.ReplaceAllStringFunc(line, func(pair string) string {
+		m := dumpsysKeyValue.FindStringSubmatch(pair)
+		if m[1] != "" {
+			return StatsLabelColor("%s", m[1]) + "=" + StatsValueColor("%s", m[2])
+		}
+		return m[3] + StatsLabelColor("%s", m[4]) + ":" + m[5]
+	})
+}
+
+// pageLines shows lines a page at a time, reading commands from the
+// terminal between pages: Enter for the next page, /TEXT to find the next
+// line containing TEXT and q to quit
+func pageLines(lines []string) error {
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return fmt.Errorf("-seek needs a terminal on stdin")
+	}
+	commands := bufio.NewScanner(os.Stdin)
+	pos := 0
+	for pos < len(lines) {
+		next := min(pos+seekPageLines, len(lines))
+		for _, line := range lines[pos:next] {
+			fmt.Println(line)
+		}
+		if next >= len(lines) {
+			return nil
+		}
+
+		fmt.Fprintf(os.Stderr, "[%d-%d/%d] Enter, /TEXT, q> ", pos+1, next, len(lines))
+		if !commands.Scan() {
+			return commands.Err()
+		}
+		switch cmd := strings.TrimSpace(commands.Text()); {
+		case cmd == "":
+			pos = next
+		case cmd == "q":
+			return nil
+		case strings.HasPrefix(cmd, "/"):
+			found := -1
+			for i := pos + 1; i < len(lines) && found < 0; i++ {
+				if strings.Contains(stripANSI(lines[i]), cmd[1:]) {
+					found = i
+				}
+			}
+			if found < 0 {
+				fmt.Fprintln(os.Stderr, "Not found")
+				continue
+			}
+			pos = found
+		default:
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Unknown command %q\n", cmd))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// emulatorBootTimeout bounds how long to wait for an emulator to boot
+const emulatorBootTimeout = 5 * time.Minute
+
+// findEmulator returns the path of the Android emulator binary
+func findEmulator() (string, error) {
+	if path, err := exec.LookPath("emulator"); err == nil {
+		return path, nil
+	}
+	name := "emulator"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	for _, env := range []string{"ANDROID_HOME", "ANDROID_SDK_ROOT"} {
+		if sdk := os.Getenv(env); sdk != "" {
+			path := filepath.Join(sdk, "emulator", name)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("emulator not found on PATH or in $ANDROID_HOME/emulator")
+}
+
+// runningAVDs returns the serials of running emulators keyed by AVD name
+func runningAVDs(opts LogcatOptions) map[string]string {
+	avds := make(map[string]string)
+	devices, err := listDevices(opts)
+	if err != nil {
+		return avds
+	}
+	for _, d := range devices {
+		if !strings.HasPrefix(d.Serial, "emulator-") {
+			continue
+		}
+		o := opts
+		o.Device, o.Transport = d.Serial, ""
+		out, err := adbCommand(o, "emu", "avd", "name").Output()
+		if err != nil {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.ReplaceAll(string(out), "\r", ""), "\n")
+		avds[strings.TrimSpace(name)] = d.Serial
+	}
+	return avds
+}
+
+// bootAVD starts the named emulator unless it is already running, waits for
+// it to finish booting and returns its serial. The emulator keeps running
+// after logcatcolor exits.
+func bootAVD(ctx context.Context, opts LogcatOptions, avd string) (string, error) {
+	if serial, ok := runningAVDs(opts)[avd]; ok {
+		fmt.Fprintf(os.Stderr, "Emulator %s is already running as %s\n", avd, serial)
+		return serial, waitForBoot(ctx, opts, serial)
+	}
+
+	path, err := findEmulator()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(path, "-avd", avd)
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("starting emulator: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	fmt.Fprintf(os.Stderr, "Booting emulator %s...\n", avd)
+	deadline := time.Now().Add(emulatorBootTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case err := <-exited:
+			return "", fmt.Errorf("emulator exited before booting: %v", err)
+		case <-time.After(time.Second):
+		}
+		if serial, ok := runningAVDs(opts)[avd]; ok {
+			return serial, waitForBoot(ctx, opts, serial)
+		}
+	}
+	return "", fmt.Errorf("emulator %s did not appear within %v", avd, emulatorBootTimeout)
+}
+
+// waitForBoot waits until the device reports sys.boot_completed
+func waitForBoot(ctx context.Context, opts LogcatOptions, serial string) error {
+	opts.Device, opts.Transport = serial, ""
+	if err := waitForDevice(ctx, opts); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(emulatorBootTimeout)
+	for time.Now().Before(deadline) {
+		out, err := adbCommand(opts, "shell", "getprop", "sys.boot_completed").Output()
+		if err == nil && strings.TrimSpace(string(out)) == "1" {
+			fmt.Fprintf(os.Stderr, "Emulator %s booted\n", serial)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	return fmt.Errorf("%s did not finish booting within %v", serial, emulatorBootTimeout)
+}
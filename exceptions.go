@@ -0,0 +1,177 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// exceptionHeader matches the first line of a Java or Kotlin stack trace,
+// capturing the exception type
+var exceptionHeader = regexp.MustCompile(`^(?:FATAL EXCEPTION.*|(?:Caused by: )?([A-Za-z_$][\w$]*(?:\.[\w$]+)+(?:Exception|Error|Throwable))(?::.*)?)$`)
+
+// stackFrame matches a frame of a stack trace, capturing the method
+var stackFrame = regexp.MustCompile(`^\s*at ([\w$.<>-]+)\(`)
+
+// moreFrames matches the "... 12 more" line ending a cause's frames
+var moreFrames = regexp.MustCompile(`^\s*\.\.\. \d+ more$`)
+
+// frameworkPackages prefix frames that are not the app's own code
+var frameworkPackages = []string{
+	"java.", "javax.", "jdk.", "sun.", "kotlin.", "kotlinx.", "android.", "androidx.",
+	"com.android.", "com.google.android.", "dalvik.", "libcore.", "org.json.", "okhttp3.", "okio.", "retrofit2.",
+}
+
+// maxExceptionFrames is how many frames are searched for an app frame
+const maxExceptionFrames = 50
+
+// exceptionStats counts the occurrences of one exception signature
+type exceptionStats struct {
+	Type  string `json:"type"`
+	Frame string `json:"frame"` // Top app frame, or the top frame without one
+	Count int    `json:"count"`
+	First string `json:"first"`
+	Last  string `json:"last"`
+}
+
+// pendingException is a stack trace still being read
+type pendingException struct {
+	entry    logLine // Header line
+	typ      string
+	topFrame string
+	frames   int
+	done     bool // Counted; its remaining frames and causes are skipped
+}
+
+// ExceptionCensus groups the exceptions logged in a session by type and
+// top app frame for the -exceptions table printed at exit
+type ExceptionCensus struct {
+	mu      sync.Mutex
+	path    string                       // JSON file to write, empty for a table on stderr
+	pending map[string]*pendingException // Traces being read, by PID and tag
+	stats   map[string]*exceptionStats
+}
+
+// newExceptionCensus returns an empty census, written to path if given
+func newExceptionCensus(path string) *ExceptionCensus {
+	return &ExceptionCensus{
+		path:    path,
+		pending: make(map[string]*pendingException),
+		stats:   make(map[string]*exceptionStats),
+	}
+}
+
+// isAppFrame reports whether a frame's method belongs to the app rather
+// than the platform or common libraries
+func isAppFrame(method string) bool {
+	return !slices.ContainsFunc(frameworkPackages, func(p string) bool { return strings.HasPrefix(method, p) })
+}
+
+// Observe follows stack traces through the lines of each process and tag
+func (c *ExceptionCensus) Observe(line string) {
+	entry, ok := parseLogLine(line)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := entry.PID + "/" + entry.Tag
+	p := c.pending[key]
+	if m := stackFrame.FindStringSubmatch(entry.Message); m != nil || moreFrames.MatchString(entry.Message) {
+		if p == nil || p.done || m == nil {
+			return
+		}
+		if p.topFrame == "" {
+			p.topFrame = m[1]
+		}
+		if p.frames++; isAppFrame(m[1]) || p.frames >= maxExceptionFrames {
+			p.topFrame = m[1]
+			c.record(p)
+		}
+		return
+	}
+	if m := exceptionHeader.FindStringSubmatch(entry.Message); m != nil {
+		switch {
+		case p != nil && strings.HasPrefix(entry.Message, "Caused by: "):
+			// Keep the outermost exception of a chain
+		case p != nil && !p.done && p.typ == "" && m[1] != "":
+			// The exception following FATAL EXCEPTION and Process lines
+			p.typ = m[1]
+		default:
+			if p != nil {
+				c.record(p)
+			}
+			c.pending[key] = &pendingException{entry: entry, typ: m[1]}
+		}
+		return
+	}
+	if p != nil && (p.typ != "" || !strings.HasPrefix(entry.Message, "Process: ")) {
+		c.record(p)
+		delete(c.pending, key)
+	}
+}
+
+// record counts a finished stack trace once
+func (c *ExceptionCensus) record(p *pendingException) {
+	if p.done || p.typ == "" {
+		p.done = true
+		return
+	}
+	p.done = true
+	signature := p.typ + " " + p.topFrame
+	s, ok := c.stats[signature]
+	if !ok {
+		s = &exceptionStats{Type: p.typ, Frame: p.topFrame, First: p.entry.Time.Format(reportTimeLayout)}
+		c.stats[signature] = s
+	}
+	s.Count++
+	s.Last = p.entry.Time.Format(reportTimeLayout)
+}
+
+// Write prints the census as a table on stderr, or saves it as JSON
+func (c *ExceptionCensus) Write() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.pending {
+		c.record(p)
+	}
+	rows := make([]*exceptionStats, 0, len(c.stats))
+	for _, s := range c.stats {
+		rows = append(rows, s)
+	}
+	slices.SortFunc(rows, func(a, b *exceptionStats) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.First, b.First))
+	})
+
+	if c.path != "" {
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(c.path, append(data, '\n'), 0o644)
+	}
+	printExceptionTable(os.Stderr, rows)
+	return nil
+}
+
+// printExceptionTable prints the exception signatures, most frequent first
+func printExceptionTable(w io.Writer, rows []*exceptionStats) {
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "No exceptions logged")
+		return
+	}
+	fmt.Fprintln(w, ReportHeaderColor("%6s  %-18s  %-18s  %s", "COUNT", "FIRST", "LAST", "EXCEPTION"))
+	for _, s := range rows {
+		fmt.Fprintf(w, "%6d  %-18s  %-18s  %s\n", s.Count, s.First, s.Last, LogLevelColors["E"]("%s", s.Type))
+		if s.Frame != "" {
+			fmt.Fprintf(w, "%48s at %s\n", "", s.Frame)
+		}
+	}
+}
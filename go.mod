@@ -2,10 +2,19 @@ module github.com/erdichen/logcatcolor
 
 go 1.24.2
 
-require github.com/fatih/color v1.18.0
+require (
+	fyne.io/systray v1.12.2
+	github.com/fatih/color v1.18.0
+	github.com/klauspost/compress v1.17.11
+	github.com/mattn/go-isatty v0.0.20
+	github.com/mattn/go-runewidth v0.0.30
+	github.com/tetratelabs/wazero v1.11.0
+	golang.org/x/sys v0.38.0
+	google.golang.org/protobuf v1.36.12
+)
 
 require (
+	github.com/clipperhouse/uax29/v2 v2.2.0 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.25.0 // indirect
 )
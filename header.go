@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// captureHeaderMagic starts the metadata header of saved captures
+const captureHeaderMagic = "# logcatcolor capture v1"
+
+// toolVersion returns the module version logcatcolor was built from
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// captureHeader describes the session a capture was saved from
+func captureHeader(opts LogcatOptions) [][2]string {
+	now := time.Now()
+	zone, _ := now.Zone()
+	fields := [][2]string{
+		{"tool", "logcatcolor " + toolVersion()},
+		{"started", now.Format(time.RFC3339)},
+		{"timezone", zone + " " + now.Format("-07:00")},
+		{"args", strings.Join(os.Args[1:], " ")},
+	}
+	if opts.Profile != "" {
+		fields = append(fields, [2]string{"profile", opts.Profile})
+	}
+	if len(opts.Presets) > 0 {
+		fields = append(fields, [2]string{"presets", strings.Join(opts.Presets, ",")})
+	}
+	if d := opts.DeviceInfo; d != nil {
+		fields = append(fields,
+			[2]string{"device", strings.TrimSpace(d.Manufacturer + " " + d.Model)},
+			[2]string{"serial", d.Serial},
+			[2]string{"fingerprint", d.Fingerprint},
+			[2]string{"android", fmt.Sprintf("%s (SDK %s)", d.Release, d.SDK)})
+		if d.Timezone != "" {
+			fields = append(fields, [2]string{"device-timezone", d.Timezone})
+		}
+		if d.ClockSampled {
+			fields = append(fields, [2]string{"clock-offset", formatOffset(d.ClockOffset)})
+		}
+	} else if opts.Device != "" {
+		fields = append(fields, [2]string{"serial", opts.Device})
+	}
+	return fields
+}
+
+// writeCaptureHeader writes the metadata header that starts a saved capture
+func writeCaptureHeader(w io.Writer, opts LogcatOptions) error {
+	var b strings.Builder
+	b.WriteString(captureHeaderMagic + "\n")
+	for _, field := range captureHeader(opts) {
+		fmt.Fprintf(&b, "# %s: %s\n", field[0], field[1])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// readCaptureHeader consumes the metadata header at the start of a capture,
+// if there is one, and returns its fields
+func readCaptureHeader(r *bufio.Reader) [][2]string {
+	if first, err := r.Peek(len(captureHeaderMagic)); err != nil || string(first) != captureHeaderMagic {
+		return nil
+	}
+	r.ReadString('\n')
+
+	var fields [][2]string
+	for {
+		if next, err := r.Peek(2); err != nil || string(next) != "# " {
+			return fields
+		}
+		line, _ := r.ReadString('\n')
+		key, value, _ := strings.Cut(strings.TrimSpace(line[2:]), ": ")
+		fields = append(fields, [2]string{key, value})
+	}
+}
+
+// printCaptureHeader describes a capture on stderr before it is replayed
+func printCaptureHeader(name string, fields [][2]string) {
+	fmt.Fprintf(os.Stderr, "%s %s\n", BannerLabelColor("%-12s", "capture:"), BannerValueColor("%s", name))
+	for _, field := range fields {
+		fmt.Fprintf(os.Stderr, "%s %s\n", BannerLabelColor("%-12s", field[0]+":"), BannerValueColor("%s", field[1]))
+	}
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// historySize is the number of invocations kept in the history file
+const historySize = 50
+
+// historyCommands are the commands whose invocations are remembered
+var historyCommands = []string{"watch", "dump", "record", "replay", "query", "export"}
+
+// HistoryEntry is a remembered invocation, split so that flags added when
+// recalling it go before its positional arguments
+type HistoryEntry struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Flags      []string  `json:"flags"`
+	Args       []string  `json:"args,omitempty"`        // Positional arguments, such as capture files
+	LogcatArgs []string  `json:"logcat_args,omitempty"` // Arguments after "--"
+}
+
+// command returns the arguments that run the entry again with extra flags
+func (e HistoryEntry) command(extra []string) []string {
+	args := slices.Concat([]string{e.Command}, e.Flags, extra, e.Args)
+	if len(e.LogcatArgs) > 0 {
+		args = slices.Concat(args, []string{"--"}, e.LogcatArgs)
+	}
+	return args
+}
+
+// String returns the entry as it would be typed
+func (e HistoryEntry) String() string {
+	return quoteArgs(e.command(nil))
+}
+
+// quoteArgs joins args as they would be typed in a shell
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\"'\\|&;<>()$`*?") {
+			quoted[i] = strconv.Quote(arg)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// historyPath returns the path of the history file, next to the default
+// configuration file
+func historyPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "logcatcolor", "history.json")
+}
+
+// loadHistory reads the remembered invocations, newest first
+func loadHistory() []HistoryEntry {
+	var history []HistoryEntry
+	if data, err := os.ReadFile(historyPath()); err == nil {
+		json.Unmarshal(data, &history)
+	}
+	return history
+}
+
+// recordHistory remembers an invocation of a command with args, parsed into
+// opts, moving an identical earlier one to the top
+func recordHistory(name string, args []string, opts LogcatOptions) {
+	path := historyPath()
+	if path == "" || len(args) == 0 || !slices.Contains(historyCommands, name) {
+		return
+	}
+	if i := slices.Index(args, "--"); i >= 0 {
+		args = args[:i]
+	}
+	if len(opts.Args) > len(args) {
+		return
+	}
+	entry := HistoryEntry{
+		Time:       time.Now(),
+		Command:    name,
+		Flags:      args[:len(args)-len(opts.Args)],
+		Args:       opts.Args,
+		LogcatArgs: opts.LogcatArgs,
+	}
+	history := slices.DeleteFunc(loadHistory(), func(e HistoryEntry) bool { return e.String() == entry.String() })
+	history = append([]HistoryEntry{entry}, history[:min(len(history), historySize-1)]...)
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+		os.WriteFile(path, data, 0o644)
+	}
+}
+
+// recallHistory replaces "--last [flags...]" and "history N [flags...]" with
+// the remembered invocation they name plus the flags
+func recallHistory(args []string) ([]string, bool) {
+	var n int
+	switch {
+	case len(args) > 0 && (args[0] == "-last" || args[0] == "--last"):
+		n, args = 1, args[1:]
+	case len(args) > 1 && args[0] == "history":
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil {
+			return nil, false
+		}
+		args = args[2:]
+	default:
+		return nil, false
+	}
+
+	history := loadHistory()
+	if n < 1 || n > len(history) {
+		if len(history) == 0 {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("No invocations remembered yet\n"))
+		} else {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("No invocation %d in the history, which has %d\n", n, len(history)))
+		}
+		os.Exit(1)
+	}
+	entry := history[n-1]
+	args = entry.command(args)
+	fmt.Fprint(os.Stderr, StatsLabelColor("logcatcolor %s\n", quoteArgs(args)))
+	return args, true
+}
+
+// runHistory lists the remembered invocations, newest first, numbered for
+// history N
+func runHistory(ctx context.Context, opts *LogcatOptions) error {
+	if len(opts.Args) > 0 && opts.Args[0] != "list" {
+		return fmt.Errorf("unknown history argument %q, expected list or a number", opts.Args[0])
+	}
+	numberColor := color.New(color.Bold).SprintfFunc()
+	for i, e := range loadHistory() {
+		fmt.Printf("%s  %s  %s\n", numberColor("%3d", i+1), StatsLabelColor("%s", e.Time.Format("2006-01-02 15:04")), e)
+	}
+	return nil
+}
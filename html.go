@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/erdichen/logcatcolor/logcat"
+)
+
+// htmlHeader starts an exported page: the terminal's color scheme, a
+// sticky filter bar and the script that applies it. %s is the title.
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { margin: 0; background: #1e1e1e; color: #d4d4d4; font: 13px/1.4 ui-monospace, Menlo, Consolas, monospace; }
+#bar { position: sticky; top: 0; display: flex; gap: 12px; align-items: center; padding: 6px 10px; background: #2d2d2d; border-bottom: 1px solid #444; font-family: sans-serif; }
+#bar input[type=search] { flex: 1; max-width: 480px; background: #1e1e1e; color: inherit; border: 1px solid #555; padding: 3px 6px; }
+#bar label { cursor: pointer; }
+#count { margin-left: auto; color: #999; }
+#log { padding: 4px 10px; white-space: pre-wrap; word-break: break-all; }
+.l { min-height: 1.4em; }
+.h { display: none; }
+.tag { color: #000; background: #11a8cd; }
+.V .lv, .V .m { color: #e5e5e5; }
+.D .lv, .D .m { color: #3b8eea; }
+.I .lv, .I .m { color: #23d18b; }
+.W .lv, .W .m { color: #e5e510; }
+.E .lv, .E .m { color: #f14c4c; }
+.F .lv, .F .m { color: #d670d6; }
+details.stack summary { color: #888; cursor: pointer; }
+</style>
+</head>
+<body>
+<div id="bar">
+<input type="search" id="q" placeholder="Filter by text or regular expression" autofocus>
+<label><input type="checkbox" class="lvl" value="V" checked>V</label>
+<label><input type="checkbox" class="lvl" value="D" checked>D</label>
+<label><input type="checkbox" class="lvl" value="I" checked>I</label>
+<label><input type="checkbox" class="lvl" value="W" checked>W</label>
+<label><input type="checkbox" class="lvl" value="E" checked>E</label>
+<label><input type="checkbox" class="lvl" value="F" checked>F</label>
+<label><input type="checkbox" id="expand">Expand stack traces</label>
+<span id="count"></span>
+</div>
+<div id="log">
+`
+
+// htmlFooter ends an exported page
+const htmlFooter = `</div>
+<script>
+const rows = Array.from(document.querySelectorAll('.l'));
+function apply() {
+	const text = document.getElementById('q').value;
+	let re = null;
+	try { re = text ? new RegExp(text, 'i') : null; } catch (e) {}
+	const levels = new Set(Array.from(document.querySelectorAll('.lvl:checked'), c => c.value));
+	let shown = 0;
+	for (const row of rows) {
+		const line = row.textContent;
+		const match = !text || (re ? re.test(line) : line.toLowerCase().includes(text.toLowerCase()));
+		const hide = !levels.has(row.dataset.level) || !match;
+		row.classList.toggle('h', hide);
+		if (!hide) shown++;
+	}
+	for (const d of document.querySelectorAll('details.stack')) {
+		d.classList.toggle('h', !d.querySelector('.l:not(.h)'));
+	}
+	document.getElementById('count').textContent = shown + ' of ' + rows.length + ' lines';
+}
+document.getElementById('q').addEventListener('input', apply);
+for (const c of document.querySelectorAll('.lvl')) c.addEventListener('change', apply);
+document.getElementById('expand').addEventListener('change', e => {
+	for (const d of document.querySelectorAll('details.stack')) d.open = e.target.checked;
+});
+apply();
+</script>
+</body>
+</html>
+`
+
+// HTMLSink writes entries as a standalone HTML page, folding the frames of
+// stack traces under their exception line
+type HTMLSink struct {
+	file   *os.File
+	w      *bufio.Writer
+	frames []string // Rows of the stack frames being folded
+	closed bool
+}
+
+// newHTMLSink creates the page at path
+func newHTMLSink(path, title string) (*HTMLSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &HTMLSink{file: f, w: bufio.NewWriter(f)}
+	fmt.Fprintf(s.w, htmlHeader, html.EscapeString(title))
+	return s, nil
+}
+
+// Write adds e as a row
+func (s *HTMLSink) Write(e logcat.Entry) error {
+	row := fmt.Sprintf(`<div class="l %s" data-level="%s">%s %5s %5s <span class="lv">%s</span> <span class="tag">%s</span> : <span class="m">%s</span></div>`+"\n",
+		e.Level, e.Level, e.Time.Format(logcat.TimeLayout), html.EscapeString(e.PID), html.EscapeString(e.TID),
+		e.Level, html.EscapeString(e.Tag), html.EscapeString(e.Message))
+	if stackFrame.MatchString(e.Message) || moreFrames.MatchString(e.Message) {
+		s.frames = append(s.frames, row)
+		return nil
+	}
+	s.flushFrames()
+	_, err := s.w.WriteString(row)
+	return err
+}
+
+// flushFrames writes the pending stack frames, folded
+func (s *HTMLSink) flushFrames() {
+	if len(s.frames) == 0 {
+		return
+	}
+	fmt.Fprintf(s.w, "<details class=\"stack\"><summary>Stack trace (%d lines)</summary>\n%s</details>\n", len(s.frames), strings.Join(s.frames, ""))
+	s.frames = nil
+}
+
+// Close finishes the page
+func (s *HTMLSink) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.flushFrames()
+	s.w.WriteString(htmlFooter)
+	if err := s.w.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// runExport converts captures into a shareable file, applying the filters
+// and rules as replay would: -html PATH writes a standalone HTML page
+func runExport(ctx context.Context, opts *LogcatOptions) error {
+	if opts.HTML == "" {
+		return fmt.Errorf("missing -html PATH to export to")
+	}
+	readers, closeAll, err := openInputs(opts.Args, opts)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	title := "logcat"
+	if len(opts.Args) > 0 {
+		names := make([]string, len(opts.Args))
+		for i, name := range opts.Args {
+			names[i] = filepath.Base(name)
+		}
+		title = strings.Join(names, ", ")
+	}
+	sink, err := newHTMLSink(opts.HTML, title)
+	if err != nil {
+		return err
+	}
+	if opts.Sinks == nil {
+		opts.Sinks = logcat.NewDispatcher()
+	}
+	opts.Sinks.Add(sink)
+	opts.SinksOnly, opts.Output, opts.Seek = true, io.Discard, false
+	opts.LocalFilter = true
+	if err := colorizeCaptures(ctx, io.MultiReader(readers...), opts); err != nil {
+		sink.Close()
+		return err
+	}
+	if err := sink.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported to %s\n", opts.HTML)
+	return nil
+}
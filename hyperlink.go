@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// sourceRefPattern matches source file references such as "MainActivity.java:123"
+var sourceRefPattern = regexp.MustCompile(`\b([\w$-]+(?:\.[\w$-]+)*\.(?:java|kt|kts|js|jsx|ts|tsx|c|cc|cpp|h|hpp|rs|go)):(\d+)\b`)
+
+// urlPattern matches http(s) URLs in log messages, ending at any escape
+// sequence
+var urlPattern = regexp.MustCompile("https?://[^\\s\"'<>\x1b]+")
+
+// hyperlinkPattern matches an OSC 8 hyperlink made by hyperlink, target and
+// text included
+var hyperlinkPattern = regexp.MustCompile("\x1b\\]8;;[^\x1b]*\x1b\\\\.*?\x1b\\]8;;\x1b\\\\")
+
+// URLColor is the color function for URLs in messages
+var URLColor = color.New(color.FgHiBlue, color.Underline).SprintfFunc()
+
+// hyperlink wraps text in an OSC 8 terminal hyperlink to target
+func hyperlink(target, text string) string {
+	return "\x1b]8;;" + target + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// linkifySourceRefs turns file:line references in a message into terminal
+// hyperlinks built from template, where {file} and {line} are substituted.
+// References inside URLs are left for colorizeMessage to link with the URL.
+func linkifySourceRefs(message, template string) string {
+	if template == "" || color.NoColor {
+		return message
+	}
+
+	urls := urlPattern.FindAllStringIndex(message, -1)
+	var sb strings.Builder
+	last := 0
+	for _, m := range sourceRefPattern.FindAllStringSubmatchIndex(message, -1) {
+		if overlaps(urls, m[0], m[1]) {
+			continue
+		}
+		target := strings.NewReplacer("{file}", url.QueryEscape(message[m[2]:m[3]]), "{line}", message[m[4]:m[5]]).Replace(template)
+		sb.WriteString(message[last:m[0]])
+		sb.WriteString(hyperlink(target, message[m[0]:m[1]]))
+		last = m[1]
+	}
+	sb.WriteString(message[last:])
+	return sb.String()
+}
+
+// overlaps reports whether the range from start to end overlaps any of spans
+func overlaps(spans [][]int, start, end int) bool {
+	for _, s := range spans {
+		if start < s[1] && s[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// colorizeMessage applies colorFunc to a message, styling any URLs distinctly
+// and making them clickable in terminals that support OSC 8. URLs inside
+// hyperlinks already made, such as their targets, are left alone.
+func colorizeMessage(message string, colorFunc func(format string, a ...any) string) string {
+	links := hyperlinkPattern.FindAllStringIndex(message, -1)
+	matches := slices.DeleteFunc(urlPattern.FindAllStringIndex(message, -1), func(m []int) bool {
+		return overlaps(links, m[0], m[1])
+	})
+	if len(matches) == 0 {
+		return colorFunc("%s", message)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		// Leave trailing punctuation out of the URL
+		end := m[1]
+		for end > m[0] && strings.ContainsRune(".,;:!?)]}", rune(message[end-1])) {
+			end--
+		}
+		if last < m[0] {
+			sb.WriteString(colorFunc("%s", message[last:m[0]]))
+		}
+		u := message[m[0]:end]
+		if color.NoColor {
+			sb.WriteString(u)
+		} else {
+			sb.WriteString(hyperlink(u, URLColor("%s", u)))
+		}
+		last = end
+	}
+	if last < len(message) {
+		sb.WriteString(colorFunc("%s", message[last:]))
+	}
+	return sb.String()
+}
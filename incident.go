@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// IncidentRecorder saves the lines around each crash, ANR or native crash
+// into its own file: the lead-up from the ring buffer and the lines after
+type IncidentRecorder struct {
+	BeforeLines int           // Lines of lead-up to save, if BeforeTime is zero
+	BeforeTime  time.Duration // Time of lead-up to save
+	AfterLines  int           // Lines to save after the trigger
+
+	dir       string
+	file      *CaptureFile // Incident being written, nil if none
+	path      string
+	remaining int
+	count     int // Incidents saved so far, numbering the files
+}
+
+// newIncidentRecorder parses -incident-before, a line count or a duration
+// such as 30s, and returns a recorder saving into dir
+func newIncidentRecorder(before string, after int, dir string) (*IncidentRecorder, error) {
+	r := &IncidentRecorder{AfterLines: after, dir: dir}
+	if n, err := strconv.Atoi(before); err == nil {
+		r.BeforeLines = n
+	} else if d, err := time.ParseDuration(before); err == nil {
+		r.BeforeTime = d
+	} else {
+		return nil, fmt.Errorf("invalid -incident-before %q, expected a line count or a duration", before)
+	}
+	return r, nil
+}
+
+// Observe writes line to the incident being recorded, or starts an incident
+// if line begins a crash. The ring buffer must already hold line.
+func (r *IncidentRecorder) Observe(line string, opts LogcatOptions) {
+	if r.file != nil {
+		r.write(line, opts)
+		return
+	}
+
+	entry, ok := parseLogLine(line)
+	if !ok {
+		return
+	}
+	kind := crashKind(entry)
+	if kind == "" {
+		return
+	}
+
+	r.count++
+	r.path = filepath.Join(r.dir, fmt.Sprintf("incident-%s-%d-%s.log", time.Now().Format("20060102-150405"), r.count, kind))
+	file, err := createCapture(r.path)
+	if err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error creating incident file: %v\n", err))
+		return
+	}
+	r.file = file
+
+	lead := []string{line}
+	if opts.Ring != nil {
+		lead = r.leadUp(opts.Ring.Lines(), entry.Time)
+	}
+	for _, l := range lead {
+		if err := r.file.WriteLine(l, opts); err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error writing incident file: %v\n", err))
+			r.Close()
+			return
+		}
+	}
+	if r.remaining = r.AfterLines; r.remaining <= 0 {
+		r.Close()
+	}
+}
+
+// leadUp returns the buffered lines to save before a trigger at time t,
+// ending with the trigger line itself
+func (r *IncidentRecorder) leadUp(lines []string, t time.Time) []string {
+	if r.BeforeTime == 0 {
+		return lines[max(len(lines)-r.BeforeLines-1, 0):]
+	}
+
+	// Keep lines from the window, with continuation lines following the
+	// timestamped line before them
+	start := len(lines) - 1
+	for i := len(lines) - 1; i >= 0; i-- {
+		lt, err := parseTimestamp(lines[i])
+		if err != nil {
+			continue
+		}
+		if t.Sub(lt) > r.BeforeTime {
+			break
+		}
+		start = i
+	}
+	return lines[start:]
+}
+
+// write adds a line to the current incident, finishing it once the lines
+// after the trigger are saved
+func (r *IncidentRecorder) write(line string, opts LogcatOptions) {
+	if err := r.file.WriteLine(line, opts); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error writing incident file: %v\n", err))
+		r.Close()
+		return
+	}
+	if r.remaining--; r.remaining <= 0 {
+		r.Close()
+	}
+}
+
+// Close finishes the incident being recorded, if any
+func (r *IncidentRecorder) Close() {
+	if r.file == nil {
+		return
+	}
+	if err := r.file.Close(); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error closing incident file: %v\n", err))
+	} else {
+		fmt.Fprintf(os.Stderr, "Incident saved to %s\n", r.path)
+	}
+	r.file = nil
+}
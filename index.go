@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"time"
+)
+
+// indexBlockLines is the number of lines summarized by each index block
+const indexBlockLines = 1024
+
+// indexBloomBytes is the size of each block's trigram Bloom filter
+const indexBloomBytes = 8192
+
+// indexSuffix is appended to a capture's name to name its index
+const indexSuffix = ".lcidx"
+
+// indexVersion is the version of the index format; indexes of other
+// versions are ignored
+const indexVersion = 2
+
+// CaptureIndex summarizes blocks of an uncompressed capture so that queries
+// can skip blocks that cannot match
+type CaptureIndex struct {
+	Version int          `json:"version"`
+	Size    int64        `json:"size"` // Size of the capture when indexed
+	ModTime time.Time    `json:"modTime"`
+	Header  int64        `json:"header"` // Length of the metadata header, which is always read
+	Blocks  []IndexBlock `json:"blocks"`
+}
+
+// IndexBlock describes a run of whole lines in a capture
+type IndexBlock struct {
+	Offset   int64     `json:"offset"`
+	Length   int64     `json:"length"`
+	First    time.Time `json:"first"` // Earliest timestamp in the block
+	Last     time.Time `json:"last"`  // Latest timestamp in the block
+	Tags     []string  `json:"tags"`
+	Levels   string    `json:"levels"`   // Levels present, in levelOrder
+	Trigrams []byte    `json:"trigrams"` // Bloom filter of the message trigrams
+	Escaped  bool      `json:"escaped"`  // Holds lines that prepareLine changes, which are never pruned
+}
+
+// trigramBits returns the two Bloom filter bits for a trigram
+func trigramBits(t string) (uint32, uint32) {
+	h := fnv.New32a()
+	h.Write([]byte(t))
+	sum := h.Sum32()
+	const bits = indexBloomBytes * 8
+	return sum % bits, (sum>>16 | sum<<16) * 0x9e3779b1 % bits
+}
+
+// addTrigrams adds the trigrams of s to a Bloom filter
+func addTrigrams(bloom []byte, s string) {
+	for i := 0; i+3 <= len(s); i++ {
+		a, b := trigramBits(s[i : i+3])
+		bloom[a/8] |= 1 << (a % 8)
+		bloom[b/8] |= 1 << (b % 8)
+	}
+}
+
+// mayContain reports whether a block may contain every trigram of s
+func (b IndexBlock) mayContain(s string) bool {
+	for i := 0; i+3 <= len(s); i++ {
+		x, y := trigramBits(s[i : i+3])
+		if b.Trigrams[x/8]&(1<<(x%8)) == 0 || b.Trigrams[y/8]&(1<<(y%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// buildIndex reads a capture and summarizes each block of lines
+func buildIndex(path string) (*CaptureIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(f)
+	if header, _ := br.Peek(4); string(header[:min(len(header), 2)]) == string(gzipMagic) || string(header) == string(zstdMagic) {
+		return nil, fmt.Errorf("%s is compressed; only uncompressed captures can be indexed", path)
+	}
+
+	idx := &CaptureIndex{Version: indexVersion, Size: info.Size(), ModTime: info.ModTime()}
+
+	// Keep the metadata header out of the blocks so that it is always read
+	if first, err := br.Peek(len(captureHeaderMagic)); err == nil && string(first) == captureHeaderMagic {
+		for {
+			line, _ := br.ReadString('\n')
+			idx.Header += int64(len(line))
+			if next, err := br.Peek(2); err != nil || string(next) != "# " {
+				break
+			}
+		}
+	}
+
+	var block *IndexBlock
+	tags := make(map[string]bool)
+	lines := 0
+	offset := idx.Header
+	finish := func() {
+		if block == nil {
+			return
+		}
+		block.Tags = make([]string, 0, len(tags))
+		for tag := range tags {
+			block.Tags = append(block.Tags, tag)
+		}
+		slices.Sort(block.Tags)
+		idx.Blocks = append(idx.Blocks, *block)
+		block, lines = nil, 0
+		clear(tags)
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if block == nil {
+				block = &IndexBlock{Offset: offset, Trigrams: make([]byte, indexBloomBytes)}
+			}
+			block.Length += int64(len(line))
+			offset += int64(len(line))
+
+			text := strings.TrimRight(line, "\r\n")
+			if strings.Contains(text, "\x1b") || !isPrintable(text) {
+				block.Escaped = true
+			}
+			if entry, ok := parseLogLine(text); ok {
+				if block.First.IsZero() || entry.Time.Before(block.First) {
+					block.First = entry.Time
+				}
+				if entry.Time.After(block.Last) {
+					block.Last = entry.Time
+				}
+				tags[entry.Tag] = true
+				if !strings.Contains(block.Levels, entry.Level) {
+					block.Levels += entry.Level
+				}
+				addTrigrams(block.Trigrams, entry.Message)
+			} else {
+				addTrigrams(block.Trigrams, text)
+			}
+			if lines++; lines >= indexBlockLines {
+				finish()
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	finish()
+	return idx, nil
+}
+
+// loadIndex returns the index of a capture if one exists and is current
+func loadIndex(path string) (*CaptureIndex, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(path + indexSuffix)
+	if err != nil {
+		return nil, false
+	}
+	var idx CaptureIndex
+	if err := json.Unmarshal(data, &idx); err != nil || idx.Version != indexVersion {
+		return nil, false
+	}
+	if idx.Size != info.Size() || !idx.ModTime.Equal(info.ModTime()) {
+		fmt.Fprint(os.Stderr, LogLevelColors["W"]("Index of %s is out of date; run logcatcolor index again\n", path))
+		return nil, false
+	}
+	return &idx, true
+}
+
+// requiredLiterals returns strings every match of a regular expression
+// must contain, for pruning blocks by trigram
+func requiredLiterals(pattern string) []string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	re = re.Simplify()
+	parts := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		parts = re.Sub
+	}
+	var literals []string
+	for _, part := range parts {
+		if part.Op == syntax.OpLiteral && part.Flags&syntax.FoldCase == 0 {
+			literals = append(literals, string(part.Rune))
+		}
+	}
+	return literals
+}
+
+// selectBlocks returns the blocks that may hold lines passing the query's
+// tag, level, message and time filters. The index describes the lines as
+// saved, so blocks whose lines prepareLine changes are always kept, all of
+// them when redactions may rewrite any line, and times are not compared when
+// -skew or -sync-marker moves them after reading.
+func (idx *CaptureIndex) selectBlocks(opts LogcatOptions) []IndexBlock {
+	if len(opts.Redactions) > 0 {
+		return idx.Blocks
+	}
+	var literals []string
+	if opts.Grep != nil {
+		literals = requiredLiterals(opts.Grep.String())
+	}
+	// Severity remapping can change levels after indexing
+	filterLevels := opts.Level != "" && len(opts.Severities) == 0
+	filterTimes := !opts.Skew && opts.SyncMarker == nil
+
+	var selected []IndexBlock
+	for _, b := range idx.Blocks {
+		switch {
+		case b.Escaped:
+			// Kept, as its lines are matched only once prepareLine changed them
+		case opts.Tag != "" && !slices.Contains(b.Tags, opts.Tag):
+			continue
+		case filterLevels && !strings.ContainsFunc(b.Levels, func(l rune) bool { return !levelBelow(string(l), opts.Level) }):
+			continue
+		case filterTimes && !b.First.IsZero() && !opts.Window.Overlaps(b.First, b.Last):
+			continue
+		case slices.ContainsFunc(literals, func(s string) bool { return !b.mayContain(s) }):
+			continue
+		}
+		selected = append(selected, b)
+	}
+	return selected
+}
+
+// Overlaps reports whether any time between first and last may fall in the
+// window. Without dates, blocks spanning midnight always may.
+func (w TimeWindow) Overlaps(first, last time.Time) bool {
+	if !w.Dated && (first.Month() != last.Month() || first.Day() != last.Day()) {
+		return true
+	}
+	if !w.Dated {
+		first = time.Date(0, 1, 1, first.Hour(), first.Minute(), first.Second(), first.Nanosecond(), time.UTC)
+		last = time.Date(0, 1, 1, last.Hour(), last.Minute(), last.Second(), last.Nanosecond(), time.UTC)
+	}
+	return (w.To.IsZero() || !first.After(w.To)) && (w.From.IsZero() || !last.Before(w.From))
+}
+
+// indexedReader reads only the selected blocks of an indexed capture
+func indexedReader(f *os.File, idx *CaptureIndex, opts LogcatOptions) io.Reader {
+	readers := []io.Reader{io.NewSectionReader(f, 0, idx.Header)}
+	for _, b := range idx.selectBlocks(opts) {
+		readers = append(readers, io.NewSectionReader(f, b.Offset, b.Length))
+	}
+	return io.MultiReader(readers...)
+}
+
+// runIndex builds an index next to each capture for faster query and replay
+func runIndex(ctx context.Context, opts *LogcatOptions) error {
+	if len(opts.Args) == 0 {
+		return fmt.Errorf("index needs one or more capture files")
+	}
+	for _, path := range opts.Args {
+		idx, err := buildIndex(path)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(idx)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path+indexSuffix, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Indexed %s: %d blocks in %s\n", path, len(idx.Blocks), path+indexSuffix)
+	}
+	return nil
+}
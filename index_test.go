@@ -0,0 +1,123 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRequiredLiterals(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"timeout", []string{"timeout"}},
+		{"connection (reset|refused)", []string{"connection "}},
+		{`took \d+ms`, []string{"took ", "ms"}},
+		{"^ANR in ", []string{"ANR in "}},
+		{"(?i)timeout", nil},
+		{"a|b", nil},
+		{".*", nil},
+		{"(", nil},
+	}
+	for _, tt := range tests {
+		if got := requiredLiterals(tt.pattern); !slices.Equal(got, tt.want) {
+			t.Errorf("requiredLiterals(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+// testClock parses a logcat time or a -from/-to value
+func testClock(t *testing.T, s string) time.Time {
+	t.Helper()
+	c, _, err := parseClock(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// testBlock returns an index block of lines logged between first and last
+// with the given tags, levels and messages
+func testBlock(t *testing.T, first, last string, tags []string, levels string, messages ...string) IndexBlock {
+	b := IndexBlock{First: testClock(t, first), Last: testClock(t, last), Tags: tags, Levels: levels, Trigrams: make([]byte, indexBloomBytes)}
+	for _, m := range messages {
+		addTrigrams(b.Trigrams, m)
+	}
+	return b
+}
+
+func TestSelectBlocks(t *testing.T) {
+	idx := &CaptureIndex{Blocks: []IndexBlock{
+		testBlock(t, "04-19 10:00:00", "04-19 10:59:59", []string{"ActivityManager", "MyApp"}, "DIW", "Start proc 1234:com.example", "hello world"),
+		testBlock(t, "04-19 11:00:00", "04-19 11:59:59", []string{"MyApp"}, "IE", "request timed out"),
+		testBlock(t, "04-19 23:30:00", "04-20 00:30:00", []string{"Other"}, "V", "across midnight"),
+	}}
+	idx.Blocks[1].Escaped = true
+
+	tests := []struct {
+		name string
+		opts LogcatOptions
+		want []int
+	}{
+		{name: "everything", want: []int{0, 1, 2}},
+		{name: "tag", opts: LogcatOptions{Tag: "ActivityManager"}, want: []int{0, 1}},
+		{name: "level", opts: LogcatOptions{Level: "E"}, want: []int{1}},
+		{name: "level remapped", opts: LogcatOptions{Level: "E", Severities: []SeverityRule{{}}}, want: []int{0, 1, 2}},
+		{name: "grep", opts: LogcatOptions{Grep: regexp.MustCompile("hello w.rld")}, want: []int{0, 1}},
+		{name: "grep case-insensitive", opts: LogcatOptions{Grep: regexp.MustCompile("(?i)ACROSS")}, want: []int{0, 1, 2}},
+		{name: "from", opts: LogcatOptions{Window: TimeWindow{From: testClock(t, "11:30")}}, want: []int{1, 2}},
+		{name: "to", opts: LogcatOptions{Window: TimeWindow{To: testClock(t, "10:30")}}, want: []int{0, 1, 2}},
+		{name: "dated", opts: LogcatOptions{Window: TimeWindow{From: testClock(t, "04-20 00:00"), Dated: true}}, want: []int{1, 2}},
+		{name: "skew", opts: LogcatOptions{Window: TimeWindow{From: testClock(t, "11:30")}, Skew: true}, want: []int{0, 1, 2}},
+		{name: "sync marker", opts: LogcatOptions{Window: TimeWindow{To: testClock(t, "09:00")}, SyncMarker: regexp.MustCompile("tap")}, want: []int{0, 1, 2}},
+		{name: "redactions", opts: LogcatOptions{Tag: "Nothing", Redactions: builtinRedactions}, want: []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		var got []int
+		for _, b := range idx.selectBlocks(tt.opts) {
+			got = append(got, slices.IndexFunc(idx.Blocks, func(c IndexBlock) bool { return c.Offset == b.Offset && c.First.Equal(b.First) }))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: selectBlocks() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		from, to    string
+		dated       bool
+		first, last string
+		want        bool
+	}{
+		{"", "", false, "04-19 10:00:00", "04-19 11:00:00", true},
+		{"10:30", "", false, "04-19 10:00:00", "04-19 11:00:00", true},
+		{"11:00", "", false, "04-19 10:00:00", "04-19 11:00:00", true},
+		{"11:00:01", "", false, "04-19 10:00:00", "04-19 11:00:00", false},
+		{"", "10:00", false, "04-19 10:00:00", "04-19 11:00:00", true},
+		{"", "09:59:59", false, "04-19 10:00:00", "04-19 11:00:00", false},
+		{"10:15", "10:45", false, "04-19 10:00:00", "04-19 11:00:00", true},
+		{"12:00", "13:00", false, "04-19 10:00:00", "04-19 11:00:00", false},
+		{"12:00", "13:00", false, "04-19 23:00:00", "04-20 01:00:00", true}, // Across midnight, without dates
+		{"04-19 12:00", "", true, "04-19 10:00:00", "04-19 11:00:00", false},
+		{"04-18 12:00", "", true, "04-19 10:00:00", "04-19 11:00:00", true},
+		{"", "04-18 12:00", true, "04-19 10:00:00", "04-19 11:00:00", false},
+		{"04-20 00:30", "", true, "04-19 23:00:00", "04-20 01:00:00", true},
+		{"04-20 02:00", "", true, "04-19 23:00:00", "04-20 01:00:00", false},
+	}
+	for _, tt := range tests {
+		var w TimeWindow
+		if tt.from != "" {
+			w.From = testClock(t, tt.from)
+		}
+		if tt.to != "" {
+			w.To = testClock(t, tt.to)
+		}
+		w.Dated = tt.dated
+		if got := w.Overlaps(testClock(t, tt.first), testClock(t, tt.last)); got != tt.want {
+			t.Errorf("window %q-%q (dated %v) overlaps %s-%s = %v, want %v", tt.from, tt.to, tt.dated, tt.first, tt.last, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// initTopTags is the number of busiest tags the init wizard offers to hide
+const initTopTags = 15
+
+// profileFilter is the filter built by the init wizard
+type profileFilter struct {
+	minLevel string
+	hidden   []string
+	grep     *regexp.Regexp
+}
+
+// keeps reports whether the filter shows entry
+func (f profileFilter) keeps(entry logLine) bool {
+	return !levelBelow(entry.Level, f.minLevel) && !slices.Contains(f.hidden, entry.Tag) &&
+		(f.grep == nil || f.grep.MatchString(entry.Message))
+}
+
+// profile returns the filter as a configuration profile
+func (f profileFilter) profile() ProfileConfig {
+	var p ProfileConfig
+	if f.minLevel != "" {
+		p.Args = append(p.Args, "-l", f.minLevel)
+	}
+	if f.grep != nil {
+		p.Args = append(p.Args, "-grep", f.grep.String())
+	}
+	if len(f.hidden) > 0 {
+		p.Tags = make(map[string]TagConfig)
+		for _, tag := range f.hidden {
+			p.Tags[tag] = TagConfig{Hide: true}
+		}
+	}
+	return p
+}
+
+// runInit samples the device's log buffers, or the captures given, shows the
+// busiest tags and levels, then asks for a filter and saves it as a profile
+func runInit(ctx context.Context, opts *LogcatOptions) error {
+	if opts.ConfigPath == "" {
+		return errors.New("no configuration file to save the profile in; give one with -config")
+	}
+	entries, err := sampleEntries(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return errors.New("no log lines to sample")
+	}
+
+	levels := make(map[string]int)
+	tags := make(map[string]int)
+	for _, entry := range entries {
+		levels[entry.Level]++
+		tags[entry.Tag]++
+	}
+	busiest := slices.SortedFunc(maps.Keys(tags), func(a, b string) int {
+		return cmp.Or(cmp.Compare(tags[b], tags[a]), cmp.Compare(a, b))
+	})
+	busiest = busiest[:min(len(busiest), initTopTags)]
+
+	numberColor := color.New(color.Bold).SprintfFunc()
+	fmt.Printf("Sampled %s lines from %d tags\n\nLevels:", StatsValueColor("%d", len(entries)), len(tags))
+	for _, l := range levelOrder {
+		if n := levels[string(l)]; n > 0 {
+			fmt.Printf("  %s %d", LogLevelColors[string(l)]("%c", l), n)
+		}
+	}
+	fmt.Printf("\n\nBusiest tags:\n")
+	for i, tag := range busiest {
+		fmt.Printf("%s %-32s %7d  %s\n", numberColor("%3d", i+1), tag, tags[tag],
+			StatsLabelColor("%4.1f%%", 100*float64(tags[tag])/float64(len(entries))))
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	var filter profileFilter
+	report := func() {
+		kept := 0
+		for _, entry := range entries {
+			if filter.keeps(entry) {
+				kept++
+			}
+		}
+		fmt.Printf("Keeps %s of %d sampled lines\n", StatsValueColor("%d", kept), len(entries))
+	}
+
+	for {
+		answer := strings.ToUpper(ask(in, "\nMinimum level, V D I W E or F (Enter for all): "))
+		if _, ok := LogLevelColors[answer]; answer == "" || ok {
+			filter.minLevel = answer
+			break
+		}
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid level %q\n", answer))
+	}
+	report()
+
+	for {
+		answer := ask(in, "\nTags to hide, by number or name, separated by spaces (Enter for none): ")
+		hidden, err := parseTagChoices(answer, busiest)
+		if err == nil {
+			filter.hidden = hidden
+			break
+		}
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("%v\n", err))
+	}
+	report()
+
+	for {
+		answer := ask(in, "\nOnly show messages matching this regular expression (Enter for all): ")
+		if answer == "" {
+			break
+		}
+		re, err := regexp.Compile(answer)
+		if err == nil {
+			filter.grep = re
+			break
+		}
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid regular expression: %v\n", err))
+	}
+	report()
+
+	cfg, err := LoadConfig(opts.ConfigPath, false)
+	if err != nil {
+		return err
+	}
+	name := cmp.Or(ask(in, "\nProfile name (Enter for default): "), "default")
+	if _, ok := cfg.Profiles[name]; ok && !strings.HasPrefix(strings.ToLower(ask(in, fmt.Sprintf("Replace profile %s? [y/N] ", name))), "y") {
+		fmt.Println("Profile not saved")
+		return nil
+	}
+	if err := saveProfile(opts.ConfigPath, name, filter.profile()); err != nil {
+		return err
+	}
+	fmt.Printf("Saved profile %s to %s; use it with -p %s\n", name, opts.ConfigPath, name)
+	return nil
+}
+
+// sampleEntries reads the captures given or, without any, the device's log
+// buffers as they are now
+func sampleEntries(ctx context.Context, opts *LogcatOptions) ([]logLine, error) {
+	var r io.Reader
+	if len(opts.Args) > 0 {
+		readers, closeAll, err := openInputs(opts.Args, opts)
+		if err != nil {
+			return nil, err
+		}
+		defer closeAll()
+		r = io.MultiReader(readers...)
+	} else {
+		if opts.Device == "" && opts.Transport == "" {
+			transport, _, err := selectDevice(*opts, opts.Selection)
+			if err != nil {
+				return nil, err
+			}
+			opts.Transport = transport
+		}
+		opts.Dump = true
+		stream, err := adbSource{*opts}.Open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer stream.Close()
+		r = stream
+	}
+
+	var entries []logLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if entry, ok := parseLogLine(scanner.Text()); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// ask prints prompt and returns the line typed, or "" at the end of input
+func ask(in *bufio.Scanner, prompt string) string {
+	fmt.Print(prompt)
+	if !in.Scan() {
+		fmt.Println()
+		return ""
+	}
+	return strings.TrimSpace(in.Text())
+}
+
+// parseTagChoices resolves the numbers of the busiest tags listed and tag
+// names typed
+func parseTagChoices(answer string, busiest []string) ([]string, error) {
+	var tags []string
+	for _, choice := range strings.Fields(answer) {
+		tag := choice
+		if n, err := strconv.Atoi(choice); err == nil {
+			if n < 1 || n > len(busiest) {
+				return nil, fmt.Errorf("no tag numbered %d", n)
+			}
+			tag = busiest[n-1]
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// saveProfile adds the profile to the configuration file at path, or
+// replaces the one of the same name, keeping the file's other settings
+func saveProfile(path, name string, p ProfileConfig) error {
+	// Drop the profile's unset fields
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	var fields any
+	json.Unmarshal(data, &fields)
+	if data, err = json.Marshal(pruneJSON(fields)); err != nil {
+		return err
+	}
+
+	return editConfig(path, func(settings map[string]json.RawMessage) error {
+		return editSetting(settings, "profiles", func(profiles map[string]json.RawMessage) error {
+			profiles[name] = data
+			return nil
+		})
+	})
+}
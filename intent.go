@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Colors of the parts of Intent and Bundle dumps
+var (
+	IntentKeyColor       = color.New(color.Faint).SprintfFunc()
+	IntentActionColor    = color.New(color.FgYellow).SprintfFunc()
+	IntentComponentColor = color.New(color.FgCyan, color.Bold).SprintfFunc()
+	IntentFlagsColor     = color.New(color.FgMagenta).SprintfFunc()
+	IntentExtraColor     = color.New(color.FgMagenta).SprintfFunc()
+)
+
+// intentBody matches the body of an Intent's toString, as in
+// "Intent { act=... cmp=... (has extras) }" and ActivityTaskManager's
+// "START u0 {act=... flg=0x10000000 cmp=...}"
+var intentBody = regexp.MustCompile(`\{ ?(?:act|cat|dat|typ|flg|pkg|cmp)=[^{}]*(?:\{[^{}]*\}[^{}]*)*\}`)
+
+// intentField matches a field of an Intent, capturing its name and value
+var intentField = regexp.MustCompile(`\b(act|cat|dat|typ|flg|xflg|pkg|cmp|sel|bnds|clip|launchParam)=(\[[^\]]*\]|\S+?)(?:[ }]|$)|\((has extras|has clip|extras)\)`)
+
+// bundleBody matches a Bundle's toString, "Bundle[{key=value, ...}]", and
+// bundleKey the keys in it
+var (
+	bundleBody = regexp.MustCompile(`Bundle\[\{[^{}]*(?:\{[^{}]*\}[^{}]*)*\}\]`)
+	bundleKey  = regexp.MustCompile(`(?:^Bundle\[\{|, )([\w.$:-]+)=`)
+)
+
+// intentFlags names the bits of an Intent's flags, taking those shared by
+// activity and broadcast flags as activity flags, which start intents log
+var intentFlags = []struct {
+	bit  uint32
+	name string
+}{
+	{0x00000001, "FLAG_GRANT_READ_URI_PERMISSION"},
+	{0x00000002, "FLAG_GRANT_WRITE_URI_PERMISSION"},
+	{0x00000004, "FLAG_FROM_BACKGROUND"},
+	{0x00000008, "FLAG_DEBUG_LOG_RESOLUTION"},
+	{0x00000010, "FLAG_EXCLUDE_STOPPED_PACKAGES"},
+	{0x00000020, "FLAG_INCLUDE_STOPPED_PACKAGES"},
+	{0x00000040, "FLAG_GRANT_PERSISTABLE_URI_PERMISSION"},
+	{0x00000080, "FLAG_GRANT_PREFIX_URI_PERMISSION"},
+	{0x00000100, "FLAG_DIRECT_BOOT_AUTO"},
+	{0x00000200, "FLAG_ACTIVITY_REQUIRE_DEFAULT"},
+	{0x00000400, "FLAG_ACTIVITY_REQUIRE_NON_BROWSER"},
+	{0x00000800, "FLAG_ACTIVITY_MATCH_EXTERNAL"},
+	{0x00001000, "FLAG_ACTIVITY_LAUNCH_ADJACENT"},
+	{0x00002000, "FLAG_ACTIVITY_RETAIN_IN_RECENTS"},
+	{0x00004000, "FLAG_ACTIVITY_TASK_ON_HOME"},
+	{0x00008000, "FLAG_ACTIVITY_CLEAR_TASK"},
+	{0x00010000, "FLAG_ACTIVITY_NO_ANIMATION"},
+	{0x00020000, "FLAG_ACTIVITY_REORDER_TO_FRONT"},
+	{0x00040000, "FLAG_ACTIVITY_NO_USER_ACTION"},
+	{0x00080000, "FLAG_ACTIVITY_NEW_DOCUMENT"},
+	{0x00100000, "FLAG_ACTIVITY_LAUNCHED_FROM_HISTORY"},
+	{0x00200000, "FLAG_ACTIVITY_RESET_TASK_IF_NEEDED"},
+	{0x00400000, "FLAG_ACTIVITY_BROUGHT_TO_FRONT"},
+	{0x00800000, "FLAG_ACTIVITY_EXCLUDE_FROM_RECENTS"},
+	{0x01000000, "FLAG_ACTIVITY_PREVIOUS_IS_TOP"},
+	{0x02000000, "FLAG_ACTIVITY_FORWARD_RESULT"},
+	{0x04000000, "FLAG_ACTIVITY_CLEAR_TOP"},
+	{0x08000000, "FLAG_ACTIVITY_MULTIPLE_TASK"},
+	{0x10000000, "FLAG_ACTIVITY_NEW_TASK"},
+	{0x20000000, "FLAG_ACTIVITY_SINGLE_TOP"},
+	{0x40000000, "FLAG_ACTIVITY_NO_HISTORY"},
+}
+
+// describeIntentFlags names the bits set in an Intent's hex flags, leaving
+// unknown bits in hex
+func describeIntentFlags(value string) (string, bool) {
+	flags, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 32)
+	if err != nil || flags == 0 {
+		return "", false
+	}
+	var names []string
+	for _, f := range intentFlags {
+		if uint32(flags)&f.bit != 0 {
+			names = append(names, f.name)
+			flags &^= uint64(f.bit)
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("0x%x", flags))
+	}
+	return strings.Join(names, "|"), true
+}
+
+// textSpan is a colored replacement for message[start:end]
+type textSpan struct {
+	start, end int
+	text       string
+}
+
+// renderSpans colors a message with the spans replacing their parts and
+// colorFunc coloring the rest
+func renderSpans(message string, spans []textSpan, colorFunc func(format string, a ...any) string) string {
+	slices.SortFunc(spans, func(a, b textSpan) int { return a.start - b.start })
+	var sb strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			continue
+		}
+		if last < s.start {
+			sb.WriteString(colorFunc("%s", message[last:s.start]))
+		}
+		sb.WriteString(s.text)
+		last = s.end
+	}
+	if last < len(message) {
+		sb.WriteString(colorFunc("%s", message[last:]))
+	}
+	return sb.String()
+}
+
+// prettifyIntent colors the fields of Intent dumps and the keys of Bundle
+// dumps in a message. With expandFlags, Intent flags are followed by their
+// names.
+func prettifyIntent(message string, colorFunc func(format string, a ...any) string, expandFlags bool) (string, bool) {
+	var spans []textSpan
+	for _, body := range intentBody.FindAllStringIndex(message, -1) {
+		for _, m := range intentField.FindAllStringSubmatchIndex(message[body[0]:body[1]], -1) {
+			if m[6] >= 0 {
+				spans = append(spans, textSpan{body[0] + m[0], body[0] + m[1], IntentExtraColor("%s", message[body[0]+m[0]:body[0]+m[1]])})
+				continue
+			}
+			key, value := message[body[0]+m[2]:body[0]+m[3]], message[body[0]+m[4]:body[0]+m[5]]
+			var text string
+			switch key {
+			case "act":
+				text = IntentActionColor("%s", value)
+			case "cmp", "pkg":
+				text = IntentComponentColor("%s", value)
+			case "flg", "xflg":
+				text = IntentFlagsColor("%s", value)
+				if names, ok := describeIntentFlags(value); ok && expandFlags {
+					text += " " + IntentKeyColor("[%s]", names)
+				}
+			default:
+				text = colorFunc("%s", value)
+			}
+			spans = append(spans, textSpan{body[0] + m[2], body[0] + m[5], IntentKeyColor("%s=", key) + text})
+		}
+	}
+	for _, body := range bundleBody.FindAllStringIndex(message, -1) {
+		for _, m := range bundleKey.FindAllStringSubmatchIndex(message[body[0]:body[1]], -1) {
+			start, end := body[0]+m[2], body[0]+m[3]
+			spans = append(spans, textSpan{start, end, IntentExtraColor("%s", message[start:end])})
+		}
+	}
+	if len(spans) == 0 {
+		return "", false
+	}
+	return renderSpans(message, spans, colorFunc), true
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jobTags matches the tags of JobScheduler and WorkManager
+var jobTags = regexp.MustCompile(`^(?:JobScheduler.*|JobServiceContext|JobStore|WM-.*|WorkManager.*)$`)
+
+// jobID matches a WorkManager work UUID or a JobScheduler job number
+const jobID = `([0-9a-fA-F]{8}(?:-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}|\d+)`
+
+// jobPattern compiles expr with {id} standing for jobID
+func jobPattern(expr string) *regexp.Regexp {
+	return regexp.MustCompile(strings.ReplaceAll(expr, "{id}", jobID))
+}
+
+// jobTransition is a step in a job's life and the messages reporting it,
+// which capture the job or work ID
+type jobTransition struct {
+	kind    string
+	color   func(format string, a ...any) string
+	done    bool // The job is no longer running
+	pattern *regexp.Regexp
+}
+
+// jobTransitions are checked in order; the first match names the step
+var jobTransitions = []jobTransition{
+	{"succeeded", NetUpColor, true, jobPattern(`Worker result SUCCESS for Work \[ id={id}`)},
+	{"failed", NetDownColor, true, jobPattern(`Worker result FAILURE for Work \[ id={id}`)},
+	{"retry", NetChangeColor, true, jobPattern(`Worker result RETRY for Work \[ id={id}`)},
+	{"cancelled", NetDownColor, true, jobPattern(`Work \[ id={id}.*\] was cancelled`)},
+	{"stopped", NetChangeColor, true, jobPattern(`(?i)(?:StopWorkRunnable for|Stopping (?:work|job):?|onStopJob,? (?:job ?)?id[=: ]*)\s*{id}`)},
+	{"finished", NetUpColor, true, jobPattern(`(?i)(?:Finished job|jobFinished,? (?:job ?)?id[=: ]*)\s*#?(?:\S+/)?{id}`)},
+	{"started", NetUpColor, false, jobPattern(`(?i)(?:Starting work for|Processor: processing(?: WorkGenerationalId\(workSpecId=)?|(?:Starting|Running) job:?\s*#?(?:\S+/)?)\s*{id}`)},
+	{"enqueued", NetChangeColor, false, jobPattern(`(?i)(?:Scheduling work ID|Scheduling job:?|enqueued? (?:work|job):?)\s*(?:id[=: ]*)?{id}`)},
+}
+
+// jobWorker captures the worker class from a WorkManager "tags={ ... }" list
+var jobWorker = regexp.MustCompile(`tags=\{ ([\w.$]+)`)
+
+// JobTracker follows JobScheduler and WorkManager jobs for -jobs, printing a
+// row for each step with how long a finished job ran
+type JobTracker struct {
+	mu      sync.Mutex
+	started map[string]time.Time
+	names   map[string]string // Worker class by work ID
+}
+
+// newJobTracker returns a tracker with no jobs
+func newJobTracker() *JobTracker {
+	return &JobTracker{started: make(map[string]time.Time), names: make(map[string]string)}
+}
+
+// isJobLine reports whether a line comes from JobScheduler or WorkManager
+func isJobLine(entry logLine) bool {
+	return jobTags.MatchString(entry.Tag)
+}
+
+// Observe prints a -jobs row above a line reporting a step of a job
+func (t *JobTracker) Observe(entry logLine, opts LogcatOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tr := range jobTransitions {
+		m := tr.pattern.FindStringSubmatch(entry.Message)
+		if m == nil {
+			continue
+		}
+		id := m[1]
+		if w := jobWorker.FindStringSubmatch(entry.Message); w != nil {
+			t.names[id] = w[1]
+		}
+
+		details := shortJobID(id)
+		if name := t.names[id]; name != "" {
+			details += " " + name
+		}
+		start, running := t.started[id]
+		switch {
+		case tr.done && running:
+			details += fmt.Sprintf(" after %v", entry.Time.Sub(start).Round(time.Millisecond))
+			delete(t.started, id)
+		case tr.kind == "started":
+			t.started[id] = entry.Time
+		}
+		if tr.done {
+			delete(t.names, id)
+		}
+		fmt.Fprintf(opts.out(), "%s%s %s %s\n", opts.Prefix, NetChangeColor(" JOB "), tr.color(" %-13s ", tr.kind),
+			StatsValueColor("%s  %s", entry.Time.Format("15:04:05.000"), details))
+		return
+	}
+}
+
+// shortJobID shortens WorkManager's UUIDs to their first group
+func shortJobID(id string) string {
+	if len(id) == 36 && id[8] == '-' {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// latencyBarWidth is the width of the longest histogram bar
+const latencyBarWidth = 40
+
+// latencyBuckets are the upper bounds of the histogram buckets
+var latencyBuckets = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second,
+	10 * time.Second, 20 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute,
+}
+
+// LatencyBarColor is the color of histogram bars
+var LatencyBarColor = color.New(color.FgCyan).SprintfFunc()
+
+// LatencyTracker records the intervals between consecutive lines of
+// selected tags for -latency-tags
+type LatencyTracker struct {
+	mu   sync.Mutex
+	tags []string
+	last map[string]time.Time
+	gaps map[string][]time.Duration
+}
+
+// newLatencyTracker returns a tracker for the given tags
+func newLatencyTracker(tags []string) *LatencyTracker {
+	return &LatencyTracker{
+		tags: tags,
+		last: make(map[string]time.Time),
+		gaps: make(map[string][]time.Duration),
+	}
+}
+
+// Observe records the interval since the previous line of the same tag
+func (t *LatencyTracker) Observe(line string) {
+	entry, ok := parseLogLine(line)
+	if !ok || !slices.Contains(t.tags, entry.Tag) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if last, ok := t.last[entry.Tag]; ok && !entry.Time.Before(last) {
+		t.gaps[entry.Tag] = append(t.gaps[entry.Tag], entry.Time.Sub(last))
+	}
+	t.last[entry.Tag] = entry.Time
+}
+
+// percentile returns the p-th percentile of sorted intervals
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(p / 100 * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+// bucketLabel describes the range of histogram bucket i
+func bucketLabel(i int) string {
+	switch {
+	case i == 0:
+		return "< " + latencyBuckets[0].String()
+	case i == len(latencyBuckets):
+		return ">= " + latencyBuckets[i-1].String()
+	}
+	return latencyBuckets[i-1].String() + "-" + latencyBuckets[i].String()
+}
+
+// Report prints percentiles and a histogram of each tag's intervals
+func (t *LatencyTracker) Report(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tag := range t.tags {
+		gaps := slices.Clone(t.gaps[tag])
+		if len(gaps) == 0 {
+			fmt.Fprintf(w, "%s: fewer than two lines\n\n", TagColor("%s", tag))
+			continue
+		}
+		slices.Sort(gaps)
+		fmt.Fprintf(w, "%s: %d intervals, min %v, p50 %v, p90 %v, p99 %v, max %v\n",
+			TagColor("%s", tag), len(gaps), gaps[0], percentile(gaps, 50), percentile(gaps, 90), percentile(gaps, 99), gaps[len(gaps)-1])
+
+		counts := make([]int, len(latencyBuckets)+1)
+		for _, gap := range gaps {
+			i, _ := slices.BinarySearch(latencyBuckets, gap+1)
+			counts[i]++
+		}
+		first := slices.IndexFunc(counts, func(n int) bool { return n > 0 })
+		last := len(counts) - 1
+		for counts[last] == 0 {
+			last--
+		}
+		peak := slices.Max(counts)
+		for i := first; i <= last; i++ {
+			bar := strings.Repeat("#", (counts[i]*latencyBarWidth+peak-1)/peak)
+			fmt.Fprintf(w, "  %15s |%s %d\n", bucketLabel(i), LatencyBarColor("%s", bar), counts[i])
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Patterns for the line formats the lenient parser recognizes besides threadtime
+var (
+	// brief and tag formats: "I/Tag( 1234): message" and "I/Tag: message"
+	briefPattern = regexp.MustCompile(`^([VDIWEF])/([^(:]*?)\s*(?:\(\s*\d+\))?: (.*)$`)
+	// process format: "I( 1234) message  (Tag)"
+	processPattern = regexp.MustCompile(`^([VDIWEF])\(\s*\d+\) (.*?)\s+\((\S+)\)$`)
+	// Kernel messages: "<6>[ 1234.567890] message" or "[ 1234.567890] message"
+	kernelPattern = regexp.MustCompile(`^(?:<([0-7])>)?\[\s*\d+\.\d+\] (.*)$`)
+)
+
+// kernelLevels maps kernel log priorities to logcat levels
+var kernelLevels = [8]string{"F", "F", "F", "E", "W", "I", "I", "D"}
+
+// parsePartialLine extracts whatever fields it can from a line that is not
+// in threadtime format. The level is empty if it could not be found.
+func parsePartialLine(line string) (level, tag, message string, ok bool) {
+	if m := briefPattern.FindStringSubmatch(line); m != nil {
+		return m[1], m[2], m[3], true
+	}
+	if m := processPattern.FindStringSubmatch(line); m != nil {
+		return m[1], m[3], m[2], true
+	}
+	if m := kernelPattern.FindStringSubmatch(line); m != nil {
+		level = "I"
+		if m[1] != "" {
+			level = kernelLevels[m[1][0]-'0']
+		}
+		return level, "kernel", m[2], true
+	}
+
+	// Look for a lone level letter among the leading fields, optionally
+	// followed by "Tag: message"
+	fields := findFieldIndices(line, 8)
+	for i, start := range fields {
+		if start+1 < len(line) && line[start+1] != ' ' || !strings.ContainsRune(levelOrder, rune(line[start])) {
+			continue
+		}
+		if start+1 >= len(line) {
+			return line[start : start+1], "", "", true
+		}
+		rest := line[start+2:]
+		if i+1 < len(fields) {
+			rest = line[fields[i+1]:]
+		}
+		if t, msg, found := strings.Cut(rest, ": "); found && !strings.Contains(strings.TrimSpace(t), " ") {
+			return line[start : start+1], strings.TrimSpace(t), msg, true
+		}
+		return line[start : start+1], "", rest, true
+	}
+	return "", "", "", false
+}
+
+// printPartialLog prints a line the lenient parser recognized, coloring it
+// by its level after applying the filters that its fields allow
+func printPartialLog(line, level, tag, message string, number int, opts LogcatOptions) {
+	level = remapSeverity(level, tag, message, opts.Severities)
+	if !matchesQuery(logLine{Level: level, Tag: tag, Message: message}, opts) {
+		return
+	}
+	if override, ok := opts.Tags[tag]; ok && tag != "" && (override.Hide || levelBelow(level, override.MinLevel)) {
+		return
+	}
+
+	colorFunc, ok := LogLevelColors[level]
+	rule, message := matchRule(logLine{Level: level, Tag: tag, Message: message}, opts.Rules)
+	if rule != nil {
+		switch rule.Action {
+		case ActionHide:
+			return
+		case ActionRaise:
+			if rule.RaiseTo != "" {
+				level = rule.RaiseTo
+			} else {
+				level = raiseLevel(level)
+			}
+			colorFunc, ok = LogLevelColors[level]
+		case ActionNotify:
+			notify(tag, message)
+		}
+		if rule.Style != nil {
+			colorFunc, ok = rule.Style, true
+		}
+	}
+	if !ok {
+		colorFunc = func(format string, a ...any) string { return fmt.Sprintf(format, a...) }
+	}
+	fmt.Fprintln(opts.out(), linePrefix(number, opts)+colorizeMessage(line, colorFunc))
+}
@@ -0,0 +1,164 @@
+// Package logcat parses Android logcat output and streams it from devices,
+// files or any other source to a callback.
+package logcat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Levels lists the logcat priority letters from lowest to highest
+const Levels = "VDIWEF"
+
+// Entry is a parsed threadtime log line
+type Entry struct {
+	Line       string    // The line as read
+	Time       time.Time // Month, day and time of day; logcat prints no year
+	UID        string    // As printed by logcat -v uid, empty without it
+	UIDIndex   int       // Offset of the UID in Line
+	PID        string
+	TID        string
+	Level      string // One of Levels
+	Tag        string
+	TagSpace   string // Padding between the tag and its colon
+	Message    string
+	LevelIndex int    // Offset of the level in Line
+	Other      string // Timestamp and PID/TID fields
+	Device     string // Serial of the device the entry came from, if known
+	Number     int    // Number of the line in its stream, if counted
+}
+
+// Parse splits a threadtime log line into its fields, reporting false for
+// lines in other formats
+func Parse(line string) (Entry, bool) {
+	// New logcat line format: [MM-DD HH:MM:SS.mmm PID TID LEVEL TAG: MESSAGE]
+	// Example: "04-19 19:34:18.813  5587  5708 I artd    : GetBestInfo no usable artifacts"
+	parts := FieldIndices(line, 6)
+	if len(parts) < 6 {
+		return Entry{}, false
+	}
+
+	// With -v uid the PID is preceded by "uid:", e.g. "u0_a123:12345" or "system: 1234"
+	uid, uidIndex := "", 0
+	if end := strings.IndexByte(line[parts[2]:], ' '); end > 0 {
+		if colon := strings.IndexByte(line[parts[2]:parts[2]+end], ':'); colon >= 0 {
+			uid, uidIndex = line[parts[2]:parts[2]+colon], parts[2]
+			rest := parts[2] + colon + 1
+			fields := FieldIndices(line[rest:], 4)
+			if len(fields) < 4 {
+				return Entry{}, false
+			}
+			for i, f := range fields {
+				parts[2+i] = rest + f
+			}
+		}
+	}
+
+	levelIndex := parts[4]
+	level := line[levelIndex : levelIndex+1]
+	if !strings.Contains(Levels, level) {
+		return Entry{}, false
+	}
+
+	tagIndex := parts[5]
+	colonIndex := strings.IndexRune(line[tagIndex:], ':')
+	if colonIndex == -1 {
+		return Entry{}, false
+	}
+	colonIndex += tagIndex
+
+	currentTime, err := ParseTimestamp(line)
+	if err != nil {
+		return Entry{}, false
+	}
+
+	tag := strings.TrimSpace(line[tagIndex:colonIndex])
+	message := ""
+	if colonIndex+2 <= len(line) {
+		message = line[colonIndex+2:]
+	}
+
+	return Entry{
+		Line:       line,
+		Time:       currentTime,
+		UID:        uid,
+		UIDIndex:   uidIndex,
+		PID:        strings.TrimSpace(line[parts[2]:parts[3]]),
+		TID:        strings.TrimSpace(line[parts[3]:parts[4]]),
+		Level:      level,
+		Tag:        tag,
+		TagSpace:   line[tagIndex+len(tag) : colonIndex],
+		Message:    message,
+		LevelIndex: levelIndex,
+		Other:      line[:parts[1]] + line[parts[2]:parts[4]],
+	}, true
+}
+
+// ParseTimestamp parses the timestamp from a log line
+func ParseTimestamp(line string) (time.Time, error) {
+	// Format: MM-DD HH:MM:SS.mmm, or MM-DD HH:MM:SS.uuuuuu with -v usec.
+	// time.Parse accepts either fraction after the seconds.
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return time.Time{}, fmt.Errorf("invalid timestamp format")
+	}
+	timestamp := parts[0] + " " + parts[1]
+	return time.Parse("01-02 15:04:05", timestamp)
+}
+
+// InferYear returns the year a logcat time, which has none, was most likely
+// logged in: the current year, or the previous one for dates after tomorrow
+// such as December's lines replayed in January
+func InferYear(t, now time.Time) int {
+	year := now.Year()
+	if time.Date(year, t.Month(), t.Day(), 0, 0, 0, 0, now.Location()).After(now.AddDate(0, 0, 1)) {
+		year--
+	}
+	return year
+}
+
+// FormatTime returns the entry's time as logcat printed it, with
+// microseconds when it was read with them (logcat -v usec)
+func (e Entry) FormatTime() string {
+	if e.Time.Nanosecond()%int(time.Millisecond) != 0 || hasUsec(e.Line) {
+		return e.Time.Format(TimeLayoutUsec)
+	}
+	return e.Time.Format(TimeLayout)
+}
+
+// hasUsec reports whether a line's timestamp has six fractional digits
+func hasUsec(line string) bool {
+	dot := strings.IndexByte(line, '.')
+	if dot < 0 || len(line) < dot+7 {
+		return false
+	}
+	for _, c := range line[dot+1 : dot+7] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldIndices returns the indices of the first non-space character for each field
+// up to the specified maximum number of fields
+func FieldIndices(line string, maxFields int) []int {
+	indices := make([]int, 0, maxFields)
+	inField := false
+
+	for i, char := range line {
+		if char != ' ' && !inField {
+			// Found start of a new field
+			indices = append(indices, i)
+			inField = true
+			if len(indices) >= maxFields {
+				break
+			}
+		} else if char == ' ' {
+			inField = false
+		}
+	}
+
+	return indices
+}
@@ -0,0 +1,115 @@
+package logcat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		ok   bool
+		want Entry // Fields compared: UID, PID, TID, Level, Tag, Message
+	}{
+		{
+			name: "threadtime",
+			line: "04-19 19:34:18.813  5587  5708 I artd    : GetBestInfo no usable artifacts",
+			ok:   true,
+			want: Entry{PID: "5587", TID: "5708", Level: "I", Tag: "artd", Message: "GetBestInfo no usable artifacts"},
+		},
+		{
+			name: "usec",
+			line: "04-19 19:34:18.813456  5587  5708 W Tag: message",
+			ok:   true,
+			want: Entry{PID: "5587", TID: "5708", Level: "W", Tag: "Tag", Message: "message"},
+		},
+		{
+			name: "uid",
+			line: "04-19 19:34:18.813 u0_a123:12345 12360 D MyApp   : hello",
+			ok:   true,
+			want: Entry{UID: "u0_a123", PID: "12345", TID: "12360", Level: "D", Tag: "MyApp", Message: "hello"},
+		},
+		{
+			name: "padded uid",
+			line: "04-19 19:34:18.813  system: 1234  1250 E ActivityManager: ANR in com.example",
+			ok:   true,
+			want: Entry{UID: "system", PID: "1234", TID: "1250", Level: "E", Tag: "ActivityManager", Message: "ANR in com.example"},
+		},
+		{
+			name: "colon in message",
+			line: "04-19 19:34:18.813  100  100 I Tag : key: value",
+			ok:   true,
+			want: Entry{PID: "100", TID: "100", Level: "I", Tag: "Tag", Message: "key: value"},
+		},
+		{
+			name: "empty message",
+			line: "04-19 19:34:18.813  100  100 I Tag :",
+			ok:   true,
+			want: Entry{PID: "100", TID: "100", Level: "I", Tag: "Tag"},
+		},
+		{name: "unknown level", line: "04-19 19:34:18.813  100  100 X Tag : message"},
+		{name: "no colon", line: "04-19 19:34:18.813  100  100 I Tag message"},
+		{name: "bad time", line: "yesterday 19:34:18.813  100  100 I Tag : message"},
+		{name: "brief", line: "I/Tag( 100): message"},
+		{name: "divider", line: "--------- beginning of main"},
+		{name: "empty", line: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := Parse(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("Parse(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			got := Entry{UID: e.UID, PID: e.PID, TID: e.TID, Level: e.Level, Tag: e.Tag, Message: e.Message}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+			if e.Line != tt.line || tt.line[e.LevelIndex:e.LevelIndex+1] != e.Level {
+				t.Errorf("Parse(%q) kept line %q with level at %d", tt.line, e.Line, e.LevelIndex)
+			}
+			if e.UID != "" && tt.line[e.UIDIndex:e.UIDIndex+len(e.UID)] != e.UID {
+				t.Errorf("Parse(%q) UIDIndex = %d, not at %q", tt.line, e.UIDIndex, e.UID)
+			}
+		})
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	for _, line := range []string{
+		"04-19 19:34:18.813  100  100 I Tag : message",
+		"04-19 19:34:18.813456  100  100 I Tag : message",
+		"04-19 19:34:18.813000  100  100 I Tag : message",
+	} {
+		e, ok := Parse(line)
+		if !ok {
+			t.Fatalf("Parse(%q) failed", line)
+		}
+		if got, want := e.FormatTime(), line[:len(line)-len("  100  100 I Tag : message")]; got != want {
+			t.Errorf("FormatTime() of %q = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestInferYear(t *testing.T) {
+	now := time.Date(2026, time.January, 5, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		month time.Month
+		day   int
+		want  int
+	}{
+		{time.January, 5, 2026},
+		{time.January, 6, 2026}, // Tomorrow, allowing for clock differences
+		{time.January, 7, 2025},
+		{time.December, 31, 2025},
+		{time.January, 1, 2026},
+	}
+	for _, tt := range tests {
+		if got := InferYear(time.Date(0, tt.month, tt.day, 0, 0, 0, 0, time.UTC), now); got != tt.want {
+			t.Errorf("InferYear(%s %d) = %d, want %d", tt.month, tt.day, got, tt.want)
+		}
+	}
+}
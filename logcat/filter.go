@@ -0,0 +1,70 @@
+package logcat
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Filter decides which entries to keep
+type Filter interface {
+	Match(Entry) bool
+}
+
+// FilterFunc adapts a function to a Filter
+type FilterFunc func(Entry) bool
+
+// Match calls f
+func (f FilterFunc) Match(e Entry) bool {
+	return f(e)
+}
+
+// Tag keeps entries with any of the given tags
+func Tag(tags ...string) Filter {
+	return FilterFunc(func(e Entry) bool { return slices.Contains(tags, e.Tag) })
+}
+
+// MinLevel keeps entries at least as severe as level, one of Levels
+func MinLevel(level string) Filter {
+	threshold := strings.Index(Levels, level)
+	return FilterFunc(func(e Entry) bool { return strings.Index(Levels, e.Level) >= threshold })
+}
+
+// Regex keeps entries whose message matches re
+func Regex(re *regexp.Regexp) Filter {
+	return FilterFunc(func(e Entry) bool { return re.MatchString(e.Message) })
+}
+
+// PID keeps entries logged by any of the given processes
+func PID(pids ...string) Filter {
+	return FilterFunc(func(e Entry) bool { return slices.Contains(pids, e.PID) })
+}
+
+// And keeps entries matching all of filters, or every entry if there are none
+func And(filters ...Filter) Filter {
+	return FilterFunc(func(e Entry) bool {
+		for _, f := range filters {
+			if !f.Match(e) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Or keeps entries matching any of filters, or no entry if there are none
+func Or(filters ...Filter) Filter {
+	return FilterFunc(func(e Entry) bool {
+		for _, f := range filters {
+			if f.Match(e) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not keeps the entries f drops
+func Not(f Filter) Filter {
+	return FilterFunc(func(e Entry) bool { return !f.Match(e) })
+}
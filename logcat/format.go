@@ -0,0 +1,131 @@
+package logcat
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// TimeLayout and TimeLayoutUsec format entry times as logcat prints them,
+// the latter with -v usec
+const (
+	TimeLayout     = "01-02 15:04:05.000"
+	TimeLayoutUsec = "01-02 15:04:05.000000"
+)
+
+// Formatter renders an entry as one record of output, including its line
+// terminator
+type Formatter interface {
+	Format(Entry) ([]byte, error)
+}
+
+// HeaderFormatter is a Formatter whose output starts with a header, such
+// as the column names of CSV
+type HeaderFormatter interface {
+	Formatter
+	Header() []byte
+}
+
+// FormatterFunc adapts a function to a Formatter
+type FormatterFunc func(Entry) ([]byte, error)
+
+// Format calls f
+func (f FormatterFunc) Format(e Entry) ([]byte, error) {
+	return f(e)
+}
+
+// Text formats entries as the lines they were parsed from
+var Text Formatter = FormatterFunc(func(e Entry) ([]byte, error) {
+	return []byte(e.Line + "\n"), nil
+})
+
+// jsonEntry is the JSON form of an entry
+type jsonEntry struct {
+	Time    string `json:"time"`
+	UID     string `json:"uid,omitempty"`
+	PID     string `json:"pid"`
+	TID     string `json:"tid"`
+	Level   string `json:"level"`
+	Tag     string `json:"tag"`
+	Message string `json:"message"`
+	Device  string `json:"device,omitempty"`
+	Number  int    `json:"line,omitempty"`
+}
+
+// newJSONEntry returns the JSON form of e
+func newJSONEntry(e Entry) jsonEntry {
+	return jsonEntry{e.FormatTime(), e.UID, e.PID, e.TID, e.Level, e.Tag, e.Message, e.Device, e.Number}
+}
+
+// JSON formats entries as one JSON object per line
+var JSON Formatter = FormatterFunc(func(e Entry) ([]byte, error) {
+	data, err := json.Marshal(newJSONEntry(e))
+	return append(data, '\n'), err
+})
+
+// Logfmt formats entries as logfmt lines: ts, level, tag, pid, tid, uid and
+// device when known, then msg
+var Logfmt Formatter = FormatterFunc(func(e Entry) ([]byte, error) {
+	var b []byte
+	for _, field := range [][2]string{
+		{"ts", e.FormatTime()},
+		{"level", e.Level},
+		{"tag", e.Tag},
+		{"pid", e.PID},
+		{"tid", e.TID},
+		{"uid", e.UID},
+		{"device", e.Device},
+		{"msg", e.Message},
+	} {
+		if field[1] == "" && field[0] != "msg" {
+			continue
+		}
+		if len(b) > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, field[0]+"="...)
+		b = appendLogfmtValue(b, field[1])
+	}
+	return append(b, '\n'), nil
+})
+
+// appendLogfmtValue appends v, quoted if it is empty or contains spaces,
+// quotes, equals signs or unprintable characters
+func appendLogfmtValue(b []byte, v string) []byte {
+	if v == "" || strings.ContainsAny(v, " =\"\\") || !strconv.CanBackquote(v) {
+		return strconv.AppendQuote(b, v)
+	}
+	return append(b, v...)
+}
+
+// csvColumns are the columns of the CSV format
+var csvColumns = []string{"time", "level", "tag", "pid", "tid", "uid", "device", "message"}
+
+// csvFormatter formats entries as CSV records
+type csvFormatter struct{}
+
+// CSV formats entries as CSV records under a header row naming the columns.
+// The header starts with a byte order mark so that spreadsheets read the
+// file as UTF-8.
+var CSV HeaderFormatter = csvFormatter{}
+
+// Header returns the byte order mark and the column names
+func (csvFormatter) Header() []byte {
+	return append([]byte("\ufeff"), csvRecord(csvColumns)...)
+}
+
+// Format returns e as one record
+func (csvFormatter) Format(e Entry) ([]byte, error) {
+	return csvRecord([]string{e.FormatTime(), e.Level, e.Tag, e.PID, e.TID, e.UID, e.Device, e.Message}), nil
+}
+
+// csvRecord encodes one CSV record, quoting fields as needed
+func csvRecord(fields []string) []byte {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write(fields)
+	w.Flush()
+	return b.Bytes()
+}
@@ -0,0 +1,187 @@
+package logcat
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// formatTests are the entries the formatter tests write and read back
+var formatTests = []struct {
+	name string
+	line string
+	uid  string
+	dev  string
+	time string // The time as the formats write it
+}{
+	{name: "plain", line: "04-19 19:34:18.813  5587  5708 I artd    : GetBestInfo no usable artifacts", time: "04-19 19:34:18.813"},
+	{name: "usec", line: "04-19 19:34:18.813456  5587  5708 W artd: took 1.5ms", time: "04-19 19:34:18.813456"},
+	{name: "quotes and commas", line: `04-19 19:34:18.813  1000  1000 E Tag : say "hi", then key=value`, dev: "R58M123", time: "04-19 19:34:18.813"},
+	{name: "uid", line: "04-19 19:34:18.813 u0_a123:12345 12360 D MyApp   : héllo\twörld", uid: "u0_a123", time: "04-19 19:34:18.813"},
+}
+
+// formatEntry parses a formatTests line, adding its device
+func formatEntry(t *testing.T, line, device string) Entry {
+	t.Helper()
+	e, ok := Parse(line)
+	if !ok {
+		t.Fatalf("Parse(%q) failed", line)
+	}
+	e.Device = device
+	return e
+}
+
+func TestJSON(t *testing.T) {
+	for _, tt := range formatTests {
+		e := formatEntry(t, tt.line, tt.dev)
+		data, err := JSON.Format(e)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got jsonEntry
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: %v in %s", tt.name, err, data)
+		}
+		want := jsonEntry{tt.time, tt.uid, e.PID, e.TID, e.Level, e.Tag, e.Message, tt.dev, 0}
+		if got != want {
+			t.Errorf("%s: JSON read back as %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var b bytes.Buffer
+	b.Write(CSV.Header())
+	for _, tt := range formatTests {
+		data, err := CSV.Format(formatEntry(t, tt.line, tt.dev))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		b.Write(data)
+	}
+	if !bytes.HasPrefix(b.Bytes(), []byte("\ufefftime,")) {
+		t.Errorf("CSV starts with %q, want a byte order mark and the header", b.Bytes()[:10])
+	}
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(b.Bytes(), []byte("\ufeff")))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(formatTests)+1 {
+		t.Fatalf("CSV has %d records, want %d", len(records), len(formatTests)+1)
+	}
+	for i, tt := range formatTests {
+		e := formatEntry(t, tt.line, tt.dev)
+		want := []string{tt.time, e.Level, e.Tag, e.PID, e.TID, tt.uid, tt.dev, e.Message}
+		if got := records[i+1]; !slices.Equal(got, want) {
+			t.Errorf("%s: CSV read back as %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestLogfmt(t *testing.T) {
+	tests := []struct {
+		line, dev, want string
+	}{
+		{
+			line: "04-19 19:34:18.813  5587  5708 I artd    : GetBestInfo",
+			want: `ts="04-19 19:34:18.813" level=I tag=artd pid=5587 tid=5708 msg=GetBestInfo` + "\n",
+		},
+		{
+			line: `04-19 19:34:18.813456  1000  1000 E Tag : say "hi" a=b`,
+			dev:  "R58M123",
+			want: `ts="04-19 19:34:18.813456" level=E tag=Tag pid=1000 tid=1000 device=R58M123 msg="say \"hi\" a=b"` + "\n",
+		},
+		{
+			line: "04-19 19:34:18.813 u0_a123:12345 12360 D MyApp   :",
+			want: `ts="04-19 19:34:18.813" level=D tag=MyApp pid=12345 tid=12360 uid=u0_a123 msg=""` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := Logfmt.Format(formatEntry(t, tt.line, tt.dev))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Logfmt of %q = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestProto(t *testing.T) {
+	for _, tt := range formatTests {
+		e := formatEntry(t, tt.line, tt.dev)
+		data, err := Proto.Format(e)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		m, n := protowire.ConsumeBytes(data)
+		if n != len(data) {
+			t.Fatalf("%s: length prefix covers %d of %d bytes", tt.name, n, len(data))
+		}
+
+		got := make(map[protowire.Number]any)
+		for len(m) > 0 {
+			num, typ, n := protowire.ConsumeTag(m)
+			if n < 0 {
+				t.Fatalf("%s: bad tag: %v", tt.name, protowire.ParseError(n))
+			}
+			m = m[n:]
+			switch typ {
+			case protowire.VarintType:
+				v, n := protowire.ConsumeVarint(m)
+				got[num], m = int64(v), m[n:]
+			case protowire.BytesType:
+				v, n := protowire.ConsumeString(m)
+				got[num], m = v, m[n:]
+			default:
+				t.Fatalf("%s: field %d has wire type %d", tt.name, num, typ)
+			}
+		}
+
+		year := InferYear(e.Time, time.Now())
+		at := time.Date(year, e.Time.Month(), e.Time.Day(), e.Time.Hour(), e.Time.Minute(), e.Time.Second(), 0, time.Local)
+		ms, us := at.UnixMilli()+int64(e.Time.Nanosecond()/1e6), int64(e.Time.Nanosecond()/1e3%1000)
+		want := map[protowire.Number]any{
+			protoTime:    ms,
+			protoLevel:   int64(strings.Index(Levels, e.Level) + 2),
+			protoTag:     e.Tag,
+			protoPID:     parseInt(t, e.PID),
+			protoTID:     parseInt(t, e.TID),
+			protoMessage: e.Message,
+		}
+		if us != 0 {
+			want[protoTimeMicros] = us
+		}
+		if tt.uid != "" {
+			want[protoUID] = tt.uid
+		}
+		if tt.dev != "" {
+			want[protoDevice] = tt.dev
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: proto fields %v, want %v", tt.name, got, want)
+		}
+		for num, v := range want {
+			if got[num] != v {
+				t.Errorf("%s: proto field %d = %v, want %v", tt.name, num, got[num], v)
+			}
+		}
+	}
+}
+
+// parseInt parses a decimal PID or TID
+func parseInt(t *testing.T, s string) int64 {
+	t.Helper()
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,84 @@
+package logcat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+)
+
+// JournaldSocket is where systemd-journald receives native protocol messages
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// journaldPriorities maps levels to syslog priorities
+var journaldPriorities = map[string]string{
+	"V": "7", // debug
+	"D": "7", // debug
+	"I": "6", // info
+	"W": "4", // warning
+	"E": "3", // err
+	"F": "2", // crit
+}
+
+// JournaldSink sends entries to systemd-journald with their level as the
+// PRIORITY, their tag as the SYSLOG_IDENTIFIER and the fields ANDROID_TAG,
+// ANDROID_PID, ANDROID_TID, ANDROID_UID, ANDROID_LEVEL, ANDROID_TIME and
+// DEVICE_SERIAL
+type JournaldSink struct {
+	conn net.Conn
+	buf  bytes.Buffer
+}
+
+// NewJournaldSink connects to journald's socket, JournaldSocket if empty
+func NewJournaldSink(socket string) (*JournaldSink, error) {
+	if socket == "" {
+		socket = JournaldSocket
+	}
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldSink{conn: conn}, nil
+}
+
+// Write sends e as one journal entry
+func (s *JournaldSink) Write(e Entry) error {
+	s.buf.Reset()
+	for _, field := range [][2]string{
+		{"MESSAGE", e.Message},
+		{"PRIORITY", journaldPriorities[e.Level]},
+		{"SYSLOG_IDENTIFIER", e.Tag},
+		{"ANDROID_TAG", e.Tag},
+		{"ANDROID_PID", e.PID},
+		{"ANDROID_TID", e.TID},
+		{"ANDROID_UID", e.UID},
+		{"ANDROID_LEVEL", e.Level},
+		{"ANDROID_TIME", e.FormatTime()},
+		{"DEVICE_SERIAL", e.Device},
+	} {
+		if field[1] != "" {
+			writeJournalField(&s.buf, field[0], field[1])
+		}
+	}
+	_, err := s.conn.Write(s.buf.Bytes())
+	return err
+}
+
+// writeJournalField appends a field in journald's native format, in which
+// values containing newlines are preceded by their length
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if strings.Contains(value, "\n") {
+		buf.WriteByte('\n')
+		binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	} else {
+		buf.WriteByte('=')
+	}
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// Close closes the connection to journald
+func (s *JournaldSink) Close() error {
+	return s.conn.Close()
+}
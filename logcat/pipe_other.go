@@ -0,0 +1,23 @@
+//go:build !windows
+
+package logcat
+
+import "errors"
+
+// PipeSink serves entries on a Windows named pipe
+type PipeSink struct{}
+
+// NewPipeSink fails: named pipes are only available on Windows
+func NewPipeSink(path string, f Formatter) (*PipeSink, error) {
+	return nil, errors.New("named pipes are only available on Windows; use unix://PATH instead")
+}
+
+// Write does nothing
+func (s *PipeSink) Write(e Entry) error {
+	return nil
+}
+
+// Close does nothing
+func (s *PipeSink) Close() error {
+	return nil
+}
@@ -0,0 +1,120 @@
+//go:build windows
+
+package logcat
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// pipeBufferSize is the size of each pipe instance's output buffer
+const pipeBufferSize = 64 << 10
+
+// PipeSink serves entries on a Windows named pipe such as
+// \\.\pipe\logcatcolor. Any number of readers can connect, and each receives
+// the entries written from then on; entries are discarded while none is
+// connected. A reader that stops reading holds up the stream once its
+// buffer is full.
+type PipeSink struct {
+	path   string
+	format Formatter
+
+	mu      sync.Mutex
+	clients []windows.Handle
+	closed  bool
+	done    chan struct{}
+}
+
+// NewPipeSink creates the pipe at path and starts accepting readers; it
+// fails if another process already serves the pipe
+func NewPipeSink(path string, f Formatter) (*PipeSink, error) {
+	h, err := createPipe(path, windows.FILE_FLAG_FIRST_PIPE_INSTANCE)
+	if err != nil {
+		return nil, &os.PathError{Op: "create", Path: path, Err: err}
+	}
+	s := &PipeSink{path: path, format: f, done: make(chan struct{})}
+	go s.accept(h)
+	return s, nil
+}
+
+// createPipe creates an instance of the pipe at path
+func createPipe(path string, flags uint32) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	return windows.CreateNamedPipe(name, windows.PIPE_ACCESS_OUTBOUND|flags,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS,
+		windows.PIPE_UNLIMITED_INSTANCES, pipeBufferSize, 0, 0, nil)
+}
+
+// accept waits for a reader on h, then creates the next instance for the
+// following reader, until the sink is closed
+func (s *PipeSink) accept(h windows.Handle) {
+	defer close(s.done)
+	for {
+		err := windows.ConnectNamedPipe(h, nil)
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			windows.CloseHandle(h)
+			return
+		}
+		if err == nil || errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+			s.clients = append(s.clients, h)
+		} else {
+			windows.CloseHandle(h)
+		}
+		s.mu.Unlock()
+
+		if h, err = createPipe(s.path, 0); err != nil {
+			return
+		}
+	}
+}
+
+// Write formats e and sends it to every connected reader, dropping those
+// that have disconnected
+func (s *PipeSink) Write(e Entry) error {
+	data, err := s.format.Format(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.clients[:0]
+	for _, h := range s.clients {
+		var n uint32
+		if err := windows.WriteFile(h, data, &n, nil); err != nil {
+			windows.DisconnectNamedPipe(h)
+			windows.CloseHandle(h)
+			continue
+		}
+		kept = append(kept, h)
+	}
+	s.clients = kept
+	return nil
+}
+
+// Close disconnects the readers and removes the pipe
+func (s *PipeSink) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for _, h := range s.clients {
+		windows.DisconnectNamedPipe(h)
+		windows.CloseHandle(h)
+	}
+	s.clients = nil
+	s.mu.Unlock()
+
+	// Connect to the waiting instance so that accept wakes up and exits
+	if f, err := os.Open(s.path); err == nil {
+		f.Close()
+	}
+	<-s.done
+	return nil
+}
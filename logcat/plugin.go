@@ -0,0 +1,131 @@
+package logcat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// pluginExitTimeout is how long a plugin has to exit once its input is closed
+const pluginExitTimeout = 2 * time.Second
+
+// Processor filters and rewrites entries, as Plugin and WASMPlugin do
+type Processor interface {
+	// Process returns e, changed or not, and false to drop it. After an
+	// error the processor is unusable and keeps returning the same error.
+	Process(e Entry) (Entry, bool, error)
+	// Err returns the error that made the processor unusable, if any
+	Err() error
+	Close() error
+}
+
+// Plugin is an external process that filters and rewrites entries. Each
+// entry is written to its stdin as a JSON object on one line, in the JSON
+// format, and it answers on stdout with one line per entry: the entry,
+// with any fields changed, or null to drop it. Fields left out of the
+// answer keep their values.
+type Plugin struct {
+	cmd  *exec.Cmd
+	name string
+	in   io.WriteCloser
+	w    *bufio.Writer
+	out  *bufio.Reader
+	err  error
+}
+
+// StartPlugin starts cmd as a plugin; its stderr is passed through
+func StartPlugin(cmd *exec.Cmd) (*Plugin, error) {
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &Plugin{cmd: cmd, name: cmd.Path, in: in, w: bufio.NewWriter(in), out: bufio.NewReader(out)}, nil
+}
+
+// Process sends e to the plugin and returns its answer, reporting false if
+// the plugin drops it. After an error the plugin is unusable and Process
+// keeps returning the same error.
+func (p *Plugin) Process(e Entry) (Entry, bool, error) {
+	if p.err != nil {
+		return e, true, p.err
+	}
+	result, keep, err := p.process(e)
+	if err != nil {
+		p.err = fmt.Errorf("plugin %s: %w", p.name, err)
+		return e, true, p.err
+	}
+	return result, keep, nil
+}
+
+// process exchanges one entry with the plugin
+func (p *Plugin) process(e Entry) (Entry, bool, error) {
+	data, err := json.Marshal(newJSONEntry(e))
+	if err != nil {
+		return e, false, err
+	}
+	p.w.Write(append(data, '\n'))
+	if err := p.w.Flush(); err != nil {
+		return e, false, err
+	}
+
+	answer, err := p.out.ReadBytes('\n')
+	if err == io.EOF && len(answer) == 0 {
+		return e, false, fmt.Errorf("exited without answering")
+	} else if err != nil && err != io.EOF {
+		return e, false, err
+	}
+	return decodeAnswer(e, answer)
+}
+
+// decodeAnswer applies a plugin's answer for e: the entry as a JSON object,
+// with the fields it changed, or null or nothing to drop it
+func decodeAnswer(e Entry, answer []byte) (Entry, bool, error) {
+	answer = bytes.TrimSpace(answer)
+	if len(answer) == 0 || string(answer) == "null" {
+		return e, false, nil
+	}
+	je := newJSONEntry(e)
+	if err := json.Unmarshal(answer, &je); err != nil {
+		return e, false, fmt.Errorf("invalid answer %q: %w", answer, err)
+	}
+	if len(je.Level) != 1 || !strings.Contains(Levels, je.Level) {
+		return e, false, fmt.Errorf("invalid level %q, must be one of %s", je.Level, Levels)
+	}
+	if je.Time != e.FormatTime() {
+		// The fraction may have three or six digits
+		t, err := time.Parse("01-02 15:04:05", je.Time)
+		if err != nil {
+			return e, false, fmt.Errorf("invalid time %q", je.Time)
+		}
+		e.Time = t
+	}
+	e.UID, e.PID, e.TID, e.Level, e.Tag, e.Message, e.Device = je.UID, je.PID, je.TID, je.Level, je.Tag, je.Message, je.Device
+	return e, true, nil
+}
+
+// Err returns the error that made the plugin unusable, if any
+func (p *Plugin) Err() error {
+	return p.err
+}
+
+// Close closes the plugin's input and waits for it to exit, killing it
+// if it does not exit in time
+func (p *Plugin) Close() error {
+	p.w.Flush()
+	p.in.Close()
+	timer := time.AfterFunc(pluginExitTimeout, func() { p.cmd.Process.Kill() })
+	defer timer.Stop()
+	return p.cmd.Wait()
+}
@@ -0,0 +1,80 @@
+package logcat
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the Entry message in entry.proto
+const (
+	protoTime protowire.Number = iota + 1
+	protoLevel
+	protoTag
+	protoPID
+	protoTID
+	protoUID
+	protoMessage
+	protoDevice
+	protoTimeMicros
+)
+
+// Proto formats entries as length-delimited protobuf Entry messages, as
+// defined in entry.proto
+var Proto Formatter = FormatterFunc(func(e Entry) ([]byte, error) {
+	var m []byte
+	if t := protoTimestamp(e.Time); t != 0 {
+		m = protowire.AppendTag(m, protoTime, protowire.VarintType)
+		m = protowire.AppendVarint(m, uint64(t))
+	}
+	if us := e.Time.Nanosecond() / int(time.Microsecond) % 1000; us != 0 {
+		m = protowire.AppendTag(m, protoTimeMicros, protowire.VarintType)
+		m = protowire.AppendVarint(m, uint64(us))
+	}
+	if i := strings.Index(Levels, e.Level); e.Level != "" && i >= 0 {
+		m = protowire.AppendTag(m, protoLevel, protowire.VarintType)
+		m = protowire.AppendVarint(m, uint64(i+2))
+	}
+	m = appendProtoString(m, protoTag, e.Tag)
+	m = appendProtoInt(m, protoPID, e.PID)
+	m = appendProtoInt(m, protoTID, e.TID)
+	m = appendProtoString(m, protoUID, e.UID)
+	m = appendProtoString(m, protoMessage, e.Message)
+	m = appendProtoString(m, protoDevice, e.Device)
+	return protowire.AppendBytes(nil, m), nil
+})
+
+// protoTimestamp returns t in Unix milliseconds, placing times without a
+// year in the year InferYear gives in the host's time zone
+func protoTimestamp(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	year := t.Year()
+	if year == 0 {
+		year = InferYear(t, time.Now())
+	}
+	return time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local).UnixMilli()
+}
+
+// appendProtoString appends a string field unless it is empty
+func appendProtoString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// appendProtoInt appends a numeric field given as text unless it is zero or
+// not a number
+func appendProtoInt(b []byte, num protowire.Number, s string) []byte {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+	if err != nil || n == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(n))
+}
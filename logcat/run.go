@@ -0,0 +1,37 @@
+package logcat
+
+import (
+	"bufio"
+	"context"
+)
+
+// Run reads src until it ends or ctx is canceled, calling fn with each
+// threadtime line; lines in other formats are skipped. An error returned by
+// fn stops the stream and is returned by Run. Canceling ctx closes the
+// stream, ending any blocked read, and Run returns ctx.Err().
+func Run(ctx context.Context, src Source, fn func(Entry) error) error {
+	r, err := src.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	stop := context.AfterFunc(ctx, func() { r.Close() })
+	defer stop()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry, ok := Parse(scanner.Text()); ok {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
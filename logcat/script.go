@@ -0,0 +1,811 @@
+package logcat
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Script is a compiled expression over an entry, such as
+//
+//	msg matches "latency=(\\d+)" && int(group(1)) > 200
+//
+// Values are strings, numbers and booleans. Identifiers name the entry's
+// fields (tag, level, pid, tid, uid, msg or message, and time); strings are
+// quoted with " or a backtick. Operators, loosest binding first, are ||, &&,
+// !, the comparisons ==, !=, <, <=, >, >=, matches or ~ (a regular
+// expression, whose groups group(n) returns), !~ and contains, then + (which
+// also joins strings), -, *, / and %, and finally unary -. Compared with a
+// field, an unquoted word is a string, as in tag == ActivityManager, and
+// level orders by severity, as in level >= W. The functions are int, str,
+// len, lower, upper, trim, group, replace(s, pattern, with) and severity,
+// which turns a level into 0 (V) to 5 (F).
+type Script struct {
+	src  string
+	eval evalFunc
+}
+
+// Env is the state a script runs in: the entry and the groups of the last
+// successful matches. Scripts run one after another in the same Env share
+// those groups.
+type Env struct {
+	Entry  Entry
+	groups []string
+}
+
+// NewEnv returns an Env for running scripts on e
+func NewEnv(e Entry) *Env {
+	return &Env{Entry: e}
+}
+
+// SetGroups sets the groups group(n) returns, such as those of a pattern
+// matched outside the script
+func (env *Env) SetGroups(groups []string) {
+	env.groups = groups
+}
+
+// evalFunc evaluates a compiled node
+type evalFunc func(env *Env) (any, error)
+
+// CompileScript compiles a script
+func CompileScript(src string) (*Script, error) {
+	return compile(src, "script")
+}
+
+// ParseFilter parses a filter expression, a script returning a boolean,
+// such as
+//
+//	tag == ActivityManager && level >= W || msg ~ "timed? out" && !(pid == 1234)
+func ParseFilter(expr string) (Filter, error) {
+	s, err := compile(expr, "filter")
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compile compiles src, naming it kind in errors
+func compile(src, kind string) (*Script, error) {
+	p := &scriptParser{src: src, kind: kind}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	eval, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+	return &Script{src: src, eval: eval}, nil
+}
+
+// String returns the script's source
+func (s *Script) String() string {
+	return s.src
+}
+
+// Eval runs the script, returning a string, a number (float64) or a bool
+func (s *Script) Eval(env *Env) (any, error) {
+	v, err := s.eval(env)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.src, err)
+	}
+	return v, nil
+}
+
+// Bool runs a script that must return a boolean
+func (s *Script) Bool(env *Env) (bool, error) {
+	v, err := s.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s: returned %s, not a boolean", s.src, typeName(v))
+	}
+	return b, nil
+}
+
+// Text runs a script and converts its result to a string
+func (s *Script) Text(env *Env) (string, error) {
+	v, err := s.Eval(env)
+	if err != nil {
+		return "", err
+	}
+	return toString(v), nil
+}
+
+// Match makes a script a Filter, keeping the entries for which it returns
+// true; errors drop the entry
+func (s *Script) Match(e Entry) bool {
+	ok, err := s.Bool(NewEnv(e))
+	return ok && err == nil
+}
+
+// Tokens of the scripting language
+const (
+	tokEOF = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+	tokOther // A character no token starts with, an error unless in a word
+)
+
+// scriptToken is a token with its text and, for literals, its value
+type scriptToken struct {
+	kind  int
+	text  string
+	value any
+}
+
+// scriptOperators lists the operator tokens, longest first
+var scriptOperators = []string{"||", "&&", "==", "!=", "!~", "<=", ">=", "<", ">", "~", "+", "-", "*", "/", "%", "!", "(", ")", ","}
+
+// comparisonOperators lists the comparison operators
+var comparisonOperators = []string{"==", "!=", "<=", ">=", "<", ">", "matches", "~", "!~", "contains"}
+
+// wordEnd matches the characters that end an unquoted word
+const wordEnd = "()!=<>~&|,\"`"
+
+// scriptParser is a recursive descent compiler for scripts
+type scriptParser struct {
+	src  string
+	kind string // "script" or "filter", for errors
+	pos  int
+	tok  scriptToken
+}
+
+// errorf returns a compile error at the current token
+func (p *scriptParser) errorf(format string, a ...any) error {
+	return p.errorAt(p.tokStart(), format, a...)
+}
+
+// errorAt returns a compile error at offset
+func (p *scriptParser) errorAt(offset int, format string, a ...any) error {
+	return fmt.Errorf("%s %q at offset %d: %s", p.kind, p.src, offset, fmt.Sprintf(format, a...))
+}
+
+// tokStart returns the offset of the current token
+func (p *scriptParser) tokStart() int {
+	return p.pos - len(p.tok.text)
+}
+
+// next reads the next token
+func (p *scriptParser) next() error {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	rest := p.src[p.pos:]
+	switch {
+	case rest == "":
+		p.tok = scriptToken{kind: tokEOF}
+		return nil
+	case rest[0] == '"' || rest[0] == '`':
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return p.errorAt(p.pos, "unterminated string")
+		}
+		s, _ := strconv.Unquote(quoted)
+		p.tok = scriptToken{kind: tokString, text: quoted, value: s}
+	case rest[0] >= '0' && rest[0] <= '9':
+		end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+		if end < 0 {
+			end = len(rest)
+		}
+		// An invalid number, such as a version, may still be an unquoted
+		// word, so it is only an error when used
+		p.tok = scriptToken{kind: tokNumber, text: rest[:end]}
+		if f, err := strconv.ParseFloat(rest[:end], 64); err == nil {
+			p.tok.value = f
+		}
+	case unicode.IsLetter(rune(rest[0])) || rest[0] == '_':
+		end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' })
+		if end < 0 {
+			end = len(rest)
+		}
+		p.tok = scriptToken{kind: tokIdent, text: rest[:end]}
+	default:
+		p.tok = scriptToken{}
+		for _, op := range scriptOperators {
+			if strings.HasPrefix(rest, op) {
+				p.tok = scriptToken{kind: tokOp, text: op}
+				break
+			}
+		}
+		if p.tok.text == "" {
+			_, size := utf8.DecodeRuneInString(rest)
+			p.tok = scriptToken{kind: tokOther, text: rest[:size]}
+		}
+	}
+	p.pos += len(p.tok.text)
+	return nil
+}
+
+// accept consumes the operator or keyword text if it comes next
+func (p *scriptParser) accept(text string) (bool, error) {
+	if (p.tok.kind != tokOp && p.tok.kind != tokIdent) || p.tok.text != text {
+		return false, nil
+	}
+	return true, p.next()
+}
+
+// acceptAny consumes the first of ops that comes next, returning "" if none
+// does
+func (p *scriptParser) acceptAny(ops []string) (string, error) {
+	for _, op := range ops {
+		if ok, err := p.accept(op); ok || err != nil {
+			return op, err
+		}
+	}
+	return "", nil
+}
+
+// binary parses operands joined by any of ops, combining them with apply
+func (p *scriptParser) binary(operand func() (evalFunc, error), ops []string, apply func(op string, a, b evalFunc) (evalFunc, error)) (evalFunc, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, err := p.acceptAny(ops)
+		if err != nil {
+			return nil, err
+		}
+		if op == "" {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = apply(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// or parses conditions joined by ||
+func (p *scriptParser) or() (evalFunc, error) {
+	return p.binary(p.and, []string{"||"}, func(op string, a, b evalFunc) (evalFunc, error) {
+		return logical(a, b, true), nil
+	})
+}
+
+// and parses conditions joined by &&
+func (p *scriptParser) and() (evalFunc, error) {
+	return p.binary(p.not, []string{"&&"}, func(op string, a, b evalFunc) (evalFunc, error) {
+		return logical(a, b, false), nil
+	})
+}
+
+// not parses a condition negated by !, which binds more loosely than
+// comparisons so that !tag == X negates the comparison
+func (p *scriptParser) not() (evalFunc, error) {
+	if ok, err := p.accept("!"); err != nil || !ok {
+		if err != nil {
+			return nil, err
+		}
+		return p.comparison()
+	}
+	operand, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	return func(env *Env) (any, error) {
+		v, err := operand(env)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply ! to %s", typeName(v))
+		}
+		return !b, nil
+	}, nil
+}
+
+// logical returns a || b when or is set and a && b otherwise, evaluating b
+// only when needed
+func logical(a, b evalFunc, or bool) evalFunc {
+	return func(env *Env) (any, error) {
+		for _, f := range []evalFunc{a, b} {
+			v, err := f(env)
+			if err != nil {
+				return nil, err
+			}
+			x, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("&& and || need booleans, not %s", typeName(v))
+			}
+			if x == or {
+				return x, nil
+			}
+		}
+		return !or, nil
+	}
+}
+
+// comparison parses a comparison, a match or a contains test. A field
+// compared with an unquoted word compares with the word as a string, and
+// level ordered against another value compares severities.
+func (p *scriptParser) comparison() (evalFunc, error) {
+	start := p.tokStart()
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	field := strings.ToLower(strings.TrimSpace(p.src[start:p.tokStart()]))
+	if _, ok := scriptFields[field]; !ok {
+		field = ""
+	}
+	for {
+		op, err := p.acceptAny(comparisonOperators)
+		if err != nil {
+			return nil, err
+		}
+		if op == "" {
+			return left, nil
+		}
+		var right evalFunc
+		word, ok, at := "", false, p.tokStart()
+		if field != "" {
+			if word, ok, err = p.word(); err != nil {
+				return nil, err
+			}
+		}
+		if ok {
+			right = func(*Env) (any, error) { return word, nil }
+		} else if right, err = p.additive(); err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case "matches", "~", "!~":
+			left = matchesOp(left, right, op == "!~")
+		case "contains":
+			a, b := left, right
+			left = func(env *Env) (any, error) {
+				s, sub, err := evalStrings(env, a, b, op)
+				return err == nil && strings.Contains(s, sub), err
+			}
+		default:
+			a, b := left, right
+			if field == "level" && op != "==" && op != "!=" {
+				if ok && !isLevel(word) {
+					return nil, p.errorAt(at, "%s can only compare levels (%s)", op, Levels)
+				}
+				a, b = severityOf(a), severityOf(b)
+			}
+			left = func(env *Env) (any, error) {
+				x, y, err := evalPair(env, a, b)
+				if err != nil {
+					return nil, err
+				}
+				return compare(op, x, y)
+			}
+		}
+		field = ""
+	}
+}
+
+// word reads an unquoted word compared with a field, up to a space or an
+// operator character such as = or &. Strings, parentheses, fields, booleans
+// and function calls are not words.
+func (p *scriptParser) word() (string, bool, error) {
+	start := p.tokStart()
+	switch p.tok.kind {
+	case tokEOF, tokString:
+		return "", false, nil
+	case tokIdent:
+		_, field := scriptFields[strings.ToLower(p.tok.text)]
+		call := strings.HasPrefix(strings.TrimLeftFunc(p.src[p.pos:], unicode.IsSpace), "(")
+		if field || call || p.tok.text == "true" || p.tok.text == "false" {
+			return "", false, nil
+		}
+	}
+	if strings.ContainsRune(wordEnd, rune(p.src[start])) {
+		return "", false, nil
+	}
+	end := start
+	for end < len(p.src) && !unicode.IsSpace(rune(p.src[end])) && !strings.ContainsRune(wordEnd, rune(p.src[end])) {
+		end++
+	}
+	p.pos = end
+	return p.src[start:end], true, p.next()
+}
+
+// isLevel reports whether s names a level
+func isLevel(s string) bool {
+	return len(s) == 1 && strings.Contains(Levels, strings.ToUpper(s))
+}
+
+// severityOf turns the level a evaluates to into its severity, keeping
+// severities such as those severity returns
+func severityOf(a evalFunc) evalFunc {
+	return func(env *Env) (any, error) {
+		v, err := a(env)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case float64:
+			return v, nil
+		case string:
+			if isLevel(v) {
+				return float64(strings.Index(Levels, strings.ToUpper(v))), nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a level (%s)", toString(v), Levels)
+	}
+}
+
+// regexpCache keeps the pattern last compiled at one place in a script, so
+// that a pattern is compiled again only when it changes
+type regexpCache struct {
+	mu sync.Mutex
+	re *regexp.Regexp
+}
+
+// compile returns pattern compiled
+func (c *regexpCache) compile(pattern string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.re == nil || c.re.String() != pattern {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		c.re = re
+	}
+	return c.re, nil
+}
+
+// matchesOp returns a test of a against the pattern b, negated if not is
+// set, recording the groups of a match
+func matchesOp(a, b evalFunc, not bool) evalFunc {
+	var cache regexpCache
+	return func(env *Env) (any, error) {
+		s, pattern, err := evalStrings(env, a, b, "matches")
+		if err != nil {
+			return nil, err
+		}
+		re, err := cache.compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		m := re.FindStringSubmatch(s)
+		if m != nil {
+			env.groups = m
+		}
+		return (m != nil) != not, nil
+	}
+}
+
+// additive parses terms joined by + and -
+func (p *scriptParser) additive() (evalFunc, error) {
+	return p.binary(p.multiplicative, []string{"+", "-"}, arithmetic)
+}
+
+// multiplicative parses factors joined by *, / and %
+func (p *scriptParser) multiplicative() (evalFunc, error) {
+	return p.binary(p.unary, []string{"*", "/", "%"}, arithmetic)
+}
+
+// arithmetic applies an arithmetic operator; + joins strings when either
+// side is one
+func arithmetic(op string, a, b evalFunc) (evalFunc, error) {
+	return func(env *Env) (any, error) {
+		x, y, err := evalPair(env, a, b)
+		if err != nil {
+			return nil, err
+		}
+		_, xs := x.(string)
+		_, ys := y.(string)
+		if op == "+" && (xs || ys) {
+			return toString(x) + toString(y), nil
+		}
+		m, okm := x.(float64)
+		n, okn := y.(float64)
+		if !okm || !okn {
+			return nil, fmt.Errorf("%s needs numbers, not %s and %s", op, typeName(x), typeName(y))
+		}
+		switch op {
+		case "+":
+			return m + n, nil
+		case "-":
+			return m - n, nil
+		case "*":
+			return m * n, nil
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return m / n, nil
+		}
+		return math.Mod(m, n), nil
+	}, nil
+}
+
+// unary parses - applied to an operand
+func (p *scriptParser) unary() (evalFunc, error) {
+	if ok, err := p.accept("-"); err != nil || !ok {
+		if err != nil {
+			return nil, err
+		}
+		return p.primary()
+	}
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return func(env *Env) (any, error) {
+		v, err := operand(env)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply - to %s", typeName(v))
+		}
+		return -f, nil
+	}, nil
+}
+
+// primary parses a literal, a field, a function call or a parenthesized
+// expression
+func (p *scriptParser) primary() (evalFunc, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber, tokString:
+		if tok.value == nil {
+			return nil, p.errorf("invalid number %q", tok.text)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		return func(*Env) (any, error) { return tok.value, nil }, nil
+	case tokOp:
+		if tok.text != "(" {
+			break
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if ok, err := p.accept(")"); err != nil || !ok {
+			return nil, p.errorf("missing )")
+		}
+		return inner, nil
+	case tokIdent:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.accept("("); err != nil {
+			return nil, err
+		} else if ok {
+			return p.call(tok.text)
+		}
+		switch tok.text {
+		case "true", "false":
+			b := tok.text == "true"
+			return func(*Env) (any, error) { return b, nil }, nil
+		}
+		field, ok := scriptFields[strings.ToLower(tok.text)]
+		if !ok {
+			return nil, p.errorf("unknown field %q", tok.text)
+		}
+		return func(env *Env) (any, error) { return field(env.Entry), nil }, nil
+	}
+	if tok.kind == tokEOF {
+		return nil, p.errorf("unexpected end")
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+// scriptFields returns the fields of an entry by name
+var scriptFields = map[string]func(Entry) string{
+	"tag":     func(e Entry) string { return e.Tag },
+	"level":   func(e Entry) string { return e.Level },
+	"pid":     func(e Entry) string { return e.PID },
+	"tid":     func(e Entry) string { return e.TID },
+	"uid":     func(e Entry) string { return e.UID },
+	"msg":     func(e Entry) string { return e.Message },
+	"message": func(e Entry) string { return e.Message },
+	"time":    func(e Entry) string { return e.FormatTime() },
+}
+
+// scriptFunction is a built-in function and its number of arguments
+type scriptFunction struct {
+	args    int
+	pattern int // Position, from 1, of an argument passed compiled as a *regexp.Regexp, or 0
+	call    func(env *Env, args []any) (any, error)
+}
+
+// scriptFunctions lists the built-in functions
+var scriptFunctions = map[string]scriptFunction{
+	"int": {1, 0, func(env *Env, args []any) (any, error) {
+		switch v := args[0].(type) {
+		case float64:
+			return math.Trunc(v), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("int(%q): not a number", v)
+			}
+			return math.Trunc(f), nil
+		}
+		return nil, fmt.Errorf("int of %s", typeName(args[0]))
+	}},
+	"str": {1, 0, func(env *Env, args []any) (any, error) { return toString(args[0]), nil }},
+	"len": {1, 0, func(env *Env, args []any) (any, error) {
+		return float64(utf8.RuneCountInString(toString(args[0]))), nil
+	}},
+	"lower": {1, 0, func(env *Env, args []any) (any, error) { return strings.ToLower(toString(args[0])), nil }},
+	"upper": {1, 0, func(env *Env, args []any) (any, error) { return strings.ToUpper(toString(args[0])), nil }},
+	"trim":  {1, 0, func(env *Env, args []any) (any, error) { return strings.TrimSpace(toString(args[0])), nil }},
+	"group": {1, 0, func(env *Env, args []any) (any, error) {
+		n, ok := args[0].(float64)
+		if !ok {
+			return nil, fmt.Errorf("group of %s", typeName(args[0]))
+		}
+		if i := int(n); i >= 0 && i < len(env.groups) {
+			return env.groups[i], nil
+		}
+		return "", nil
+	}},
+	"replace": {3, 2, func(env *Env, args []any) (any, error) {
+		return args[1].(*regexp.Regexp).ReplaceAllString(toString(args[0]), toString(args[2])), nil
+	}},
+	"severity": {1, 0, func(env *Env, args []any) (any, error) {
+		return float64(strings.Index(Levels, strings.ToUpper(toString(args[0])))), nil
+	}},
+}
+
+// call parses the arguments of a function call
+func (p *scriptParser) call(name string) (evalFunc, error) {
+	fn, ok := scriptFunctions[name]
+	if !ok {
+		return nil, p.errorf("unknown function %s", name)
+	}
+	var args []evalFunc
+	for {
+		if ok, err := p.accept(")"); err != nil {
+			return nil, err
+		} else if ok {
+			break
+		}
+		if len(args) > 0 {
+			if ok, err := p.accept(","); err != nil || !ok {
+				return nil, p.errorf("expected , or ) in call to %s", name)
+			}
+		}
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) != fn.args {
+		return nil, p.errorf("%s takes %d arguments, not %d", name, fn.args, len(args))
+	}
+	var cache regexpCache
+	return func(env *Env) (any, error) {
+		values := make([]any, len(args))
+		for i, arg := range args {
+			v, err := arg(env)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		if fn.pattern > 0 {
+			re, err := cache.compile(toString(values[fn.pattern-1]))
+			if err != nil {
+				return nil, err
+			}
+			values[fn.pattern-1] = re
+		}
+		return fn.call(env, values)
+	}, nil
+}
+
+// evalPair evaluates two operands
+func evalPair(env *Env, a, b evalFunc) (any, any, error) {
+	x, err := a(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	y, err := b(env)
+	return x, y, err
+}
+
+// evalStrings evaluates two operands that must be strings
+func evalStrings(env *Env, a, b evalFunc, op string) (string, string, error) {
+	x, y, err := evalPair(env, a, b)
+	if err != nil {
+		return "", "", err
+	}
+	s, oks := x.(string)
+	t, okt := y.(string)
+	if !oks || !okt {
+		return "", "", fmt.Errorf("%s needs strings, not %s and %s", op, typeName(x), typeName(y))
+	}
+	return s, t, nil
+}
+
+// compare applies a comparison operator to two values of the same type
+func compare(op string, x, y any) (any, error) {
+	var c int
+	switch m := x.(type) {
+	case float64:
+		n, ok := y.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number and %s", typeName(y))
+		}
+		c = cmp.Compare(m, n)
+	case string:
+		n, ok := y.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string and %s", typeName(y))
+		}
+		c = strings.Compare(m, n)
+	case bool:
+		n, ok := y.(bool)
+		if !ok || (op != "==" && op != "!=") {
+			return nil, fmt.Errorf("cannot apply %s to boolean and %s", op, typeName(y))
+		}
+		if m != n {
+			c = 1
+		}
+	}
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+// toString converts a value to a string, printing whole numbers without
+// a fraction
+func toString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// typeName names the type of a value in error messages
+func typeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
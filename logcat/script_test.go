@@ -0,0 +1,161 @@
+package logcat
+
+import (
+	"strings"
+	"testing"
+)
+
+// testEntry is the entry the filter and script tests run on
+var testEntry = Entry{
+	Line:    "04-19 19:34:18.813  1234  1250 W ActivityManager: request timed out after 250ms, v1.2.3",
+	PID:     "1234",
+	TID:     "1250",
+	Level:   "W",
+	Tag:     "ActivityManager",
+	Message: "request timed out after 250ms, v1.2.3",
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`tag == ActivityManager`, true},
+		{`tag != ActivityManager`, false},
+		{`TAG == ActivityManager`, true},
+		{`tag=="ActivityManager"`, true},
+		{`level >= W`, true},
+		{`level > W`, false},
+		{`level < e`, true},
+		{`level == W && tag == Other`, false},
+		{`level == W || tag == Other`, true},
+		{`tag == Other || level >= E || msg ~ "timed? out"`, true},
+		{`msg ~ ^request`, true},
+		{`msg !~ ^request`, false},
+		{`msg ~ v1.2.3`, true},
+		{`msg matches "after (\\d+)ms" && int(group(1)) > 200`, true},
+		{`msg contains timed`, true},
+		{`pid == 1234`, true},
+		{`int(pid) > 1000`, true},
+		{`!(pid == 1234)`, false},
+		{`!tag == Other`, true},
+		{`!(tag == Other) && (level == W || level == E)`, true},
+		{`uid == ""`, true},
+		{`severity(level) == 3`, true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(testEntry); got != tt.want {
+			t.Errorf("ParseFilter(%q).Match() = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{`colour == red`, `unknown field "colour"`},
+		{`tag ==`, "unexpected end"},
+		{`level >= X`, ">= can only compare levels"},
+		{`(tag == A`, "missing )"},
+		{`tag == A)`, `unexpected ")"`},
+		{`msg ~ "unterminated`, "unterminated string"},
+		{`tag == A & level == W`, `unexpected "&"`},
+		{`lower(tag, msg) == a`, "lower takes 1 arguments, not 2"},
+		{`nope(tag)`, "unknown function nope"},
+	}
+	for _, tt := range tests {
+		_, err := ParseFilter(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseFilter(%q) error = %v, want one containing %q", tt.expr, err, tt.err)
+		}
+	}
+}
+
+func TestCompileScript(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`msg`, "request timed out after 250ms, v1.2.3"},
+		{`tag + "/" + level`, "ActivityManager/W"},
+		{`upper(tag)`, "ACTIVITYMANAGER"},
+		{`len(tag) * 2 - 1`, "29"},
+		{`7 % 4 + 10 / 4`, "5.5"},
+		{`-int(pid)`, "-1234"},
+		{`trim("  x  ")`, "x"},
+		{`str(1 < 2)`, "true"},
+		{`replace(msg, "[0-9]+", "N")`, "request timed out after Nms, vN.N.N"},
+		{`replace(tag, "(Activity)(Manager)", "$2$1")`, "ManagerActivity"},
+		{`msg matches "after (\\d+)(ms)" && group(2) == "ms"`, "true"},
+		{"msg matches `v(\\d)\\.` && group(1) == \"1\"", "true"},
+		{`msg matches "nothing" || group(0) == ""`, "true"},
+		{`time`, "04-19 19:34:18.813"},
+		{`lower(level) == "w" && !false`, "true"},
+	}
+	e := testEntry
+	e, _ = Parse(e.Line)
+	for _, tt := range tests {
+		s, err := CompileScript(tt.src)
+		if err != nil {
+			t.Errorf("CompileScript(%q): %v", tt.src, err)
+			continue
+		}
+		got, err := s.Text(NewEnv(e))
+		if err != nil {
+			t.Errorf("CompileScript(%q).Text(): %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CompileScript(%q).Text() = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{`tag && true`, "&& and || need booleans, not string"},
+		{`1 + true`, "+ needs numbers, not number and boolean"},
+		{`1 / 0`, "division by zero"},
+		{`int(tag)`, "not a number"},
+		{`-tag`, "cannot apply - to string"},
+		{`!tag`, "cannot apply ! to string"},
+		{`1 < "2"`, "cannot compare number and string"},
+		{`msg matches "("`, "missing closing )"},
+		{`replace(msg, "(", "")`, "missing closing )"},
+	}
+	for _, tt := range tests {
+		s, err := CompileScript(tt.src)
+		if err != nil {
+			t.Errorf("CompileScript(%q): %v", tt.src, err)
+			continue
+		}
+		if _, err := s.Eval(NewEnv(testEntry)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("CompileScript(%q).Eval() error = %v, want one containing %q", tt.src, err, tt.err)
+		}
+	}
+}
+
+func TestScriptGroups(t *testing.T) {
+	s, err := CompileScript(`"took " + group(1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := NewEnv(testEntry)
+	env.SetGroups([]string{"after 250ms", "250"})
+	if got, err := s.Text(env); err != nil || got != "took 250" {
+		t.Errorf("Text() = %q, %v, want %q", got, err, "took 250")
+	}
+	if ok, err := s.Bool(env); err == nil || ok {
+		t.Errorf("Bool() of a string = %v, %v, want an error", ok, err)
+	}
+}
@@ -0,0 +1,128 @@
+package logcat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// Sink receives entries, such as a terminal, a file or a network peer
+type Sink interface {
+	Write(Entry) error
+	Close() error
+}
+
+// WriterSink writes entries to W in the format of Format, starting with
+// its header if it is a HeaderFormatter
+type WriterSink struct {
+	W      io.Writer
+	Format Formatter
+	Name   string // Describes W in errors, e.g. a file name or address
+
+	started bool
+}
+
+// Write formats e and writes it
+func (s *WriterSink) Write(e Entry) error {
+	data, err := s.Format.Format(e)
+	if h, ok := s.Format.(HeaderFormatter); ok && !s.started {
+		data = append(h.Header(), data...)
+	}
+	if err == nil {
+		_, err = s.W.Write(data)
+		s.started = true
+	}
+	if err != nil && s.Name != "" {
+		return fmt.Errorf("%s: %w", s.Name, err)
+	}
+	return err
+}
+
+// Close closes W if it is an io.Closer
+func (s *WriterSink) Close() error {
+	if c, ok := s.W.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// NewFileSink creates the file at path and writes entries to it
+func NewFileSink(path string, f Formatter) (*WriterSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterSink{W: file, Format: f, Name: path}, nil
+}
+
+// NewNetworkSink connects to addr over network ("tcp", "udp" or "unix")
+// and sends entries to it
+func NewNetworkSink(network, addr string, f Formatter) (*WriterSink, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterSink{W: conn, Format: f, Name: network + "://" + addr}, nil
+}
+
+// Dispatcher fans entries out to several sinks. A sink that fails is
+// closed and dropped so that the others keep receiving entries.
+type Dispatcher struct {
+	mu    sync.Mutex
+	sinks []Sink
+}
+
+// NewDispatcher returns a dispatcher writing to sinks
+func NewDispatcher(sinks ...Sink) *Dispatcher {
+	return &Dispatcher{sinks: sinks}
+}
+
+// Add starts sending entries to s
+func (d *Dispatcher) Add(s Sink) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sinks = append(d.sinks, s)
+}
+
+// Len returns the number of sinks still receiving entries
+func (d *Dispatcher) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.sinks)
+}
+
+// Write sends e to every sink, returning the errors of those that failed
+// and were dropped
+func (d *Dispatcher) Write(e Entry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var errs []error
+	kept := d.sinks[:0]
+	for _, s := range d.sinks {
+		if err := s.Write(e); err != nil {
+			errs = append(errs, err)
+			s.Close()
+			continue
+		}
+		kept = append(kept, s)
+	}
+	d.sinks = kept
+	return errors.Join(errs...)
+}
+
+// Close closes every sink
+func (d *Dispatcher) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var errs []error
+	for _, s := range d.sinks {
+		errs = append(errs, s.Close())
+	}
+	d.sinks = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,176 @@
+package logcat
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Source opens a stream of logcat output: an adb logcat process, a file, a
+// socket or any other reader
+type Source interface {
+	Open(ctx context.Context) (io.ReadCloser, error)
+}
+
+// Reader is a Source reading from an open reader, such as a network
+// connection or a test buffer. It is closed with the stream if it is an
+// io.Closer.
+type Reader struct {
+	R io.Reader
+}
+
+// Open returns the reader
+func (s Reader) Open(ctx context.Context) (io.ReadCloser, error) {
+	if rc, ok := s.R.(io.ReadCloser); ok {
+		return rc, nil
+	}
+	return io.NopCloser(s.R), nil
+}
+
+// File is a Source reading a saved capture
+type File string
+
+// Open opens the file
+func (f File) Open(ctx context.Context) (io.ReadCloser, error) {
+	return os.Open(string(f))
+}
+
+// Command is a Source reading the output of a command, such as logcat run
+// over ssh
+type Command struct {
+	Name string
+	Args []string
+}
+
+// Open starts the command; closing the stream stops it
+func (c Command) Open(ctx context.Context) (io.ReadCloser, error) {
+	return startCommand(ctx, c.Name, c.Args)
+}
+
+// commandStream is the output of a running command
+type commandStream struct {
+	io.ReadCloser
+	cmd   *exec.Cmd
+	close sync.Once
+}
+
+// startCommand runs a command, returning its output
+func startCommand(ctx context.Context, name string, args []string) (*commandStream, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &commandStream{ReadCloser: stdout, cmd: cmd}, nil
+}
+
+// Close stops the command if it is still running and waits for it. It may
+// be called more than once, and while Read is blocked, to end the read.
+func (s *commandStream) Close() error {
+	s.close.Do(func() {
+		s.cmd.Process.Kill()
+		s.cmd.Wait()
+	})
+	return nil
+}
+
+// ADB streams logcat -v threadtime from a device through adb
+type ADB struct {
+	Serial     string        // Device serial, empty for adb's default device
+	Args       []string      // Extra logcat arguments, such as "-b", "crash"
+	Reconnect  bool          // Wait for the device and restart logcat whenever it exits
+	RetryDelay time.Duration // Pause before reconnecting, one second if zero
+}
+
+// adbStream reads one adb logcat process after another
+type adbStream struct {
+	ctx    context.Context
+	source ADB
+
+	mu     sync.Mutex // Guards stream and closed against a concurrent Close
+	stream *commandStream
+	closed bool
+}
+
+// Open starts adb logcat
+func (a ADB) Open(ctx context.Context) (io.ReadCloser, error) {
+	s := &adbStream{ctx: ctx, source: a}
+	if err := s.start(false); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// start runs adb logcat, first waiting for the device when reconnecting
+func (s *adbStream) start(wait bool) error {
+	var args []string
+	if s.source.Serial != "" {
+		args = append(args, "-s", s.source.Serial)
+	}
+	if wait {
+		args = append(args, "wait-for-device")
+	}
+	args = append(append(args, "logcat", "-v", "threadtime"), s.source.Args...)
+	stream, err := startCommand(s.ctx, "adb", args)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		stream.Close()
+		return io.EOF
+	}
+	s.stream = stream
+	return nil
+}
+
+// current returns the running adb logcat process
+func (s *adbStream) current() *commandStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream
+}
+
+// Close stops adb logcat and any reconnection
+func (s *adbStream) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	stream := s.stream
+	s.mu.Unlock()
+	return stream.Close()
+}
+
+// Read reads from adb logcat, restarting it at the end of its output when
+// reconnecting
+func (s *adbStream) Read(p []byte) (int, error) {
+	for {
+		stream := s.current()
+		n, err := stream.Read(p)
+		if err != io.EOF || !s.source.Reconnect {
+			return n, err
+		}
+		stream.Close()
+		delay := s.source.RetryDelay
+		if delay == 0 {
+			delay = time.Second
+		}
+		select {
+		case <-s.ctx.Done():
+			return n, io.EOF
+		case <-time.After(delay):
+		}
+		if err := s.start(true); err != nil {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
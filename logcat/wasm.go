@@ -0,0 +1,131 @@
+package logcat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// WASMPlugin is a WebAssembly module that filters and rewrites entries in a
+// sandbox, with no access to files or the network. The module exports its
+// memory and two functions:
+//
+//	alloc(size i32) i32          // returns a buffer of size bytes for the next entry
+//	process(ptr i32, len i32) i64 // handles the entry written to the buffer
+//
+// Each entry is written to a buffer from alloc as a JSON object, in the
+// JSON format. process answers as a Plugin does, with the entry or null,
+// packed as the answer's address in the high 32 bits and its length in the
+// low 32 bits; 0 drops the entry. Modules may import WASI, whose output goes
+// to stderr.
+type WASMPlugin struct {
+	ctx     context.Context
+	name    string
+	runtime wazero.Runtime
+	memory  api.Memory
+	alloc   api.Function
+	process api.Function
+	mu      sync.Mutex
+	err     error
+}
+
+// LoadWASMPlugin compiles and instantiates the module at path. Compiled
+// modules are kept in cacheDir, unless it is empty, to load faster next time.
+func LoadWASMPlugin(ctx context.Context, path, cacheDir string) (*WASMPlugin, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := wazero.NewRuntimeConfig()
+	if cacheDir != "" {
+		cache, err := wazero.NewCompilationCacheWithDir(cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		config = config.WithCompilationCache(cache)
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+	modConfig := wazero.NewModuleConfig().WithStdout(os.Stderr).WithStderr(os.Stderr).WithStartFunctions("_initialize")
+	mod, err := runtime.InstantiateWithConfig(ctx, code, modConfig)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+
+	p := &WASMPlugin{ctx: ctx, name: path, runtime: runtime, memory: mod.Memory(), alloc: mod.ExportedFunction("alloc"), process: mod.ExportedFunction("process")}
+	switch {
+	case p.memory == nil:
+		err = fmt.Errorf("%s exports no memory", path)
+	case p.alloc == nil || p.process == nil:
+		err = fmt.Errorf("%s must export alloc and process", path)
+	}
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	return p, nil
+}
+
+// Process passes e through the module
+func (p *WASMPlugin) Process(e Entry) (Entry, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return e, true, p.err
+	}
+	result, keep, err := p.call(e)
+	if err != nil {
+		p.err = fmt.Errorf("plugin %s: %w", p.name, err)
+		return e, true, p.err
+	}
+	return result, keep, nil
+}
+
+// call exchanges one entry with the module
+func (p *WASMPlugin) call(e Entry) (Entry, bool, error) {
+	data, err := json.Marshal(newJSONEntry(e))
+	if err != nil {
+		return e, false, err
+	}
+	results, err := p.alloc.Call(p.ctx, uint64(len(data)))
+	if err != nil {
+		return e, false, err
+	}
+	ptr := uint32(results[0])
+	if !p.memory.Write(ptr, data) {
+		return e, false, fmt.Errorf("alloc returned %#x, outside memory", ptr)
+	}
+
+	results, err = p.process.Call(p.ctx, uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return e, false, err
+	}
+	if results[0] == 0 {
+		return e, false, nil
+	}
+	answer, ok := p.memory.Read(uint32(results[0]>>32), uint32(results[0]))
+	if !ok {
+		return e, false, fmt.Errorf("process returned an answer outside memory")
+	}
+	return decodeAnswer(e, answer)
+}
+
+// Err returns the error that made the plugin unusable, if any
+func (p *WASMPlugin) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Close releases the module
+func (p *WASMPlugin) Close() error {
+	return p.runtime.Close(p.ctx)
+}
@@ -1,25 +1,129 @@
 package main
 
 import (
-	"bufio"
+	"cmp"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/erdichen/logcatcolor/logcat"
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 )
 
 // LogcatOptions holds configuration for filtering logcat output
 type LogcatOptions struct {
-	Filters   []string
-	Tag       string
-	Level     string
-	Device    string        // Serial number of the device/emulator
-	MaxDelta  time.Duration // Maximum duration for showing time differences
-	KeepGoing bool          // Whether to restart the command when it exits
+	Args         []string // Positional arguments, such as capture files
+	ConfigPath   string   // Configuration file, reloaded when it changes
+	Profile      string   // Active profile from the configuration file
+	Presets      []string // Presets activated with -preset, after Profile
+	CmdArgs      []string // Command-line arguments, which win over a reloaded profile's
+	Redact       bool     // Whether the built-in PII redactions are enabled
+	Filters      []string
+	Buffers      []string // Log buffers to read, empty for the logcat default
+	Tag          string
+	Level        string
+	Grep         *regexp.Regexp         // Message pattern that lines must match, nil for all
+	LocalFilter  bool                   // Apply Tag and Level filters here rather than in adb
+	Filter       logcat.Filter          // Further client-side filter from -filter, nil for none
+	GrepOnDevice bool                   // Also pass Grep to the device's logcat -e to cut traffic
+	Window       TimeWindow             // Replayed lines must fall inside this window
+	Seek         bool                   // Page through replayed captures with keyboard navigation
+	Skew         bool                   // Correct device clock skew in merged captures and multi-device streams
+	SyncMarker   *regexp.Regexp         // Event assumed to happen at the same moment in each merged capture
+	HostTime     bool                   // Show device timestamps in host time
+	HostClock    *HostClock             // Converts timestamps for HostTime once measured
+	Capture      *CaptureFile           // Receives every line read, before filtering, for -write
+	Split        *Splitter              // Also writes every line into per-tag or per-process files
+	SplitOnly    bool                   // Write split files without printing to the terminal
+	OutDir       string                 // Directory for split files and recorded sessions
+	Session      *Session               // Session being recorded by the record command
+	Ring         *RingBuffer            // Recent lines kept in memory for -ring dumps
+	RingTrigger  *regexp.Regexp         // Lines that dump the ring buffer
+	Incidents    *IncidentRecorder      // Saves the lines around crashes and ANRs
+	Latency      *LatencyTracker        // Intervals between lines of the -latency-tags
+	Report       *RateReport            // Per-tag and per-process counts printed at exit
+	Exceptions   *ExceptionCensus       // Exception signatures printed at exit
+	Binder       *BinderStats           // Binder failures counted for the summary at exit
+	Quiet        bool                   // Print statistics and incidents instead of lines
+	Net          bool                   // Show only networking lines, under a timeline of connectivity changes
+	Jobs         *JobTracker            // Shows only job lines, under a row for each step of a job, with -jobs
+	Stats        *IntervalStats         // Periodic statistics for -stats-interval
+	BenchLines   int                    // Synthetic lines the bench command renders
+	BenchLength  int                    // Approximate message length of synthetic lines
+	BenchRate    int                    // Lines per second fed to the bench command, 0 for as fast as possible
+	DemoRate     int                    // Lines per second of the demo command
+	DemoLines    int                    // Lines the demo command prints before exiting, 0 for no limit
+	Plugins      []logcat.Processor     // Plugins filtering and rewriting lines, in order
+	Sinks        *logcat.Dispatcher     // Receive the lines shown, in the -output formats
+	SinksOnly    bool                   // An -output to stdout replaces the colored lines
+	Output       io.Writer              // Where colored lines are printed, os.Stdout if nil
+	Lenient      bool                   // Color and filter lines in other formats by whatever fields they have
+	Usec         bool                   // Request microsecond timestamps (logcat -v usec)
+	UIDColumn    bool                   // Request and show the UID of each line (logcat -v uid)
+	UIDFilters   []string               // UIDs, app IDs or package names that lines must belong to
+	User         int                    // Android user whose lines to show, -1 for all
+	UIDNames     map[int]string         // Package names by app ID
+	AppsOnly     bool                   // Only show lines from third-party apps' processes
+	AppIDs       map[int]bool           // App IDs of the third-party packages, for AppsOnly
+	Dump         bool                   // Dump the log buffers and exit instead of streaming
+	LogcatArgs   []string               // Extra arguments appended to the adb logcat command
+	Device       string                 // Serial number of the device/emulator
+	Transport    string                 // adb transport ID, overriding Device
+	Selection    DeviceSelection        // Heuristics for choosing among several devices
+	ADBHost      string                 // Host of the adb server, empty for the default
+	ADBPort      int                    // Port of the adb server, 0 for the default
+	MaxDelta     time.Duration          // Maximum duration for showing time differences
+	DeltaMode    string                 // Which consecutive lines show time differences: tag, pid, line or off
+	DeltaFormat  string                 // Measure differences since-first line of a run or since-last line
+	DeltaColumn  bool                   // Show the time since the previous line in a column of its own
+	ShowDate     bool                   // Show the inferred year with each timestamp
+	Suggest      *MuteSuggester         // Offers to mute noisy tags with -suggest-mutes, nil without
+	Tray         *TrayStatus            // Status shown by the -tray icon, nil without one
+	Bell         string                 // Level from which shown lines ring the terminal bell, empty for none
+	LineNumbers  bool                   // Number the lines read in a column and in JSON output
+	RepeatTag    string                 // How a tag repeated from the previous line is shown: show, blank or dim
+	Detach       bool                   // Run the daemon in the background
+	RotateSize   int64                  // Size at which daemon captures are rotated, 0 for never
+	RotateKeep   int                    // Rotated daemon captures to keep
+	AlertExec    string                 // Command the daemon runs on each crash
+	KeepGoing    bool                   // Whether to restart the command when it exits
+	SourceMap    *SourceMap             // Source map for decoding React Native stack frames
+	LinkURL      string                 // URL template for file:line hyperlinks, empty to disable
+	Prettify     []string               // Message transforms from prettifiers to try, in order
+	Redactions   []RedactionRule        // Rules masking sensitive text before any output
+	Severities   []SeverityRule         // Rules remapping the level of matching lines
+	Rules        []HighlightRule        // Rules styling, hiding or acting on matching lines
+	Tags         map[string]TagOverride // Per-tag visibility, level and color overrides
+	Aliases      map[string]string      // Display names for tags
+	LineLevel    string                 // Minimum level (E or F) for full-line background highlighting
+	Pidcat       *PidcatOptions         // Non-nil in pidcat compatibility mode
+	Devices      []string               // Serials to tail together, or "all"
+	AVD          string                 // Emulator to boot before streaming
+	Prefix       string                 // Printed before every output line
+	Banner       bool                   // Print device information before streaming
+	Raw          bool                   // Print lines as received, without colors or reformatting
+	RawLine      string                 // The line being printed as read, before prepareLine, for Raw
+	HTML         string                 // Page written by the export command
+	Section      *regexp.Regexp         // Heading of the dumpsys sections to show, nil for all
+	TraceSync    bool                   // Write clock sync markers to the device's trace buffer and logcat
+	Trace        string                 // Systrace or ftrace text trace whose sections annotate replayed lines
+	TraceNames   *regexp.Regexp         // Names of the trace sections to annotate, nil for long ones
+	Wait         bool                   // Wait for the device to attach before streaming
+	DeviceInfo   *DeviceInfo            // Device information, once queried
+	Clear        bool                   // Clear the log buffers before streaming
+	ClearEach    bool                   // Also clear the log buffers before each -k restart
+	BufferSize   string                 // Size to set the log buffers to before streaming, e.g. "16M"
+	RootCapture  bool                   // Use root, when available, for extended capture
+	Root         RootAccess             // Root access detected on the device
+	ANRDir       string                 // Directory to pull /data/anr traces into on ANRs
 }
 
 // LogLevelColors maps log levels to color functions
@@ -32,115 +136,538 @@ var LogLevelColors = map[string]func(format string, a ...any) string{
 	"F": color.New(color.FgMagenta).SprintfFunc(), // Fatal: Magenta
 }
 
+// LineBackgroundColors maps log levels to colors for full-line highlighting
+var LineBackgroundColors = map[string]func(format string, a ...any) string{
+	"E": color.New(color.FgHiWhite, color.BgRed).SprintfFunc(),     // Error: White on red
+	"F": color.New(color.FgHiWhite, color.BgMagenta).SprintfFunc(), // Fatal: White on magenta
+}
+
 // TagColor is the color function for tags
 var TagColor = color.New(color.FgBlack, color.BgCyan).SprintfFunc()
 
-// lastTagTime tracks the last timestamp for each tag
-var lastTagTime = make(map[string]time.Time)
+// RepeatedTagColor is the color of a tag repeated from the previous line
+// with -repeat-tag dim
+var RepeatedTagColor = color.New(color.Faint).SprintfFunc()
 
-func main() {
-	// Parse command-line arguments for filtering
-	opts := parseArgs()
+// LineNumberColor is the color of the -line-numbers column
+var LineNumberColor = color.New(color.Faint).SprintfFunc()
 
-	for {
-		// Start adb logcat command
-		cmd := buildAdbCommand(opts)
+// lineNumbers counts the lines read in each stream, by prefix, for
+// -line-numbers
+var lineNumbers = make(map[string]int)
 
-		// Set up pipe for command output
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error creating stdout pipe: %v\n", err))
-			os.Exit(1)
-		}
+// lastShownTag tracks the tag of the last line printed in each stream, by
+// prefix, for -repeat-tag
+var lastShownTag = make(map[string]string)
 
-		// Start the command
-		if err := cmd.Start(); err != nil {
-			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error starting adb logcat: %v\n", err))
-			os.Exit(1)
-		}
+// lastTagTime tracks the last timestamp for each tag, or each delta key,
+// for the -delta-column
+var lastTagTime = make(map[string]time.Time)
 
-		lastTag := ""
-		lastTime := time.Time{}
-		lastOther := ""
+func main() {
+	// Stop adb and run the shutdown hooks on SIGINT/SIGTERM
+	ctx := handleSignals()
 
-		// Read and display logs in real-time
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			line := scanner.Text()
-			lastTag, lastTime, lastOther = printColoredLog(line, lastTag, lastTime, lastOther, opts)
+	// Select the subcommand, defaulting to watch for backward compatibility
+	cmd, _ := findCommand("watch")
+	args := os.Args[1:]
+	if pidcat, rest := isPidcatInvocation(args); pidcat {
+		opts := parsePidcatArgs(rest)
+		if err := runPidcat(ctx, &opts); err != nil {
+			exit(reportError(err))
 		}
-
-		// Check for errors while scanning
-		if err := scanner.Err(); err != nil {
-			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error reading logcat output: %v\n", err))
+		exit(exitStatus())
+	}
+	if recalled, ok := recallHistory(args); ok {
+		args = recalled
+	}
+	if len(args) > 0 {
+		if c, ok := findCommand(args[0]); ok {
+			cmd, args = c, args[1:]
 		}
+	}
 
-		// Wait for the command to finish
-		if err := cmd.Wait(); err != nil {
-			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error waiting for adb logcat: %v\n", err))
-		}
+	// Parse command-line arguments for filtering
+	opts := parseArgs(cmd.name, args)
+	recordHistory(cmd.name, args, opts)
 
-		// Exit if keep-going is not enabled
-		if !opts.KeepGoing {
-			return
+	run := func() {
+		if err := cmd.run(ctx, &opts); err != nil {
+			exit(reportError(err))
 		}
-
-		// Add a small delay before restarting to prevent rapid restart loops
-		time.Sleep(time.Second)
-		fmt.Fprintf(os.Stderr, "adb logcat exited, restarting...\n")
+		exit(exitStatus())
 	}
+	if opts.Tray != nil {
+		runWithTray(opts.Tray, run)
+	}
+	run()
 }
 
 // parseArgs parses command-line arguments for filtering options
-func parseArgs() LogcatOptions {
+func parseArgs(name string, args []string) LogcatOptions {
 	opts := LogcatOptions{}
 
-	fs := flag.NewFlagSet("logcatcolor", flag.ExitOnError)
+	fs := flag.NewFlagSet("logcatcolor "+name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: logcatcolor [command] [flags] [files...] [-- logcat args...]\n\nCommands:\n")
+		for _, c := range commands {
+			fmt.Fprintf(fs.Output(), "  %-13s %s\n", c.name, c.summary)
+		}
+		fmt.Fprintf(fs.Output(), "\nFlags:\n")
+		fs.PrintDefaults()
+	}
 	// Define flags
 	var filters []string
 	fs.Func("s", "Filter string to match in log messages (can be specified multiple times)", func(s string) error {
 		filters = append(filters, s)
 		return nil
 	})
+	var buffers, outputs, plugins []string
+	fs.Func("plugin", "Pass each line, as JSON, through this command or .wasm module, which answers with the line, changed or not, or null to drop it (can be specified multiple times)", func(s string) error {
+		plugins = append(plugins, s)
+		return nil
+	})
+	fs.Func("output", "Also send the lines shown as FORMAT (text, json, logfmt, csv or proto) to stdout in place of colors, a file, tcp://, udp://, unix:// or a Windows pipe:// DEST, as FORMAT[:DEST], or to systemd-journald as journald[:SOCKET] (can be specified multiple times)", func(s string) error {
+		outputs = append(outputs, s)
+		return nil
+	})
+	fs.Func("b", "Log buffer to show: main, system, radio, events, crash or all (can be specified multiple times)", func(s string) error {
+		buffers = append(buffers, s)
+		return nil
+	})
 	tag := fs.String("t", "", "Filter by tag")
 	level := fs.String("l", "", "Filter by log level (V/D/I/W/E/F)")
+	grep := fs.String("grep", "", "Only show lines whose message matches this regular expression")
+	filterExpr := fs.String("filter", "", "Only show lines matching this expression, e.g. 'tag == ActivityManager && level >= W || msg ~ timeout'")
 	device := fs.String("d", "", "Device serial number or -d for hardware device")
 	emulator := fs.Bool("e", false, "Use default emulator device")
+	avd := fs.String("avd", "", "Boot this Android Virtual Device (unless running), wait for it and stream from it")
+	devices := fs.String("devices", "", "Tail several devices at once: comma-separated serials, or \"all\"")
+	transport := fs.String("transport", "", "Select the device by adb transport ID (see adb devices -l)")
+	fs.BoolVar(&opts.Selection.Newest, "newest", false, "With several devices attached, use the most recently connected one")
+	fs.BoolVar(&opts.Selection.USBOnly, "usb-only", false, "Only consider physical devices connected over USB")
+	fs.BoolVar(&opts.Selection.EmulatorOnly, "emulator-only", false, "Only consider emulators")
+	adbHost := fs.String("adb-host", "", "Host of the adb server (default localhost)")
+	adbPort := fs.Int("adb-port", 0, "Port of the adb server (default $ANDROID_ADB_SERVER_PORT or 5037)")
 	maxDelta := fs.Duration("delta", 10*time.Second, "Maximum duration for showing time differences between log entries")
+	traceSync := fs.Bool("trace-sync", false, "Write a clock sync marker to the device's trace buffer and to logcat every 10s, to align atrace or perfetto traces with the log")
+	trace := fs.String("trace", "", "Annotate replayed lines with the sections of this systrace or ftrace text trace, captured with -trace-sync")
+	traceSections := fs.String("trace-sections", "", "Only annotate the -trace sections whose name matches this regular expression, instead of those lasting 16ms or more")
+	section := fs.String("section", "", "Only show the dumpsys sections whose heading matches this regular expression, ignoring case, with the dumpsys command")
+	htmlPath := fs.String("html", "", "Export captures to this standalone HTML page, with the export command")
+	raw := fs.Bool("raw", false, "Print the lines that pass the filters as received, without colors, aliases, rewrites or time differences, for tools that expect logcat's format")
+	colorMode := fs.String("color", "auto", "Color the output: auto (only on a terminal, or with FORCE_COLOR set), always (e.g. for less -R or CI log viewers) or never")
+	deltaMode := fs.String("delta-mode", "tag", "Show time differences between consecutive lines of the same tag, the same pid, any line, or off")
+	deltaFormat := fs.String("delta-format", "since-first", "Measure time differences since the first line of a run (since-first) or since the previous line (since-last)")
+	showDate := fs.Bool("show-date", false, "Show timestamps with their year, inferred from the host's date, as in 2026-04-19 19:34:18.813")
+	suggestMutes := fs.Duration("suggest-mutes", 0, "Every this often, offer to mute the tag that logged the most lines below I, saving accepted mutes in the active profile, e.g. 1m")
+	tray := fs.Bool("tray", false, "Show the connection status and the number of unread errors in a system tray or menu bar icon")
+	bell := fs.String("bell", "", "Ring the terminal bell, at most once a second, when a line at or above this level (V/D/I/W/E/F) is shown")
+	lineNumbers := fs.Bool("line-numbers", false, "Number the lines read in a column before each line, and in JSON output")
+	repeatTag := fs.String("repeat-tag", "show", "Show a tag repeated from the previous line (show), leave its column blank (blank) or dim it (dim), like pidcat")
+	deltaColumn := fs.Bool("delta-column", false, "Always show the time since the previous line (of the same tag or pid, with -delta-mode) in its own column, keeping timestamps")
+	dump := fs.Bool("dump", false, "Colorize the current log buffers and exit (logcat -d), like the dump command")
 	keepGoing := fs.Bool("k", false, "Restart the command when it exits")
+	wait := fs.Bool("wait", false, "Wait for the device to be attached before streaming (and before each -k restart)")
+	noBanner := fs.Bool("no-banner", false, "Do not print device information before streaming")
+	clear := fs.Bool("clear", false, "Clear the selected log buffers before streaming")
+	clearEach := fs.Bool("clear-on-restart", false, "Also clear the log buffers before each -k restart")
+	bufferSize := fs.String("buffer-size", "", "Set the size of the selected log buffers before streaming (logcat -G), e.g. 16M")
+	rootCapture := fs.Bool("root", false, "When adb root or su is available, capture all buffers with larger logd buffers")
+	anrDir := fs.String("pull-anr", "", "With -root, pull /data/anr traces into this directory when an ANR is logged")
+	from := fs.String("from", "", "Only replay lines logged at or after this time (HH:MM[:SS] or MM-DD HH:MM[:SS])")
+	to := fs.String("to", "", "Only replay lines logged at or before this time")
+	skew := fs.Bool("skew", false, "Correct device clock skew: measured with adb shell date for -devices, or from captures' recorded clock offsets for replay and merge")
+	hostTime := fs.Bool("host-time", false, "Show timestamps in host time, correcting the device's clock offset and time zone (re-measured every minute)")
+	syncMarker := fs.String("sync-marker", "", "When merging, align each capture's clock so the first lines matching this regular expression coincide")
+	seek := fs.Bool("seek", false, "Page through replayed captures, seeking with +10s/-1m, e/E for errors and g TIME")
+	splitBy := fs.String("split-by", "", "Also write lines into one file per tag (tag) or per process (process) under -out-dir")
+	outDir := fs.String("out-dir", ".", "Directory for -split-by files and recorded sessions")
+	sessionName := fs.String("session", "", "Name of the directory the record command saves the session in (default session-DATE-TIME)")
+	splitOnly := fs.Bool("split-only", false, "With -split-by, only write the files and print nothing")
+	ringSize := fs.Int("ring", 5000, "Lines to keep in memory and save to -out-dir on SIGUSR2 or -ring-trigger (0 to disable)")
+	ringTrigger := fs.String("ring-trigger", "", "Save the in-memory lines whenever a line matches this regular expression")
+	incidents := fs.Bool("incidents", false, "Save the lines around each crash or ANR to an incident file in -out-dir")
+	incidentBefore := fs.String("incident-before", "30s", "Lead-up to save with each incident, as a duration or a line count (from the -ring buffer)")
+	detach := fs.Bool("detach", false, "Start the daemon in the background, logging to daemon.log in -out-dir")
+	rotateSize := fs.String("rotate-size", "64M", "Size at which the daemon starts a new capture file")
+	rotateKeep := fs.Int("rotate-keep", 10, "Old capture files the daemon keeps per device")
+	alertExec := fs.String("alert-exec", "", "Command the daemon runs on each crash, ANR or native crash, with LOGCATCOLOR_SERIAL, _KIND, _SUMMARY and _INCIDENT set")
+	incidentAfter := fs.Int("incident-after", 100, "Lines to save after each crash or ANR")
+	latencyTags := fs.String("latency-tags", "", "Print histograms of the intervals between consecutive lines of these comma-separated tags at exit")
+	report := fs.String("report", "", "At exit, summarize lines, errors, bytes and rates per tag and per process as text or json")
+	reportSort := fs.String("report-sort", "lines", "Order -report rows by lines, errors, bytes, rate or name")
+	reportFile := fs.String("report-file", "", "Write the -report to this file instead of stderr")
+	exceptions := fs.Bool("exceptions", false, "At exit, list the exceptions logged, grouped by type and top app frame, with counts")
+	exceptionsFile := fs.String("exceptions-file", "", "Save the -exceptions census to this JSON file instead of printing it (implies -exceptions)")
+	net := fs.Bool("net", false, "Show only connectivity, Wi-Fi, telephony data and DNS lines, with a timeline row for each network change")
+	jobs := fs.Bool("jobs", false, "Show only JobScheduler and WorkManager lines, with a row for each job enqueued, started, stopped, finished or failed")
+	quiet := fs.Bool("quiet", false, "Print no lines, only periodic statistics and crashes, ANRs and native crashes")
+	statsInterval := fs.Duration("stats-interval", 0, "Print line counts, levels and the busiest tags this often (10s with -quiet)")
+	benchLines := fs.Int("bench-lines", 200000, "Number of synthetic lines the bench command renders")
+	benchLength := fs.Int("bench-length", 80, "Approximate message length of the bench command's lines")
+	benchRate := fs.Int("bench-rate", 0, "Lines per second to feed the bench command (0 for as fast as possible)")
+	demoRate := fs.Int("demo-rate", 20, "Lines per second of the demo command's fake stream")
+	demoLines := fs.Int("demo-lines", 0, "Exit the demo command after this many lines (0 for no limit)")
+	writePath := fs.String("write", "", "Also save every line read to this file, compressed if it ends in .gz or .zst")
+	lenient := fs.Bool("lenient", false, "Color and filter lines in other formats (brief, tag, process, kernel) by the fields they have")
+	usec := fs.Bool("usec", false, "Request microsecond timestamps (logcat -v usec) so short deltas are not rounded")
+	showUID := fs.Bool("show-uid", false, "Show the UID (or package name) of each line, using logcat -v uid")
+	uids := fs.String("uid", "", "Only show lines from these comma-separated UIDs, app IDs or package names (implies -show-uid)")
+	appsOnly := fs.Bool("apps-only", false, "Only show lines from processes of installed third-party apps, hiding system processes (implies -show-uid)")
+	user := fs.Int("user", -1, "Only show lines from processes of this Android user, e.g. 10 for a work profile (implies -show-uid)")
+	fs.Bool("pidcat", false, "Accept pidcat's arguments and mimic its output (implied when run as pidcat)")
+	configPath := fs.String("config", defaultConfigPath(), "Path to the JSON configuration file")
+	profile := fs.String("p", "", "Activate a named profile from the config file, or a built-in preset")
+	var presets []string
+	fs.Func("preset", "Activate a built-in preset or a profile from the config file, after -p (can be specified multiple times; see the presets command)", func(s string) error {
+		presets = append(presets, s)
+		return nil
+	})
+	redactPII := fs.Bool("redact", false, "Mask emails, tokens, MAC/IMEI numbers and GPS coordinates in all output")
+	lineLevel := fs.String("bg", "", "Paint whole lines at or above this level (E or F) with a background color")
+	var prettify []string
+	fs.Func("prettify", "Reformat messages holding hex dumps (hex), SQL statements (sql) or Intent and Bundle dumps (intent, or intent-flags to name flags), or preview base64 payloads (base64); comma-separated, tried in order (can be specified multiple times)", func(s string) error {
+		names, err := parsePrettify(s)
+		prettify = append(prettify, names...)
+		return err
+	})
+	linkURL := fs.String("link", "", "URL template for file:line hyperlinks, e.g. idea://open?file={file}&line={line}")
+	sourceMap := fs.String("sourcemap", "", "Source map (e.g. index.map) for decoding React Native stack frames")
 
-	// Filter os.Args[1:] to remove "-d" if the next argument starts with "-"
-	// This prevents flag.Parse from incorrectly interpreting a subsequent flag as the value for -d.
-	originalCmdArgs := os.Args[1:]
-	filteredCmdArgs := make([]string, 0, len(originalCmdArgs))
-	for i, arg := range originalCmdArgs {
-		isDashD := arg == "-d" || arg == "--d" || strings.HasPrefix(arg, "-d=") || strings.HasPrefix(arg, "--d=")
-		if isDashD {
-			if i+1 == len(originalCmdArgs) {
-				opts.Device = "-d"
-				continue
-			} else if i+1 < len(originalCmdArgs) {
-				nextArg := originalCmdArgs[i+1]
-				if strings.HasPrefix(nextArg, "-") {
-					opts.Device = "-d"
-					continue
-				}
-			}
-		}
-		filteredCmdArgs = append(filteredCmdArgs, arg)
+	// Arguments after "--" are passed through to adb logcat
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, opts.LogcatArgs = args[:i], args[i+1:]
 	}
 
 	// Parse flags
-	fs.Parse(filteredCmdArgs)
+	cmdArgs := filterDeviceArgs(args, &opts)
+	fs.Parse(cmdArgs)
+
+	if err := setColorMode(*colorMode); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -color %q, %v\n", *colorMode, err))
+		os.Exit(1)
+	}
+
+	// Load the configuration file, which must exist if -config was given explicitly
+	configSet := false
+	fs.Visit(func(f *flag.Flag) { configSet = configSet || f.Name == "config" })
+	cfg, err := LoadConfig(*configPath, configSet)
+	if err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error loading config: %v\n", err))
+		os.Exit(1)
+	}
+
+	// Apply the profile's and presets' settings, then parse their flags
+	// followed by the command line's so that explicit flags win
+	names := presets
+	if *profile != "" {
+		names = append([]string{*profile}, presets...)
+	}
+	if len(names) > 0 {
+		var profileArgs []string
+		for _, name := range names {
+			p, ok := findProfile(cfg, name)
+			if !ok {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Unknown profile %q\n", name))
+				os.Exit(1)
+			}
+			cfg.ApplyProfile(p)
+			profileArgs = append(profileArgs, p.Args...)
+		}
+		filters, buffers, presets, prettify = nil, nil, nil, nil
+		fs.Parse(append(filterDeviceArgs(profileArgs, &opts), cmdArgs...))
+	}
 
 	// Set options from flags
+	opts.Args = fs.Args()
 	opts.Filters = filters
+	opts.Buffers = buffers
 	opts.Tag = *tag
 	opts.Level = strings.ToUpper(*level)
 	opts.MaxDelta = *maxDelta
+	opts.DeltaMode = *deltaMode
+	if !slices.Contains([]string{"tag", "pid", "line", "off"}, opts.DeltaMode) {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -delta-mode %q, must be tag, pid, line or off\n", opts.DeltaMode))
+		os.Exit(1)
+	}
+	opts.DeltaFormat = *deltaFormat
+	opts.DeltaColumn = *deltaColumn
+	opts.RepeatTag = *repeatTag
+	opts.LineNumbers = *lineNumbers
+	opts.Bell = strings.ToUpper(*bell)
+	if *tray {
+		opts.Tray = newTrayStatus()
+	}
+	if _, ok := LogLevelColors[opts.Bell]; opts.Bell != "" && !ok {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -bell %q, must be V, D, I, W, E or F\n", *bell))
+		os.Exit(1)
+	}
+	opts.ShowDate = *showDate
+	if !slices.Contains([]string{"show", "blank", "dim"}, opts.RepeatTag) {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -repeat-tag %q, must be show, blank or dim\n", opts.RepeatTag))
+		os.Exit(1)
+	}
+	if opts.DeltaFormat != "since-first" && opts.DeltaFormat != "since-last" {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -delta-format %q, must be since-first or since-last\n", opts.DeltaFormat))
+		os.Exit(1)
+	}
+	opts.Dump = *dump
 	opts.KeepGoing = *keepGoing
+	opts.Raw = *raw
+	opts.HTML = *htmlPath
+	opts.Banner = !*noBanner && !opts.Raw
+	if opts.Raw {
+		color.NoColor = true
+	}
+	opts.Wait = *wait
+	opts.Clear = *clear || *clearEach
+	opts.ClearEach = *clearEach
+	opts.BufferSize = *bufferSize
+	opts.Lenient = *lenient
+	opts.Usec = *usec
+	opts.User = *user
+	if *uids != "" {
+		opts.UIDFilters = strings.Split(*uids, ",")
+	}
+	opts.AppsOnly = *appsOnly
+	opts.UIDColumn = *showUID || len(opts.UIDFilters) > 0 || opts.User >= 0 || opts.AppsOnly
+	opts.RootCapture = *rootCapture
+	opts.ANRDir = *anrDir
+	opts.LinkURL = *linkURL
+	opts.Prettify = prettify
+	opts.LineLevel = strings.ToUpper(*lineLevel)
+	if *grep != "" {
+		re, err := regexp.Compile(*grep)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -grep pattern: %v\n", err))
+			os.Exit(1)
+		}
+		opts.Grep = re
+	}
+	opts.TraceSync = *traceSync
+	opts.Trace = *trace
+	if *traceSections != "" {
+		re, err := regexp.Compile(*traceSections)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -trace-sections pattern: %v\n", err))
+			os.Exit(1)
+		}
+		opts.TraceNames = re
+	}
+	if *section != "" {
+		re, err := regexp.Compile("(?i)" + *section)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -section pattern: %v\n", err))
+			os.Exit(1)
+		}
+		opts.Section = re
+	}
+	if *filterExpr != "" {
+		f, err := logcat.ParseFilter(*filterExpr)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -filter: %v\n", err))
+			os.Exit(1)
+		}
+		opts.Filter = f
+	}
+	if _, ok := LineBackgroundColors[opts.LineLevel]; opts.LineLevel != "" && !ok {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -bg level %q, must be E or F\n", *lineLevel))
+		os.Exit(1)
+	}
+
+	opts.ConfigPath = *configPath
+	opts.Profile = *profile
+	opts.CmdArgs = cmdArgs
+	if *suggestMutes > 0 {
+		if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+			fmt.Fprint(os.Stderr, LogLevelColors["W"]("Ignoring -suggest-mutes, which needs a terminal to answer on\n"))
+		} else {
+			// Save mutes in the top-level tags unless a configuration profile is active
+			saveIn := ""
+			if _, ok := cfg.Profiles[opts.Profile]; ok {
+				saveIn = opts.Profile
+			}
+			opts.Suggest = newMuteSuggester(*suggestMutes, opts.ConfigPath, saveIn)
+		}
+	}
+	opts.Presets = presets
+	opts.Redact = *redactPII
+	if err := applyConfig(&opts, cfg); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error in config: %v\n", err))
+		os.Exit(1)
+	}
+
+	if *sourceMap != "" {
+		sm, err := LoadSourceMap(*sourceMap)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error loading source map: %v\n", err))
+			os.Exit(1)
+		}
+		opts.SourceMap = sm
+	}
+
+	opts.Seek = *seek
+	opts.Skew = *skew
+	opts.HostTime = *hostTime
+	if *syncMarker != "" {
+		re, err := regexp.Compile(*syncMarker)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -sync-marker pattern: %v\n", err))
+			os.Exit(1)
+		}
+		opts.SyncMarker = re
+	}
+	window, err := parseTimeWindow(*from, *to)
+	if err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -from/-to: %v\n", err))
+		os.Exit(1)
+	}
+	opts.Window = window
+
+	if *writePath != "" {
+		w, err := createCapture(*writePath)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error creating capture file: %v\n", err))
+			os.Exit(1)
+		}
+		opts.Capture = w
+		onShutdown(func() {
+			if err := w.Close(); err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error closing capture file: %v\n", err))
+			}
+		})
+	}
 
-	// Handle device selection
+	for _, command := range plugins {
+		plugin, err := startPlugin(command)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error starting plugin %s: %v\n", command, err))
+			os.Exit(1)
+		}
+		opts.Plugins = append(opts.Plugins, plugin)
+		onShutdown(func() { plugin.Close() })
+	}
+
+	for _, spec := range outputs {
+		sink, toStdout, err := newOutputSink(spec)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error setting up -output %s: %v\n", spec, err))
+			os.Exit(1)
+		}
+		if opts.Sinks == nil {
+			sinks := logcat.NewDispatcher()
+			onShutdown(func() { sinks.Close() })
+			opts.Sinks = sinks
+		}
+		opts.Sinks.Add(sink)
+		opts.SinksOnly = opts.SinksOnly || toStdout
+	}
+
+	opts.OutDir = *outDir
+	if name == "record" {
+		session, err := newSession(*sessionName, *outDir)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error creating session: %v\n", err))
+			os.Exit(1)
+		}
+		opts.Session = session
+	}
+
+	if *ringSize > 0 {
+		opts.Ring = newRingBuffer(*ringSize)
+		if *ringTrigger != "" {
+			re, err := regexp.Compile(*ringTrigger)
+			if err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -ring-trigger pattern: %v\n", err))
+				os.Exit(1)
+			}
+			opts.RingTrigger = re
+		}
+	}
+
+	opts.Detach, opts.RotateKeep, opts.AlertExec = *detach, *rotateKeep, *alertExec
+	if opts.RotateSize, err = parseSize(*rotateSize); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid -rotate-size: %v\n", err))
+		os.Exit(1)
+	}
+
+	if *incidents || name == "daemon" || name == "service" {
+		recorder, err := newIncidentRecorder(*incidentBefore, *incidentAfter, *outDir)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("%v\n", err))
+			os.Exit(1)
+		}
+		opts.Incidents = recorder
+		onShutdown(recorder.Close)
+	}
+
+	if *latencyTags != "" {
+		tracker := newLatencyTracker(strings.Split(*latencyTags, ","))
+		opts.Latency = tracker
+		onShutdown(func() { tracker.Report(os.Stderr) })
+	}
+
+	if *report != "" {
+		r, err := newRateReport(*report, *reportSort, *reportFile)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("%v\n", err))
+			os.Exit(1)
+		}
+		opts.Report = r
+		onShutdown(func() {
+			if err := r.Write(); err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error writing report: %v\n", err))
+			}
+		})
+	}
+
+	binder := newBinderStats()
+	opts.Binder = binder
+	onShutdown(func() { binder.Write(os.Stderr) })
+
+	if *exceptions || *exceptionsFile != "" {
+		census := newExceptionCensus(*exceptionsFile)
+		opts.Exceptions = census
+		onShutdown(func() {
+			if err := census.Write(); err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error writing exception census: %v\n", err))
+			}
+		})
+	}
+
+	opts.BenchLines, opts.BenchLength, opts.BenchRate = *benchLines, *benchLength, *benchRate
+	opts.DemoRate, opts.DemoLines = *demoRate, *demoLines
+	opts.Quiet = *quiet
+	opts.Net = *net
+	if *jobs {
+		opts.Jobs = newJobTracker()
+	}
+	if *statsInterval == 0 && opts.Quiet {
+		*statsInterval = quietStatsInterval
+	}
+	if *statsInterval > 0 {
+		opts.Stats = newIntervalStats(opts.out(), *statsInterval)
+	}
+
+	if *splitBy != "" {
+		split, err := newSplitter(*splitBy, *outDir)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error setting up -split-by: %v\n", err))
+			os.Exit(1)
+		}
+		opts.Split, opts.SplitOnly = split, *splitOnly
+		onShutdown(func() { split.Close() })
+	}
+
+	// Handle server and device selection
+	opts.ADBHost = *adbHost
+	opts.ADBPort = *adbPort
+	opts.Transport = *transport
+	opts.AVD = *avd
+	if *devices != "" {
+		opts.Devices = strings.Split(*devices, ",")
+	}
 	switch {
 	case *emulator:
 		opts.Device = "-e"
@@ -151,18 +678,51 @@ func parseArgs() LogcatOptions {
 	return opts
 }
 
+// applyConfig replaces the configuration-derived options with those from cfg
+func applyConfig(opts *LogcatOptions, cfg *Config) error {
+	opts.Redactions = nil
+	if opts.Redact {
+		opts.Redactions = append(opts.Redactions, builtinRedactions...)
+	}
+	return cfg.Apply(opts)
+}
+
+// filterDeviceArgs removes "-d" from args if the next argument starts with "-",
+// recording the hardware device selection in opts instead.
+// This prevents flag.Parse from incorrectly interpreting a subsequent flag as the value for -d.
+func filterDeviceArgs(args []string, opts *LogcatOptions) []string {
+	filtered := make([]string, 0, len(args))
+	for i, arg := range args {
+		isDashD := arg == "-d" || arg == "--d" || strings.HasPrefix(arg, "-d=") || strings.HasPrefix(arg, "--d=")
+		if isDashD {
+			if i+1 == len(args) {
+				opts.Device = "-d"
+				continue
+			} else if i+1 < len(args) {
+				nextArg := args[i+1]
+				if strings.HasPrefix(nextArg, "-") {
+					opts.Device = "-d"
+					continue
+				}
+			}
+		}
+		filtered = append(filtered, arg)
+	}
+	return filtered
+}
+
 // buildAdbCommand constructs the adb logcat command with filters
 func buildAdbCommand(opts LogcatOptions) *exec.Cmd {
 	args := []string{"logcat", "-v", "threadtime"}
+	if opts.UIDColumn {
+		args = append(args, "-v", "uid")
+	}
+	if opts.Usec {
+		args = append(args, "-v", "usec")
+	}
 
-	// Add device selection if specified
-	switch opts.Device {
-	case "-d":
-		args = append([]string{"-d"}, args...)
-	case "-e":
-		args = append([]string{"-e"}, args...)
-	default:
-		args = append([]string{"-s", opts.Device}, args...)
+	if opts.Dump {
+		args = append(args, "-d")
 	}
 
 	// Add filters if specified
@@ -170,104 +730,344 @@ func buildAdbCommand(opts LogcatOptions) *exec.Cmd {
 		args = append(args, fmt.Sprintf("%s:%s", opts.Tag, opts.Level))
 	}
 
+	for _, buffer := range opts.Buffers {
+		args = append(args, "-b", buffer)
+	}
+
 	for _, filter := range opts.Filters {
 		args = append(args, "-s", filter)
 	}
 
-	return exec.Command("adb", args...)
+	if opts.GrepOnDevice && opts.Grep != nil {
+		args = append(args, "-e", opts.Grep.String())
+	}
+
+	args = append(args, opts.LogcatArgs...)
+
+	return adbCommand(opts, args...)
+}
+
+// clearBuffers runs adb logcat -c for the selected buffers
+func clearBuffers(opts LogcatOptions) error {
+	return runBufferCommand(opts, "-c")
+}
+
+// resizeBuffers runs adb logcat -G for the selected buffers
+func resizeBuffers(opts LogcatOptions) error {
+	return runBufferCommand(opts, "-G", opts.BufferSize)
+}
+
+// runBufferCommand runs adb logcat with the given flags for the selected
+// buffers, for flags such as -c and -G that act on the buffers and exit
+func runBufferCommand(opts LogcatOptions, flags ...string) error {
+	args := []string{"logcat"}
+	for _, buffer := range opts.Buffers {
+		args = append(args, "-b", buffer)
+	}
+	cmd := adbCommand(opts, append(args, flags...)...)
+	stderr, err := pipeStderr(cmd)
+	if err != nil {
+		return err
+	}
+	if err := startChild(cmd); err != nil {
+		return classifyADBError(err, nil)
+	}
+	stderrLines := stderr.Wait()
+	if err := waitChild(cmd); err != nil {
+		return classifyADBError(err, stderrLines)
+	}
+	return nil
+}
+
+// adbCommand returns an adb command for the selected server and device
+func adbCommand(opts LogcatOptions, args ...string) *exec.Cmd {
+	return exec.Command("adb", slices.Concat(serverArgs(opts), deviceArgs(opts), args)...)
+}
+
+// serverArgs returns the adb arguments selecting the adb server. Without
+// them adb honors ANDROID_ADB_SERVER_PORT and ADB_SERVER_SOCKET itself.
+func serverArgs(opts LogcatOptions) []string {
+	var args []string
+	if opts.ADBHost != "" {
+		args = append(args, "-H", opts.ADBHost)
+	}
+	if opts.ADBPort != 0 {
+		args = append(args, "-P", strconv.Itoa(opts.ADBPort))
+	}
+	return args
+}
+
+// deviceArgs returns the adb arguments selecting the device
+func deviceArgs(opts LogcatOptions) []string {
+	if opts.Transport != "" {
+		return []string{"-t", opts.Transport}
+	}
+	switch opts.Device {
+	case "":
+		return nil
+	case "-d":
+		return []string{"-d"}
+	case "-e":
+		return []string{"-e"}
+	default:
+		return []string{"-s", opts.Device}
+	}
 }
 
 // parseTimestamp parses the timestamp from a log line
 func parseTimestamp(line string) (time.Time, error) {
-	// Format: MM-DD HH:MM:SS.mmm
-	parts := strings.Fields(line)
-	if len(parts) < 2 {
-		return time.Time{}, fmt.Errorf("invalid timestamp format")
-	}
-	timestamp := parts[0] + " " + parts[1]
-	return time.Parse("01-02 15:04:05.000", timestamp)
+	return logcat.ParseTimestamp(line)
 }
 
 // findFieldIndices returns the indices of the first non-space character for each field
 // up to the specified maximum number of fields
 func findFieldIndices(line string, maxFields int) []int {
-	indices := make([]int, 0, maxFields)
-	inField := false
-
-	for i, char := range line {
-		if char != ' ' && !inField {
-			// Found start of a new field
-			indices = append(indices, i)
-			inField = true
-			if len(indices) >= maxFields {
-				break
-			}
-		} else if char == ' ' {
-			inField = false
-		}
+	return logcat.FieldIndices(line, maxFields)
+}
+
+// matchesQuery reports whether a line, with its level already remapped,
+// passes the client-side filters
+func matchesQuery(entry logLine, opts LogcatOptions) bool {
+	if opts.Grep != nil && !opts.Grep.MatchString(entry.Message) {
+		return false
+	}
+	if opts.Filter != nil && !opts.Filter.Match(entry) {
+		return false
+	}
+	if !opts.LocalFilter {
+		return true
 	}
+	if opts.Tag != "" && opts.Tag != entry.Tag {
+		return false
+	}
+	return !levelBelow(entry.Level, opts.Level)
+}
 
-	return indices
+// logLine is a parsed threadtime log line
+type logLine = logcat.Entry
+
+// parseLogLine splits a threadtime log line into its fields
+func parseLogLine(line string) (logLine, bool) {
+	return logcat.Parse(line)
 }
 
-// printColoredLog prints a log line with color based on its log level
-func printColoredLog(line, lastTag string, lastTime time.Time, lastOther string, opts LogcatOptions) (string, time.Time, string) {
-	// New logcat line format: [MM-DD HH:MM:SS.mmm PID TID LEVEL TAG: MESSAGE]
-	// Example: "04-19 19:34:18.813  5587  5708 I artd    : GetBestInfo no usable artifacts"
-	parts := findFieldIndices(line, 6)
-	if len(parts) < 6 {
-		// Fallback to default if line format is unexpected
-		fmt.Println(line)
-		return lastTag, lastTime, lastOther
+// out returns the writer colored lines are printed to
+func (opts LogcatOptions) out() io.Writer {
+	if opts.Output != nil {
+		return opts.Output
+	}
+	return os.Stdout
+}
+
+// deltaKey returns what consecutive lines must share for the second to
+// show its time difference from the first instead of its timestamp
+func deltaKey(entry logLine, opts LogcatOptions) string {
+	switch opts.DeltaMode {
+	case "pid":
+		return entry.PID
+	case "line":
+		return "*"
+	case "off":
+		return ""
 	}
+	return entry.Tag
+}
 
-	levelIndex := parts[4]
-	level := line[levelIndex : levelIndex+1]
+// swapRenderState gives printColoredLog fresh line numbers, tag and day
+// state, as for a separate rendering, returning a function restoring the
+// stream's state
+func swapRenderState() func() {
+	numbers, tags, times, days := lineNumbers, lastShownTag, lastTagTime, lastShownDay
+	lineNumbers, lastShownTag = make(map[string]int), make(map[string]string)
+	lastTagTime, lastShownDay = make(map[string]time.Time), make(map[string]time.Time)
+	return func() {
+		lineNumbers, lastShownTag, lastTagTime, lastShownDay = numbers, tags, times, days
+	}
+}
 
-	// Get the color function for the log level, default to no color if not found
-	colorFunc, exists := LogLevelColors[level]
-	if !exists {
-		fmt.Println(line)
-		return lastTag, lastTime, lastOther
+// countLine returns the number of the next line read in the stream
+func countLine(opts LogcatOptions) int {
+	lineNumbers[opts.Prefix]++
+	return lineNumbers[opts.Prefix]
+}
+
+// linePrefix returns what precedes a printed line: the device prefix and,
+// with -line-numbers, the line's number
+func linePrefix(number int, opts LogcatOptions) string {
+	if !opts.LineNumbers {
+		return opts.Prefix
 	}
+	return opts.Prefix + LineNumberColor("%7d", number) + " "
+}
+
+// isRepeatedTag reports whether tag is that of the previous line printed in
+// the stream, and records it for the next line
+func isRepeatedTag(tag string, opts LogcatOptions) bool {
+	previous, seen := lastShownTag[opts.Prefix]
+	lastShownTag[opts.Prefix] = tag
+	return seen && previous == tag
+}
 
-	tagIndex := parts[5]
-	colonIndex := strings.IndexRune(line[tagIndex:], ':')
-	if colonIndex == -1 {
-		fmt.Println(line)
+// printColoredLog prints a log line with color based on its log level.
+// lastTag and the returned key identify the previous line as deltaKey does.
+func printColoredLog(line, lastTag string, lastTime time.Time, lastOther string, opts LogcatOptions) (string, time.Time, string) {
+	original, number := cmp.Or(opts.RawLine, line), countLine(opts)
+	entry, ok := parseLogLine(line)
+	if !ok {
+		// Lines without fields are not records, so an -output on stdout
+		// leaves them out; otherwise color what can be recognized in
+		// lenient mode, or print them as they are
+		if opts.SinksOnly {
+			return lastTag, lastTime, lastOther
+		}
+		if opts.Raw {
+			fmt.Fprintln(opts.out(), original)
+			return lastTag, lastTime, lastOther
+		}
+		if opts.Lenient {
+			if level, tag, message, ok := parsePartialLine(line); ok {
+				printPartialLog(line, level, tag, message, number, opts)
+				return "", time.Time{}, ""
+			}
+		}
+		fmt.Fprintln(opts.out(), linePrefix(number, opts)+line)
+		return lastTag, lastTime, lastOther
+	}
+	if !matchesUID(entry.UID, opts) {
 		return lastTag, lastTime, lastOther
 	}
-	colonIndex += tagIndex
 
-	tag := strings.TrimSpace(line[tagIndex:colonIndex])
-	tagSpace := line[tagIndex+len(tag) : colonIndex]
+	// Show the package name in place of an application's UID
+	if entry.UID != "" {
+		if label := uidLabel(entry.UID, opts.UIDNames); label != entry.UID {
+			line = line[:entry.UIDIndex] + label + line[entry.UIDIndex+len(entry.UID):]
+			entry, _ = parseLogLine(line)
+		}
+	}
+	level, tag, tagSpace, message := entry.Level, entry.Tag, entry.TagSpace, entry.Message
+	levelIndex := entry.LevelIndex
 
-	// Parse current timestamp
-	currentTime, err := parseTimestamp(line)
-	if err != nil {
-		fmt.Println(line)
+	// Apply severity remapping and highlight rules before choosing the color
+	level = remapSeverity(level, tag, message, opts.Severities)
+	entry.Level = level
+	if !matchesQuery(entry, opts) {
+		return lastTag, lastTime, lastOther
+	}
+	if entry, ok = applyPlugins(entry, opts); !ok {
+		return lastTag, lastTime, lastOther
+	}
+	level, tag, tagSpace, message = entry.Level, entry.Tag, entry.TagSpace, entry.Message
+	tagColor := TagColor
+	if override, ok := opts.Tags[tag]; ok {
+		if override.Hide || levelBelow(level, override.MinLevel) {
+			return lastTag, lastTime, lastOther
+		}
+		if override.Color != nil {
+			tagColor = override.Color
+		}
+	}
+	var style func(format string, a ...any) string
+	entry.Level, entry.Tag, entry.Message = level, tag, message
+	rule, message := matchRule(entry, opts.Rules)
+	if rule != nil {
+		switch rule.Action {
+		case ActionHide:
+			return lastTag, lastTime, lastOther
+		case ActionRaise:
+			if rule.RaiseTo != "" {
+				level = rule.RaiseTo
+			} else {
+				level = raiseLevel(level)
+			}
+		case ActionNotify:
+			notify(tag, message)
+		}
+		style = rule.Style
+	}
+	entry.Level, entry.Message = level, message
+	if opts.LineNumbers {
+		entry.Number = number
+	}
+	if !dispatchEntry(entry, opts) {
+		return lastTag, lastTime, lastOther
+	}
+	ringBell(level, opts)
+	if opts.Raw {
+		fmt.Fprintln(opts.out(), original)
 		return lastTag, lastTime, lastOther
 	}
+	newDay := printDayChange(entry, opts)
+	colorFunc := LogLevelColors[level]
+	if style != nil {
+		colorFunc = style
+	}
 
-	other := line[:parts[1]] + line[parts[2]:parts[4]]
+	// Highlight binder failures unless a rule styled them, with the parcel
+	// size when given
+	if kind, size := binderKind(message); kind != "" && style == nil {
+		colorFunc = BinderColor
+		if size > 0 {
+			message += " [" + describeParcel(size) + "]"
+		}
+	}
 
 	// Calculate delta time
+	currentTime, other := entry.Time, entry.Other
 	delta := currentTime.Sub(lastTime)
+	key := deltaKey(entry, opts)
 
-	// Prepare metadata part
-	var metadata string
-	if lastTag == tag && delta.Seconds() < opts.MaxDelta.Seconds() {
-		metadata = fmt.Sprintf("%-*v", levelIndex, "+"+delta.String())
+	// Prepare metadata part, with -show-date starting timestamps with the year
+	var metadata, year string
+	if opts.ShowDate {
+		year = fmt.Sprintf("%d-", logcat.InferYear(currentTime, time.Now()))
+	}
+	if opts.DeltaColumn {
+		metadata = deltaColumn(entry, opts) + " " + year + line[:levelIndex]
+	} else if key != "" && lastTag == key && !newDay && delta.Seconds() < opts.MaxDelta.Seconds() {
+		metadata = fmt.Sprintf("%-*v", len(year)+levelIndex, "+"+delta.String())
+		if opts.DeltaFormat == "since-last" {
+			lastTime = currentTime
+		}
 	} else {
 		// Use original metadata for first occurrence
-		metadata = line[:levelIndex]
+		metadata = year + line[:levelIndex]
 		lastTime = currentTime
 		lastOther = other
 	}
 
-	message := line[colonIndex+2:]
+	if tag == reactNativeTag {
+		message, colorFunc = prettifyReactNative(message, level, colorFunc, opts.SourceMap)
+	}
+	message, colorFunc = prettifyMessage(message, colorFunc, opts)
+	message = linkifySourceRefs(message, opts.LinkURL)
+
+	// Show the tag's alias, keeping the original column width where possible.
+	// logcat pads tags by bytes, so wide and multi-byte tags are realigned
+	// by their display width.
+	displayTag := tagAlias(tag, opts)
+	tagSpace = strings.Repeat(" ", max(len(tag)+len(tagSpace)-displayWidth(displayTag), 0))
+	if isRepeatedTag(tag, opts) {
+		switch opts.RepeatTag {
+		case "blank":
+			tagSpace = strings.Repeat(" ", displayWidth(displayTag)) + tagSpace
+			displayTag = ""
+		case "dim":
+			tagColor = RepeatedTagColor
+		}
+	}
+
+	// Paint the entire line, metadata included, for severe levels
+	if bgColor, ok := LineBackgroundColors[level]; ok && opts.LineLevel != "" && !levelBelow(level, opts.LineLevel) {
+		text := fmt.Sprintf("%s%s %s%s : %s", metadata, level, displayTag, tagSpace, message)
+		if !color.NoColor {
+			// Extend the background to the right edge of the terminal
+			text += "\x1b[K"
+		}
+		fmt.Fprintln(opts.out(), linePrefix(number, opts)+bgColor("%s", text))
+		return key, lastTime, lastOther
+	}
 
-	fmt.Printf("%s%s %s%s : %s\n", metadata, colorFunc("%s", level), TagColor("%s", tag), tagSpace, colorFunc("%s", message))
+	fmt.Fprintf(opts.out(), "%s%s%s %s%s : %s\n", linePrefix(number, opts), metadata, LogLevelColors[level]("%s", level), tagColor("%s", displayTag), tagSpace, colorizeMessage(message, colorFunc))
 
-	return tag, lastTime, lastOther
+	return key, lastTime, lastOther
 }
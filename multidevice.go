@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// maxDeviceLabel is the width of the device label column
+const maxDeviceLabel = 10
+
+// DeviceLabelColors is the palette device labels are assigned from
+var DeviceLabelColors = []func(format string, a ...any) string{
+	color.New(color.FgBlack, color.BgGreen).SprintfFunc(),
+	color.New(color.FgBlack, color.BgYellow).SprintfFunc(),
+	color.New(color.FgBlack, color.BgBlue).SprintfFunc(),
+	color.New(color.FgBlack, color.BgMagenta).SprintfFunc(),
+	color.New(color.FgBlack, color.BgCyan).SprintfFunc(),
+	color.New(color.FgBlack, color.BgWhite).SprintfFunc(),
+	color.New(color.FgBlack, color.BgHiGreen).SprintfFunc(),
+	color.New(color.FgBlack, color.BgHiYellow).SprintfFunc(),
+}
+
+// deviceStream is the display state of one device in a multi-device session
+type deviceStream struct {
+	serial    string
+	label     string
+	prefix    string
+	state     streamState
+	offset    time.Duration // How far the device clock is ahead of the host's, with -skew
+	hostClock *HostClock    // Converts the device's times, with -host-time
+
+	// UID settings resolved for the device by prepareUIDColumn
+	uidColumn  bool
+	uidFilters []string
+	user       int
+	uidNames   map[int]string
+	appsOnly   bool
+	appIDs     map[int]bool
+}
+
+// applyUIDs replaces the UID settings in opts with the device's
+func (s *deviceStream) applyUIDs(opts *LogcatOptions) {
+	opts.UIDColumn, opts.UIDFilters, opts.User, opts.UIDNames = s.uidColumn, s.uidFilters, s.user, s.uidNames
+	opts.AppsOnly, opts.AppIDs = s.appsOnly, s.appIDs
+}
+
+// deviceLine is a line read from one of several devices
+type deviceLine struct {
+	index int
+	line  string
+}
+
+// deviceLabelColor returns a stable color for a device serial
+func deviceLabelColor(serial string) func(format string, a ...any) string {
+	h := fnv.New32a()
+	h.Write([]byte(serial))
+	return DeviceLabelColors[h.Sum32()%uint32(len(DeviceLabelColors))]
+}
+
+// multiDeviceStreams resolves the -devices list into streams with unique labels
+func multiDeviceStreams(opts LogcatOptions) ([]*deviceStream, error) {
+	attached, err := listDevices(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var streams []*deviceStream
+	all := len(opts.Devices) == 1 && opts.Devices[0] == "all"
+	for _, d := range attached {
+		if d.State != "device" || (!all && !slices.Contains(opts.Devices, d.Serial)) {
+			continue
+		}
+		label := d.Model
+		if label == "" {
+			label = d.Serial
+		}
+		streams = append(streams, &deviceStream{serial: d.Serial, label: label})
+	}
+	if len(streams) == 0 {
+		return nil, &ADBError{Message: "none of the requested devices is connected", Hint: "Check the serials with `adb devices -l`.", ExitCode: ExitNoDevices}
+	}
+
+	// Shorten labels, falling back to the serial's tail where models repeat
+	counts := make(map[string]int)
+	for _, s := range streams {
+		counts[s.label]++
+	}
+	for i, s := range streams {
+		if counts[s.label] > 1 {
+			s.label = s.serial[max(len(s.serial)-4, 0):] + ":" + s.label
+		}
+		s.label = truncateWidth(s.label, maxDeviceLabel)
+		s.prefix = deviceLabelColor(s.serial)(" %d %s ", i+1, padRight(s.label, maxDeviceLabel)) + " "
+	}
+	return streams, nil
+}
+
+// runMultiWatch tails several devices at once, prefixing each line with a
+// colored device label. Lines from every device go through the same
+// recorders and statistics as a single device's. On a terminal, typing a
+// device number or label and Enter shows only that device; an empty line
+// shows all of them again.
+func runMultiWatch(ctx context.Context, opts *LogcatOptions) error {
+	// Options tied to a single device's adb session cannot apply to several
+	var single []string
+	if opts.Wait {
+		single = append(single, "-wait")
+	}
+	if opts.AVD != "" {
+		single = append(single, "-avd")
+	}
+	if opts.RootCapture {
+		single = append(single, "-root")
+	}
+	if opts.Split != nil && opts.Split.by == "process" {
+		single = append(single, "-split-by process")
+	}
+	if len(single) > 0 {
+		return fmt.Errorf("%s cannot be used with -devices", strings.Join(single, ", "))
+	}
+
+	streams, err := multiDeviceStreams(*opts)
+	if err != nil {
+		return err
+	}
+	for _, s := range streams {
+		if err := prepareDeviceStream(ctx, s, *opts); err != nil {
+			return err
+		}
+	}
+	if opts.Tray != nil {
+		opts.Tray.SetDevice(fmt.Sprintf("%d devices", len(streams)))
+	}
+
+	lines := make(chan deviceLine, 256)
+	var wg sync.WaitGroup
+	for i, s := range streams {
+		wg.Add(1)
+		deviceOpts := *opts
+		s.applyUIDs(&deviceOpts)
+		go func() {
+			defer wg.Done()
+			streamDevice(ctx, deviceOpts, i, s.serial, lines)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	// Follow device selection typed on the terminal
+	var solo atomic.Int32
+	solo.Store(-1)
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintln(os.Stderr, "Type a device number or label and Enter to show only that device; Enter alone shows all.")
+		go func() {
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				solo.Store(int32(findStream(streams, strings.TrimSpace(scanner.Text()))))
+			}
+		}()
+	}
+
+	var dumpRequests <-chan os.Signal
+	if opts.Ring != nil {
+		dumpRequests = opts.Ring.DumpRequests()
+	}
+	configChanged := watchConfig(ctx, opts.ConfigPath)
+	for l := range lines {
+		select {
+		case <-configChanged:
+			if reloaded, err := reloadConfig(*opts); err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error reloading config: %v\n", err))
+			} else {
+				*opts = reloaded
+				fmt.Fprintf(os.Stderr, "Configuration reloaded from %s\n", opts.ConfigPath)
+			}
+		default:
+		}
+
+		if opts.Ring != nil {
+			opts.Ring.DumpOnRequest(dumpRequests, opts.OutDir, *opts)
+		}
+		s := streams[l.index]
+		deviceOpts := *opts
+		deviceOpts.Device, deviceOpts.Prefix, deviceOpts.HostClock = s.serial, s.prefix, s.hostClock
+		s.applyUIDs(&deviceOpts)
+		if i := solo.Load(); i >= 0 && int(i) != l.index {
+			// Devices not shown are still recorded and counted
+			deviceOpts.Output = io.Discard
+		}
+		if err := handleLine(shiftTimestamp(l.line, -s.offset), deviceOpts, &s.state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareDeviceStream describes a device of a multi-device session and does
+// what runWatch does before streaming from a single device: resolving UIDs,
+// measuring the clock, resizing the buffers and starting trace sync markers
+func prepareDeviceStream(ctx context.Context, s *deviceStream, opts LogcatOptions) error {
+	// Resolve package names, -user packages and third-party apps on each
+	// device
+	opts.Device, opts.Transport = s.serial, ""
+	if opts.UIDColumn {
+		prepareUIDColumn(&opts)
+	}
+	s.uidColumn, s.uidFilters, s.user, s.uidNames = opts.UIDColumn, opts.UIDFilters, opts.User, opts.UIDNames
+	s.appsOnly, s.appIDs = opts.AppsOnly, opts.AppIDs
+
+	clock := ""
+	if opts.Skew {
+		if offset, err := measureClockOffset(opts); err != nil {
+			clock = fmt.Sprintf(" (clock not measured: %v)", err)
+		} else {
+			s.offset = offset
+			clock = fmt.Sprintf(" (clock %s, corrected)", formatOffset(offset))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s%s%s\n", s.prefix, s.serial, clock)
+	if opts.Banner {
+		if info, err := queryDeviceInfo(opts); err == nil {
+			// An -output on stdout must get only its records
+			w := io.Writer(os.Stdout)
+			if opts.SinksOnly {
+				w = os.Stderr
+			}
+			printBanner(w, info)
+		}
+	}
+
+	if opts.HostTime {
+		if clock, err := startHostClock(opts); err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["W"]("%sShowing device times; measuring the device clock failed: %v\n", s.prefix, err))
+		} else {
+			s.hostClock = clock
+		}
+	}
+	if opts.BufferSize != "" {
+		if err := resizeBuffers(opts); err != nil {
+			return err
+		}
+	}
+	if opts.TraceSync {
+		startTraceSync(ctx, opts)
+	}
+	return nil
+}
+
+// findStream returns the index of the stream selected by a typed number or
+// label prefix, or -1 to show all streams
+func findStream(streams []*deviceStream, text string) int {
+	if text == "" {
+		return -1
+	}
+	if n, err := strconv.Atoi(text); err == nil && n >= 1 && n <= len(streams) {
+		return n - 1
+	}
+	for i, s := range streams {
+		if strings.HasPrefix(strings.ToLower(s.label), strings.ToLower(text)) || s.serial == text {
+			return i
+		}
+	}
+	return -1
+}
+
+// streamDevice runs adb logcat for one device, sending its lines to lines
+// and restarting it with -k, until ctx is canceled
+func streamDevice(ctx context.Context, opts LogcatOptions, index int, serial string, lines chan<- deviceLine) {
+	opts.Device, opts.Transport = serial, ""
+	clear := opts.Clear
+	for {
+		// Start each session with empty buffers when asked to
+		if clear {
+			if err := clearBuffers(opts); err != nil {
+				reportError(err)
+				return
+			}
+			clear = opts.ClearEach
+		}
+
+		stream, err := adbSource{opts}.Open(ctx)
+		if err != nil {
+			reportError(err)
+			return
+		}
+
+		scanner := bufio.NewScanner(stream)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			select {
+			case lines <- deviceLine{index: index, line: scanner.Text()}:
+			case <-ctx.Done():
+			}
+		}
+		if err := stream.Close(); err != nil {
+			reportError(err)
+		}
+
+		if !opts.KeepGoing || opts.Dump || ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+		fmt.Fprintf(os.Stderr, "adb logcat for %s exited, restarting...\n", serial)
+	}
+}
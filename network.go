@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Colors of the -net timeline rows for networks coming up, going down and
+// other changes
+var (
+	NetUpColor     = color.New(color.FgBlack, color.BgGreen).SprintfFunc()
+	NetDownColor   = color.New(color.FgHiWhite, color.BgRed).SprintfFunc()
+	NetChangeColor = color.New(color.FgBlack, color.BgYellow).SprintfFunc()
+)
+
+// networkTags matches the tags of the connectivity, Wi-Fi, telephony data
+// and DNS services
+var networkTags = regexp.MustCompile(`^(?:ConnectivityService|ConnectivityManager|NetworkMonitor.*|NetworkStack|DnsManager|DnsResolver|Wifi.*|wpa_supplicant|SST|ServiceStateTracker|DCT|DataConnection.*|DataNetwork.*|PhoneSwitcher)$`)
+
+// networkTransition is a kind of connectivity change and the messages
+// reporting it
+type networkTransition struct {
+	kind    string
+	color   func(format string, a ...any) string
+	pattern *regexp.Regexp
+}
+
+// networkTransitions are checked in order; the first match names the change
+var networkTransitions = []networkTransition{
+	{"not validated", NetDownColor, regexp.MustCompile(`(?i)validation failed|validated=false|partial connectivity|captive portal`)},
+	{"validated", NetUpColor, regexp.MustCompile(`(?i)validation (?:passed|succeeded)|now validated|validated=true`)},
+	{"default", NetChangeColor, regexp.MustCompile(`(?i)new default network|default network changed`)},
+	{"roaming", NetChangeColor, regexp.MustCompile(`(?i)\broam(?:ing|ed)?\b`)},
+	{"lost", NetDownColor, regexp.MustCompile(`(?i)\bonLost\b|\blost\b|CTRL-EVENT-DISCONNECTED|\bdisconnected\b`)},
+	{"connected", NetUpColor, regexp.MustCompile(`(?i)\bonAvailable\b|CTRL-EVENT-CONNECTED|\bconnected to\b|state: ?CONNECTED\b`)},
+	{"dns", NetChangeColor, regexp.MustCompile(`(?i)setDnsConfiguration|dns servers?\b|private dns`)},
+	{"service state", NetChangeColor, regexp.MustCompile(`(?i)(?:voice|data) ?reg ?state|Poll ServiceState done|RADIO_(?:ON|OFF|UNAVAILABLE)|airplane mode`)},
+}
+
+// networkDetails extract what a change concerns: the transport, netId and
+// SSID
+var networkDetails = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(WIFI|CELLULAR|ETHERNET|VPN|BLUETOOTH)\b`),
+	regexp.MustCompile(`(?i)\b(netId[=: ]\s*\d+)`),
+	regexp.MustCompile(`(?i)\b(SSID[=: ]\s*"?[^",\s]+"?)`),
+}
+
+// isNetworkLine reports whether a line comes from one of the networking
+// services
+func isNetworkLine(entry logLine) bool {
+	return networkTags.MatchString(entry.Tag)
+}
+
+// printNetworkEvent prints a -net timeline row above a line reporting a
+// connectivity change: its time, the kind of change and the network
+func printNetworkEvent(entry logLine, opts LogcatOptions) {
+	for _, t := range networkTransitions {
+		if !t.pattern.MatchString(entry.Message) {
+			continue
+		}
+		var details []string
+		for _, pattern := range networkDetails {
+			if m := pattern.FindStringSubmatch(entry.Message); m != nil {
+				details = append(details, m[1])
+			}
+		}
+		if len(details) == 0 {
+			details = append(details, entry.Tag)
+		}
+		fmt.Fprintf(opts.out(), "%s%s %s %s\n", opts.Prefix, NetChangeColor(" NET "), t.color(" %-13s ", t.kind),
+			StatsValueColor("%s  %s", entry.Time.Format("15:04:05.000"), strings.Join(details, " ")))
+		return
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// notifyInterval is the minimum time between desktop notifications
+const notifyInterval = 2 * time.Second
+
+var (
+	notifyMu   sync.Mutex
+	lastNotify time.Time
+)
+
+// notify shows a desktop notification without blocking, dropping it if
+// another notification was shown recently
+func notify(title, message string) {
+	notifyMu.Lock()
+	if time.Since(lastNotify) < notifyInterval {
+		notifyMu.Unlock()
+		return
+	}
+	lastNotify = time.Now()
+	notifyMu.Unlock()
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(message), strconv.Quote(title))
+		cmd = exec.Command("osascript", "-e", script)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("notify-send", title, message)
+	default:
+		return
+	}
+
+	if err := cmd.Start(); err != nil {
+		return
+	}
+	go cmd.Wait()
+}
+
+// bellInterval is the minimum time between terminal bells
+const bellInterval = time.Second
+
+var lastBell time.Time
+
+// ringBell sounds the terminal bell for a line at or above the -bell level,
+// at most once per bellInterval. It is written to stderr so that it reaches
+// the terminal when the output is piped.
+func ringBell(level string, opts LogcatOptions) {
+	if opts.Bell == "" || levelBelow(level, opts.Bell) {
+		return
+	}
+	notifyMu.Lock()
+	defer notifyMu.Unlock()
+	if time.Since(lastBell) < bellInterval {
+		return
+	}
+	lastBell = time.Now()
+	fmt.Fprint(os.Stderr, "\a")
+}
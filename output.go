@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/erdichen/logcatcolor/logcat"
+)
+
+// outputFormats maps the -output format names to their formatters
+var outputFormats = map[string]logcat.Formatter{
+	"text":   logcat.Text,
+	"json":   logcat.JSON,
+	"logfmt": logcat.Logfmt,
+	"csv":    logcat.CSV,
+	"proto":  logcat.Proto,
+}
+
+// newOutputSink creates the sink for an -output FORMAT[:DEST] flag. DEST is
+// a file, tcp://HOST:PORT, udp://HOST:PORT, unix://PATH, a Windows named
+// pipe as \\.\pipe\NAME or pipe://NAME, or stdout when omitted, in which
+// case it reports true. journald[:SOCKET] sends lines to systemd-journald
+// instead.
+func newOutputSink(spec string) (logcat.Sink, bool, error) {
+	name, dest, _ := strings.Cut(spec, ":")
+	if name == "journald" {
+		sink, err := logcat.NewJournaldSink(dest)
+		if err != nil {
+			return nil, false, err
+		}
+		return sink, false, nil
+	}
+	format, ok := outputFormats[name]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown format %q, must be journald or one of %s", name, strings.Join(slices.Sorted(maps.Keys(outputFormats)), ", "))
+	}
+	if dest == "" || dest == "-" {
+		return &logcat.WriterSink{W: os.Stdout, Format: format}, true, nil
+	}
+	if pipe, ok := strings.CutPrefix(dest, "pipe://"); ok {
+		dest = `\\.\pipe\` + pipe
+	}
+	if strings.HasPrefix(dest, `\\.\pipe\`) {
+		sink, err := logcat.NewPipeSink(dest, format)
+		return sink, false, err
+	}
+	for _, network := range []string{"tcp", "udp", "unix"} {
+		if addr, ok := strings.CutPrefix(dest, network+"://"); ok {
+			sink, err := logcat.NewNetworkSink(network, addr, format)
+			return sink, false, err
+		}
+	}
+	sink, err := logcat.NewFileSink(dest, format)
+	return sink, false, err
+}
+
+// deviceSerial returns the serial of the device being streamed, if known
+func deviceSerial(opts LogcatOptions) string {
+	if opts.Device != "" && opts.Device != "-d" && opts.Device != "-e" {
+		return opts.Device
+	}
+	if opts.DeviceInfo != nil {
+		return opts.DeviceInfo.Serial
+	}
+	return ""
+}
+
+// dispatchEntry sends a line that passed the filters to the -output sinks,
+// reporting whether the colored line should still be printed
+func dispatchEntry(entry logLine, opts LogcatOptions) bool {
+	if opts.Sinks == nil {
+		return true
+	}
+	if entry.Device == "" {
+		entry.Device = deviceSerial(opts)
+	}
+	if err := opts.Sinks.Write(entry); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["W"]("Stopped writing output: %v\n", err))
+	}
+	return !opts.SinksOnly
+}
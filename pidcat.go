@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// PidcatOptions holds the pidcat-compatible command-line options
+type PidcatOptions struct {
+	Packages          []string // Packages whose processes are shown, empty for all
+	TagWidth          int      // Width of the right-aligned tag column
+	MinLevel          string   // Minimum level to show
+	Current           bool     // Filter on the app currently in the foreground
+	Tags              []string // Only show these tags, empty for all
+	IgnoreTags        []string // Never show these tags
+	AllPackages       bool     // Show lines from all processes
+	AlwaysDisplayTags bool     // Repeat the tag on consecutive lines from the same tag
+	Clear             bool     // Clear the log buffers before starting
+}
+
+// PidcatLevelColors maps log levels to the boxed level style used by pidcat
+var PidcatLevelColors = map[string]func(format string, a ...any) string{
+	"V": color.New(color.FgBlack, color.BgWhite).SprintfFunc(),
+	"D": color.New(color.FgBlack, color.BgBlue).SprintfFunc(),
+	"I": color.New(color.FgBlack, color.BgGreen).SprintfFunc(),
+	"W": color.New(color.FgBlack, color.BgYellow).SprintfFunc(),
+	"E": color.New(color.FgBlack, color.BgRed).SprintfFunc(),
+	"F": color.New(color.FgBlack, color.BgRed).SprintfFunc(),
+}
+
+// pidcatTagColors is the palette tags are assigned from
+var pidcatTagColors = []func(format string, a ...any) string{
+	color.New(color.FgRed).SprintfFunc(),
+	color.New(color.FgGreen).SprintfFunc(),
+	color.New(color.FgYellow).SprintfFunc(),
+	color.New(color.FgBlue).SprintfFunc(),
+	color.New(color.FgMagenta).SprintfFunc(),
+	color.New(color.FgCyan).SprintfFunc(),
+}
+
+// ActivityManager messages announcing process starts and deaths
+var (
+	pidStartPattern   = regexp.MustCompile(`^Start proc (\d+):([a-zA-Z0-9._:]+)/[a-z0-9]+ for (.*)$`)
+	pidStartPatternV1 = regexp.MustCompile(`^Start proc ([a-zA-Z0-9._:]+) for ([a-z]+ [^:]+): pid=(\d+) uid=(\d+) gids=(.*)$`)
+	pidDeathPattern   = regexp.MustCompile(`^Process ([a-zA-Z0-9._:]+) \(pid (\d+)\) has died`)
+	pidKillPattern    = regexp.MustCompile(`^Killing (\d+):([a-zA-Z0-9._:]+)/[^:]+: (.*)$`)
+)
+
+// isPidcatInvocation reports whether the arguments or program name request
+// pidcat compatibility mode, returning the arguments without --pidcat
+func isPidcatInvocation(args []string) (bool, []string) {
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	rest := slices.DeleteFunc(slices.Clone(args), func(a string) bool { return a == "--pidcat" || a == "-pidcat" })
+	return name == "pidcat" || len(rest) != len(args), rest
+}
+
+// parsePidcatArgs parses pidcat's command-line arguments
+func parsePidcatArgs(args []string) LogcatOptions {
+	opts := LogcatOptions{Pidcat: &PidcatOptions{}}
+	p := opts.Pidcat
+
+	fs := flag.NewFlagSet("pidcat", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: pidcat [flags] [package ...]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.IntVar(&p.TagWidth, "w", 23, "Width of log tag")
+	fs.IntVar(&p.TagWidth, "tag-width", 23, "Width of log tag")
+	fs.StringVar(&p.MinLevel, "l", "V", "Minimum level to be displayed")
+	fs.StringVar(&p.MinLevel, "min-level", "V", "Minimum level to be displayed")
+	fs.BoolVar(&p.Current, "current", false, "Filter logcat by current running app")
+	serial := fs.String("s", "", "Use device with given serial")
+	fs.StringVar(serial, "serial", "", "Use device with given serial")
+	useDevice := fs.Bool("d", false, "Use first device for log input (adb -d option)")
+	fs.BoolVar(useDevice, "device", false, "Use first device for log input (adb -d option)")
+	useEmulator := fs.Bool("e", false, "Use first emulator for log input (adb -e option)")
+	fs.BoolVar(useEmulator, "emulator", false, "Use first emulator for log input (adb -e option)")
+	fs.BoolVar(&p.Clear, "c", false, "Clear the entire log before running")
+	fs.BoolVar(&p.Clear, "clear", false, "Clear the entire log before running")
+	appendTag := func(s string) error { p.Tags = append(p.Tags, s); return nil }
+	fs.Func("t", "Filter output by specified tag(s)", appendTag)
+	fs.Func("tag", "Filter output by specified tag(s)", appendTag)
+	appendIgnore := func(s string) error { p.IgnoreTags = append(p.IgnoreTags, s); return nil }
+	fs.Func("i", "Filter output by ignoring specified tag(s)", appendIgnore)
+	fs.Func("ignore-tag", "Filter output by ignoring specified tag(s)", appendIgnore)
+	fs.BoolVar(&p.AllPackages, "a", false, "Print all log messages")
+	fs.BoolVar(&p.AllPackages, "all", false, "Print all log messages")
+	fs.BoolVar(&p.AlwaysDisplayTags, "always-display-tags", false, "Always display the tag name")
+	fs.Parse(args)
+
+	p.Packages = fs.Args()
+	p.MinLevel = strings.ToUpper(p.MinLevel[:min(len(p.MinLevel), 1)])
+	switch {
+	case *serial != "":
+		opts.Device = *serial
+	case *useDevice:
+		opts.Device = "-d"
+	case *useEmulator:
+		opts.Device = "-e"
+	}
+	return opts
+}
+
+// runPidcat streams logcat in pidcat's layout, following the processes of
+// the selected packages as they start and die
+func runPidcat(ctx context.Context, opts *LogcatOptions) error {
+	p := opts.Pidcat
+	adb := func(args ...string) *exec.Cmd {
+		return adbCommand(*opts, args...)
+	}
+
+	if p.Current {
+		pkg, err := currentPackage(adb)
+		if err != nil {
+			return err
+		}
+		p.Packages = append(p.Packages, pkg)
+	}
+	if p.Clear {
+		if err := adb("logcat", "-c").Run(); err != nil {
+			return fmt.Errorf("clearing logcat: %w", err)
+		}
+	}
+
+	// Seed the PID set with the packages' running processes
+	pids := make(map[string]string)
+	if err := runningPids(adb, p.Packages, pids); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["W"]("Warning: listing processes: %v\n", err))
+	}
+
+	cmd := adb("logcat", "-v", "threadtime")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("creating stdout pipe: %w", err)
+	}
+	stderr, err := pipeStderr(cmd)
+	if err != nil {
+		return fmt.Errorf("creating stderr pipe: %w", err)
+	}
+	if err := startChild(cmd); err != nil {
+		return classifyADBError(err, nil)
+	}
+	stop := context.AfterFunc(ctx, func() { terminateProcess(cmd) })
+	defer stop()
+
+	showAll := p.AllPackages || len(p.Packages) == 0
+	lastTag := ""
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry, ok := parseLogLine(prepareLine(scanner.Text(), *opts))
+		if !ok {
+			continue
+		}
+
+		// Track process starts and deaths announced by ActivityManager
+		if entry.Tag == "ActivityManager" {
+			if pkg, pid, target, ok := parseProcessStart(entry.Message); ok && matchesPackage(pkg, p.Packages) {
+				pids[pid] = pkg
+				printPidcatEvent(p, fmt.Sprintf("Process %s created for %s", pkg, target), "PID: "+pid)
+				lastTag = ""
+			} else if pkg, pid, ok := parseProcessDeath(entry.Message); ok && pids[pid] == pkg {
+				delete(pids, pid)
+				printPidcatEvent(p, fmt.Sprintf("Process %s (PID: %s) ended", pkg, pid), "")
+				lastTag = ""
+			}
+		}
+
+		if _, ok := pids[entry.PID]; !ok && !showAll {
+			continue
+		}
+		if levelBelow(entry.Level, p.MinLevel) {
+			continue
+		}
+		if len(p.Tags) > 0 && !slices.Contains(p.Tags, entry.Tag) {
+			continue
+		}
+		if slices.Contains(p.IgnoreTags, entry.Tag) {
+			continue
+		}
+
+		tagColumn := strings.Repeat(" ", p.TagWidth)
+		if entry.Tag != lastTag || p.AlwaysDisplayTags {
+			tag := padLeft(truncateWidth(entry.Tag, p.TagWidth), p.TagWidth)
+			tagColumn = pidcatTagColor(entry.Tag)("%s", tag)
+			lastTag = entry.Tag
+		}
+		fmt.Printf("%s %s %s\n", tagColumn, PidcatLevelColors[entry.Level](" %s ", entry.Level), entry.Message)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error reading logcat output: %v\n", err))
+	}
+	stderrLines := stderr.Wait()
+	if err := waitChild(cmd); err != nil && ctx.Err() == nil {
+		return classifyADBError(err, stderrLines)
+	}
+	return nil
+}
+
+// printPidcatEvent prints a process lifecycle line spanning the tag column
+func printPidcatEvent(p *PidcatOptions, message, detail string) {
+	fmt.Println()
+	fmt.Printf("%*s %s %s\n", p.TagWidth, "", color.New(color.FgWhite, color.Bold).Sprint(message), detail)
+	fmt.Println()
+}
+
+// pidcatTagColor returns a stable color for tag
+func pidcatTagColor(tag string) func(format string, a ...any) string {
+	h := fnv.New32a()
+	h.Write([]byte(tag))
+	return pidcatTagColors[h.Sum32()%uint32(len(pidcatTagColors))]
+}
+
+// matchesPackage reports whether a process name belongs to one of packages,
+// including their ":subprocess" processes
+func matchesPackage(process string, packages []string) bool {
+	if len(packages) == 0 {
+		return true
+	}
+	name, _, _ := strings.Cut(process, ":")
+	return slices.Contains(packages, name) || slices.Contains(packages, process)
+}
+
+// parseProcessStart extracts the process name, PID and start reason from an
+// ActivityManager "Start proc" message
+func parseProcessStart(message string) (process, pid, target string, ok bool) {
+	if m := pidStartPattern.FindStringSubmatch(message); m != nil {
+		return m[2], m[1], m[3], true
+	}
+	if m := pidStartPatternV1.FindStringSubmatch(message); m != nil {
+		return m[1], m[3], m[2], true
+	}
+	return "", "", "", false
+}
+
+// parseProcessDeath extracts the process name and PID from an ActivityManager
+// death or kill message
+func parseProcessDeath(message string) (process, pid string, ok bool) {
+	if m := pidDeathPattern.FindStringSubmatch(message); m != nil {
+		return m[1], m[2], true
+	}
+	if m := pidKillPattern.FindStringSubmatch(message); m != nil {
+		return m[2], m[1], true
+	}
+	return "", "", false
+}
+
+// runningPids adds the PIDs of running processes belonging to packages to
+// pids, or of all processes if packages is empty
+func runningPids(adb func(args ...string) *exec.Cmd, packages []string, pids map[string]string) error {
+	out, err := adb("shell", "ps", "-A").Output()
+	if err != nil || strings.Count(string(out), "\n") <= 1 {
+		// Older devices list all processes without -A
+		if out, err = adb("shell", "ps").Output(); err != nil {
+			return err
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(out), "\r", ""), "\n")
+	if len(lines) == 0 {
+		return nil
+	}
+	header := strings.Fields(lines[0])
+	pidCol := slices.Index(header, "PID")
+	if pidCol < 0 {
+		return fmt.Errorf("unexpected ps output")
+	}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) <= pidCol {
+			continue
+		}
+		name := fields[len(fields)-1]
+		if matchesPackage(name, packages) {
+			pids[fields[pidCol]] = name
+		}
+	}
+	return nil
+}
+
+// resumedActivityPattern matches the foreground activity in dumpsys output
+var resumedActivityPattern = regexp.MustCompile(`(?:mResumedActivity|ResumedActivity): ActivityRecord\{\S+ \S+ ([a-zA-Z0-9._]+)/`)
+
+// currentPackage returns the package of the activity in the foreground
+func currentPackage(adb func(args ...string) *exec.Cmd) (string, error) {
+	out, err := adb("shell", "dumpsys", "activity", "activities").Output()
+	if err != nil {
+		return "", fmt.Errorf("querying current activity: %w", err)
+	}
+	m := resumedActivityPattern.FindSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("no activity is in the foreground")
+	}
+	return string(m[1]), nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/erdichen/logcatcolor/logcat"
+)
+
+// startPlugin loads a -plugin: a WebAssembly module if it ends in .wasm,
+// otherwise a program followed by its arguments. Programs run in their own
+// process group so that they keep answering while the pipeline drains
+// after Ctrl-C.
+func startPlugin(command string) (logcat.Processor, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	if strings.HasSuffix(command, ".wasm") {
+		p, err := logcat.LoadWASMPlugin(context.Background(), command, wasmCacheDir())
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stderr = os.Stderr
+	setProcessGroup(cmd)
+	p, err := logcat.StartPlugin(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// wasmCacheDir returns where compiled WebAssembly plugins are kept, or ""
+// if there is no cache directory
+func wasmCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "logcatcolor", "wasm")
+}
+
+// applyPlugins passes an entry through the -plugin processes in turn,
+// reporting false if one of them drops it. A plugin that fails is reported
+// once and skipped from then on.
+func applyPlugins(entry logLine, opts LogcatOptions) (logLine, bool) {
+	for _, p := range opts.Plugins {
+		if p.Err() != nil {
+			continue
+		}
+		result, keep, err := p.Process(entry)
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error in %v; no longer using it\n", err))
+			continue
+		}
+		if !keep {
+			return entry, false
+		}
+
+		// Keep the message in the same column when the tag changes
+		if result.Tag != entry.Tag {
+			result.TagSpace = strings.Repeat(" ", max(len(entry.Tag)+len(entry.TagSpace)-len(result.Tag), 0))
+		}
+		entry = result
+	}
+	return entry, true
+}
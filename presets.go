@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// presetFiles holds the built-in profiles, one JSON file per preset
+//
+//go:embed presets/*.json
+var presetFiles embed.FS
+
+// builtinPresets returns the built-in profiles by name
+func builtinPresets() map[string]ProfileConfig {
+	presets := make(map[string]ProfileConfig)
+	entries, _ := presetFiles.ReadDir("presets")
+	for _, e := range entries {
+		data, err := presetFiles.ReadFile(path.Join("presets", e.Name()))
+		if err != nil {
+			continue
+		}
+		var p ProfileConfig
+		if err := json.Unmarshal(data, &p); err != nil {
+			panic(fmt.Sprintf("invalid built-in preset %s: %v", e.Name(), err))
+		}
+		presets[strings.TrimSuffix(e.Name(), ".json")] = p
+	}
+	return presets
+}
+
+// findProfile returns the named profile from the configuration file, or the
+// built-in preset of that name
+func findProfile(cfg *Config, name string) (ProfileConfig, bool) {
+	if p, ok := cfg.Profiles[name]; ok {
+		return p, true
+	}
+	p, ok := builtinPresets()[name]
+	return p, ok
+}
+
+// runPresets lists the built-in presets and the configuration file's
+// profiles with their descriptions, or with "show NAME" prints one as JSON
+func runPresets(ctx context.Context, opts *LogcatOptions) error {
+	cfg, err := LoadConfig(opts.ConfigPath, false)
+	if err != nil {
+		return err
+	}
+	builtins := builtinPresets()
+
+	switch {
+	case len(opts.Args) == 0 || opts.Args[0] == "list":
+		names := slices.Sorted(maps.Keys(builtins))
+		for name := range cfg.Profiles {
+			if _, ok := builtins[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		slices.Sort(names)
+		nameColor := color.New(color.Bold).SprintfFunc()
+		for _, name := range names {
+			p, _ := findProfile(cfg, name)
+			source, description := "built-in", p.Description
+			if _, ok := cfg.Profiles[name]; ok {
+				source = "config"
+				if _, ok := builtins[name]; ok {
+					description = strings.TrimSpace(description + " (overrides the built-in preset)")
+				}
+			}
+			fmt.Printf("%-24s %s %s\n", nameColor("%s", name), StatsLabelColor("%-8s", source), description)
+		}
+		return nil
+
+	case opts.Args[0] == "show" && len(opts.Args) == 2:
+		name := opts.Args[1]
+		if _, ok := cfg.Profiles[name]; !ok {
+			if data, err := presetFiles.ReadFile(path.Join("presets", name+".json")); err == nil {
+				_, err := os.Stdout.Write(data)
+				return err
+			}
+		}
+		p, ok := findProfile(cfg, name)
+		if !ok {
+			return fmt.Errorf("unknown preset %q", name)
+		}
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		// Drop the unset fields of the configuration's profile
+		var fields any
+		json.Unmarshal(data, &fields)
+		data, err = json.MarshalIndent(pruneJSON(fields), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", data)
+		return nil
+	}
+	return fmt.Errorf("usage: logcatcolor presets [list | show NAME]")
+}
+
+// pruneJSON removes the null, empty and false values from decoded JSON
+func pruneJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			field = pruneJSON(field)
+			if field == nil || field == "" || field == false {
+				delete(v, k)
+			} else if m, ok := field.(map[string]any); ok && len(m) == 0 {
+				delete(v, k)
+			} else if a, ok := field.([]any); ok && len(a) == 0 {
+				delete(v, k)
+			} else {
+				v[k] = field
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = pruneJSON(v[i])
+		}
+	}
+	return v
+}
+
+// toggledPresets are the profiles switched on by typing their names while
+// streaming
+var (
+	toggledMu      sync.Mutex
+	toggledPresets []string
+)
+
+// activeToggles returns the profiles switched on while streaming
+func activeToggles() []string {
+	toggledMu.Lock()
+	defer toggledMu.Unlock()
+	return slices.Clone(toggledPresets)
+}
+
+// readPresetToggles switches the profile typed on the terminal on or off,
+// notifying the returned channel so that the configuration is reloaded
+// with it. Only its settings change; its flags need a restart. A line
+// answering a -suggest-mutes question goes to the suggester instead.
+func readPresetToggles(opts LogcatOptions) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return changed
+	}
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if opts.Suggest != nil && opts.Suggest.Answer(scanner.Text()) {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+				continue
+			}
+			name := strings.TrimSpace(scanner.Text())
+			if name == "" {
+				continue
+			}
+			cfg, err := LoadConfig(opts.ConfigPath, false)
+			if err != nil {
+				cfg = &Config{}
+			}
+			if _, ok := findProfile(cfg, name); !ok {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Unknown profile %q\n", name))
+				continue
+			}
+
+			toggledMu.Lock()
+			state := "on"
+			if i := slices.Index(toggledPresets, name); i >= 0 {
+				toggledPresets = slices.Delete(toggledPresets, i, i+1)
+				state = "off"
+			} else {
+				toggledPresets = append(toggledPresets, name)
+			}
+			toggledMu.Unlock()
+			fmt.Fprintf(os.Stderr, "Profile %s %s\n", name, state)
+
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changed
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// prettifier reformats a message it recognizes for -prettify, returning it
+// colored. colorFunc is the color the message would otherwise have.
+type prettifier func(message string, colorFunc func(format string, a ...any) string) (string, bool)
+
+// prettifiers are the -prettify transforms by name
+var prettifiers = map[string]prettifier{
+	"hex":    prettifyHex,
+	"base64": prettifyBase64,
+	"sql":    prettifySQL,
+	"intent": func(message string, colorFunc func(format string, a ...any) string) (string, bool) {
+		return prettifyIntent(message, colorFunc, false)
+	},
+	"intent-flags": func(message string, colorFunc func(format string, a ...any) string) (string, bool) {
+		return prettifyIntent(message, colorFunc, true)
+	},
+}
+
+// parsePrettify checks a comma-separated list of -prettify names
+func parsePrettify(s string) ([]string, error) {
+	var names []string
+	for name := range strings.SplitSeq(s, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := prettifiers[name]; !ok {
+			return nil, fmt.Errorf("unknown prettifier %q, must be one of %s", name, strings.Join(slices.Sorted(maps.Keys(prettifiers)), ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// prettifyMessage applies the first of the -prettify transforms that
+// recognizes the message
+func prettifyMessage(message string, colorFunc func(format string, a ...any) string, opts LogcatOptions) (string, func(format string, a ...any) string) {
+	for _, name := range opts.Prettify {
+		if text, ok := prettifiers[name](message, colorFunc); ok {
+			return text, func(format string, a ...any) string { return fmt.Sprintf(format, a...) }
+		}
+	}
+	return message, colorFunc
+}
+
+// Colors of the hex dump columns
+var (
+	HexOffsetColor = color.New(color.Faint).SprintfFunc()
+	HexByteColor   = color.New(color.FgCyan).SprintfFunc()
+	HexZeroColor   = color.New(color.Faint).SprintfFunc()
+	HexASCIIColor  = color.New(color.FgYellow).SprintfFunc()
+)
+
+// hexBytes matches at least 16 bytes written as hex pairs separated by
+// spaces, commas or colons, optionally with 0x prefixes and brackets, and
+// Java's Arrays.toString of a byte[], such as "[72, 105, -1, ...]"
+var (
+	hexBytes     = regexp.MustCompile(`[\[{(]?(?:(?:0[xX])?[0-9a-fA-F]{2}(?:, ?|[ :]))(?:(?:0[xX])?[0-9a-fA-F]{2}(?:, ?|[ :])){14,}(?:0[xX])?[0-9a-fA-F]{2}[\]})]?`)
+	decimalBytes = regexp.MustCompile(`\[(?:-?\d{1,3}, ){15,}-?\d{1,3}\]`)
+)
+
+// hexDumpIndent indents the rows of a hex dump under the message
+const hexDumpIndent = "    "
+
+// prettifyHex reformats a run of bytes in a message into a hex dump of
+// offset, hex and ASCII columns under the rest of the message
+func prettifyHex(message string, colorFunc func(format string, a ...any) string) (string, bool) {
+	var data []byte
+	loc := decimalBytes.FindStringIndex(message)
+	if loc != nil {
+		for field := range strings.SplitSeq(strings.Trim(message[loc[0]:loc[1]], "[]"), ", ") {
+			n, err := strconv.Atoi(field)
+			if err != nil || n < -128 || n > 255 {
+				return "", false
+			}
+			data = append(data, byte(n))
+		}
+	} else if loc = hexBytes.FindStringIndex(message); loc != nil {
+		for _, field := range strings.FieldsFunc(message[loc[0]:loc[1]], func(r rune) bool { return strings.ContainsRune(" ,:[]{}()", r) }) {
+			n, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X"), 16, 8)
+			if err != nil {
+				return "", false
+			}
+			data = append(data, byte(n))
+		}
+	} else {
+		return "", false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(colorFunc("%s", strings.TrimSpace(strings.TrimSpace(message[:loc[0]])+" "+strings.TrimSpace(message[loc[1]:]))))
+	fmt.Fprintf(&sb, " %s", HexOffsetColor("(%d bytes)", len(data)))
+	for offset := 0; offset < len(data); offset += 16 {
+		row := data[offset:min(offset+16, len(data))]
+		sb.WriteString("\n" + hexDumpIndent + HexOffsetColor("%08x", offset) + " ")
+		for i := range 16 {
+			if i == 8 {
+				sb.WriteByte(' ')
+			}
+			switch {
+			case i >= len(row):
+				sb.WriteString("   ")
+			case row[i] == 0:
+				sb.WriteString(" " + HexZeroColor("00"))
+			default:
+				sb.WriteString(" " + HexByteColor("%02x", row[i]))
+			}
+		}
+		sb.WriteString("  ")
+		for _, b := range row {
+			if b >= 0x20 && b < 0x7f {
+				sb.WriteString(HexASCIIColor("%c", b))
+			} else {
+				sb.WriteString(HexOffsetColor("."))
+			}
+		}
+	}
+	return sb.String(), true
+}
+
+// Base64Color is the color of decoded base64 previews
+var Base64Color = color.New(color.FgMagenta).SprintfFunc()
+
+// base64Run matches a run of at least 24 base64 characters, standard or
+// URL-safe, with optional padding
+var base64Run = regexp.MustCompile(`[A-Za-z0-9+/_-]{24,}={0,2}`)
+
+// base64Preview is how much of a decoded payload is shown
+const base64Preview = 60
+
+// prettifyBase64 follows each base64 run in a message that decodes with a
+// preview of its payload: the text if it is printable, otherwise the first
+// bytes in hex
+func prettifyBase64(message string, colorFunc func(format string, a ...any) string) (string, bool) {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range base64Run.FindAllStringIndex(message, -1) {
+		data, ok := decodeBase64(message[loc[0]:loc[1]])
+		if !ok {
+			continue
+		}
+		sb.WriteString(colorFunc("%s", message[last:loc[1]]))
+		sb.WriteString(" " + Base64Color("[%s]", describePayload(data)))
+		last = loc[1]
+	}
+	if last == 0 {
+		return "", false
+	}
+	sb.WriteString(colorFunc("%s", message[last:]))
+	return sb.String(), true
+}
+
+// decodeBase64 decodes a run in any of the base64 alphabets, rejecting runs
+// that are more likely hex digests or identifiers
+func decodeBase64(run string) ([]byte, bool) {
+	if strings.Trim(run, "0123456789abcdefABCDEF") == "" || !strings.ContainsAny(run, "0123456789+/=_-") {
+		return nil, false
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := enc.DecodeString(run); err == nil {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// describePayload previews decoded bytes as quoted text if they are
+// printable UTF-8, or as hex
+func describePayload(data []byte) string {
+	text := string(data)
+	if utf8.ValidString(text) && !strings.ContainsFunc(text, func(r rune) bool { return !unicode.IsPrint(r) && !unicode.IsSpace(r) }) {
+		if runes := []rune(text); len(runes) > base64Preview {
+			text = string(runes[:base64Preview]) + "…"
+		}
+		return fmt.Sprintf(`base64 "%s"`, strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(text))
+	}
+	preview := fmt.Sprintf("% x", data[:min(len(data), 16)])
+	if len(data) > 16 {
+		preview += " …"
+	}
+	return fmt.Sprintf("base64 %d bytes: %s", len(data), preview)
+}
+
+// Colors of SQL keywords, string literals and numbers, and of the badge of
+// slow queries
+var (
+	SQLKeywordColor = color.New(color.FgBlue, color.Bold).SprintfFunc()
+	SQLStringColor  = color.New(color.FgGreen).SprintfFunc()
+	SQLNumberColor  = color.New(color.FgCyan).SprintfFunc()
+	SlowQueryColor  = color.New(color.FgHiWhite, color.BgRed, color.Bold).SprintfFunc()
+)
+
+// sqlStatement matches the start of a SQL statement written in capitals, as
+// SQLite, Room and most apps log them
+var sqlStatement = regexp.MustCompile(`\b(?:SELECT\s.*?\bFROM\b|INSERT\s+(?:OR\s+[A-Z]+\s+)?INTO\b|REPLACE\s+INTO\b|UPDATE\s+(?:OR\s+[A-Z]+\s+)?\S+\s+SET\b|DELETE\s+FROM\b|(?:CREATE|DROP)\s+(?:TEMP\s+)?(?:TABLE|INDEX|VIEW|TRIGGER)\b|ALTER\s+TABLE\b|PRAGMA\s|WITH\s+(?:RECURSIVE\s+)?\w+\s+AS\b|BEGIN(?:\s+(?:DEFERRED|IMMEDIATE|EXCLUSIVE))?\s+TRANSACTION\b)`)
+
+// sqlToken matches the tokens of a statement that are highlighted: string
+// literals, words and numbers
+var sqlToken = regexp.MustCompile(`'(?:[^']|'')*'|\b[A-Za-z_]\w*\b|\b\d+(?:\.\d+)?\b`)
+
+// sqlKeywords are the SQL keywords highlighted in statements
+var sqlKeywords = strings.Fields(`ABORT ADD ALL ALTER AND AS ASC BEGIN BETWEEN BY CASE COMMIT
+	CONFLICT CREATE CROSS DEFAULT DELETE DESC DISTINCT DROP ELSE END EXCEPT EXISTS FOREIGN
+	FROM GLOB GROUP HAVING IF IGNORE IN INDEX INNER INSERT INTERSECT INTO IS JOIN KEY LEFT
+	LIKE LIMIT NOT NULL OFFSET ON OR ORDER OUTER PRAGMA PRIMARY RECURSIVE REFERENCES REPLACE
+	RETURNING ROLLBACK SELECT SET TABLE TEMP THEN TRANSACTION TRIGGER UNION UNIQUE UPDATE
+	USING VALUES VIEW WHEN WHERE WITH`)
+
+// sqlDuration matches the execution time logged with a statement, such as
+// SQLiteConnection's "took 512ms"
+var sqlDuration = regexp.MustCompile(`(?i)\b(?:took|in|time[=:]?|duration[=:]?)\s*(\d+(?:\.\d+)?)\s*ms\b`)
+
+// sqlSlowQuery is the execution time from which a statement is marked slow
+const sqlSlowQuery = 100
+
+// prettifySQL highlights the keywords, strings and numbers of a SQL
+// statement in a message, marking it SLOW if it took sqlSlowQuery ms or
+// more
+func prettifySQL(message string, colorFunc func(format string, a ...any) string) (string, bool) {
+	loc := sqlStatement.FindStringIndex(message)
+	if loc == nil {
+		return "", false
+	}
+
+	var sb strings.Builder
+	if m := sqlDuration.FindStringSubmatch(message); m != nil {
+		if ms, _ := strconv.ParseFloat(m[1], 64); ms >= sqlSlowQuery {
+			sb.WriteString(SlowQueryColor(" SLOW %sms ", m[1]) + " ")
+		}
+	}
+	last := 0
+	for _, tok := range sqlToken.FindAllStringIndex(message[loc[0]:], -1) {
+		start, end := loc[0]+tok[0], loc[0]+tok[1]
+		word := message[start:end]
+		var colored string
+		switch {
+		case word[0] == '\'':
+			colored = SQLStringColor("%s", word)
+		case word[0] >= '0' && word[0] <= '9':
+			colored = SQLNumberColor("%s", word)
+		case slices.Contains(sqlKeywords, strings.ToUpper(word)):
+			colored = SQLKeywordColor("%s", word)
+		default:
+			continue
+		}
+		if last < start {
+			sb.WriteString(colorFunc("%s", message[last:start]))
+		}
+		sb.WriteString(colored)
+		last = end
+	}
+	if last < len(message) {
+		sb.WriteString(colorFunc("%s", message[last:]))
+	}
+	return sb.String(), true
+}
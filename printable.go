@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ansiPattern matches CSI sequences such as colors from logcat -v color or
+// another colorizer, and OSC sequences such as hyperlinks
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-?]*[ -/]*[@-~]|\x1b\\][^\x07\x1b]*(?:\x07|\x1b\\\\)")
+
+// stripANSI removes terminal escape sequences already present in a line so
+// that it parses normally and gets logcatcolor's own colors
+func stripANSI(line string) string {
+	if !strings.Contains(line, "\x1b") {
+		return line
+	}
+	return ansiPattern.ReplaceAllString(line, "")
+}
+
+// escapeControl makes a raw log line safe to print: control characters,
+// including ESC, and invalid UTF-8 bytes are shown as escapes (like logcat
+// -v printable) so log content cannot change the terminal's state
+func escapeControl(line string) string {
+	if isPrintable(line) {
+		return line
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			fmt.Fprintf(&b, "\\x%02x", line[i])
+		case r == '\t':
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", r)
+		case r >= 0x80 && r < 0xa0:
+			fmt.Fprintf(&b, "\\u%04x", r)
+		default:
+			b.WriteString(line[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// isPrintable reports whether line needs no escaping
+func isPrintable(line string) bool {
+	for _, r := range line {
+		if r == utf8.RuneError || r < 0x20 && r != '\t' || r >= 0x7f && r < 0xa0 {
+			return false
+		}
+	}
+	return true
+}
+
+// prepareLine cleans up a raw input line before it is parsed: existing
+// colors are removed, other escapes made visible and redactions applied
+func prepareLine(line string, opts LogcatOptions) string {
+	return redact(escapeControl(stripANSI(line)), opts.Redactions)
+}
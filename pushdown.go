@@ -0,0 +1,35 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// nonPortableRegexp matches Go regexp syntax that the device's ECMAScript
+// std::regex does not understand: flag groups, named groups, Unicode
+// classes, \A and \z anchors and POSIX bracket classes
+var nonPortableRegexp = regexp.MustCompile(`\(\?|\\[pPAzQE]|\[\[:`)
+
+// canPushDownGrep reports whether pattern means the same to the device's
+// logcat -e as it does here
+func canPushDownGrep(pattern string) bool {
+	return pattern != "" && !nonPortableRegexp.MatchString(pattern)
+}
+
+// seesUnfilteredLines reports whether something asked for records or counts
+// every line read, before -grep, so that the device must not filter the
+// stream. The binder failure summary, which is always on, is not counted.
+func (opts LogcatOptions) seesUnfilteredLines() bool {
+	return opts.Capture != nil || opts.Session != nil || opts.Ring != nil || opts.Incidents != nil ||
+		opts.Latency != nil || opts.Report != nil || opts.Exceptions != nil ||
+		opts.Stats != nil || opts.Tray != nil || opts.Suggest != nil || opts.Split != nil ||
+		opts.ANRDir != "" || opts.Quiet || opts.Net || opts.Jobs != nil
+}
+
+// supportsRegexFilter reports whether the device's logcat accepts -e by
+// dumping a single line with it; older releases reject the option
+func supportsRegexFilter(opts LogcatOptions) bool {
+	cmd := adbCommand(opts, "logcat", "-d", "-t", "1", "-e", ".")
+	out, err := cmd.CombinedOutput()
+	return err == nil && !strings.Contains(string(out), "Unrecognized Option")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// quietStatsInterval is how often -quiet prints statistics when
+// -stats-interval is not given
+const quietStatsInterval = 10 * time.Second
+
+// statsTopTags is the number of busiest tags named in each statistics line
+const statsTopTags = 3
+
+// StatsTimeColor styles the time that starts each statistics line
+var StatsTimeColor = color.New(color.FgCyan).SprintfFunc()
+
+// IncidentLabelColor styles the kind of incident reported with -quiet
+var IncidentLabelColor = color.New(color.FgHiWhite, color.BgRed, color.Bold).SprintfFunc()
+
+// IntervalStats counts the lines of each interval for -stats-interval
+type IntervalStats struct {
+	mu       sync.Mutex
+	w        io.Writer
+	interval time.Duration
+	start    time.Time
+	lines    int
+	levels   map[string]int
+	tags     map[string]int
+	total    int
+}
+
+// newIntervalStats prints statistics to w every interval until shutdown
+func newIntervalStats(w io.Writer, interval time.Duration) *IntervalStats {
+	s := &IntervalStats{w: w, interval: interval, start: time.Now(), levels: make(map[string]int), tags: make(map[string]int)}
+	go func() {
+		for range time.Tick(interval) {
+			s.Print()
+		}
+	}()
+	onShutdown(s.Print)
+	return s
+}
+
+// Observe counts a line, under its tag's alias
+func (s *IntervalStats) Observe(line string, opts LogcatOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines++
+	s.total++
+	if entry, ok := parseLogLine(line); ok {
+		s.levels[entry.Level]++
+		s.tags[tagAlias(entry.Tag, opts)]++
+	}
+}
+
+// Print prints the statistics of the interval so far and starts a new one
+func (s *IntervalStats) Print() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	elapsed := max(now.Sub(s.start).Seconds(), 0.001)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %6d lines %8.1f/s", StatsTimeColor("%s", now.Format("15:04:05")), s.lines, float64(s.lines)/elapsed)
+	for _, l := range levelOrder {
+		level := string(l)
+		if n := s.levels[level]; n > 0 {
+			fmt.Fprintf(&b, "  %s %d", LogLevelColors[level]("%s", level), n)
+		}
+	}
+	tags := slices.SortedFunc(maps.Keys(s.tags), func(a, b string) int {
+		return cmp.Or(cmp.Compare(s.tags[b], s.tags[a]), cmp.Compare(a, b))
+	})
+	for i, tag := range tags[:min(len(tags), statsTopTags)] {
+		sep := ", "
+		if i == 0 {
+			sep = "  top: "
+		}
+		fmt.Fprintf(&b, "%s%s %d", sep, tag, s.tags[tag])
+	}
+	fmt.Fprintf(&b, "  (total %d)\n", s.total)
+	io.WriteString(s.w, b.String())
+
+	s.start, s.lines = now, 0
+	clear(s.levels)
+	clear(s.tags)
+}
+
+// printQuietIncident reports a crash, ANR or native crash seen with -quiet
+func printQuietIncident(line string, opts LogcatOptions) {
+	entry, ok := parseLogLine(line)
+	if !ok {
+		return
+	}
+	if kind := crashKind(entry); kind != "" {
+		fmt.Fprintf(opts.out(), "%s %s\n", IncidentLabelColor(" %s ", kind), strings.TrimSpace(line))
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// reactNativeTag is the tag React Native uses for JavaScript console output
+const reactNativeTag = "ReactNativeJS"
+
+// JSFrameColor and JSLocationColor style decoded JavaScript stack frames
+var (
+	JSFrameColor    = color.New(color.FgCyan).SprintfFunc()
+	JSLocationColor = color.New(color.Faint).SprintfFunc()
+)
+
+// jsFramePattern matches Hermes ("at fn (address at index.android.bundle:1:234)")
+// and JSC ("fn@index.android.bundle:1:234") stack frames
+var jsFramePattern = regexp.MustCompile(`^(\s*)(?:at\s+(\S+)\s+\((?:address at\s+)?(\S+):(\d+):(\d+)\)|(\S*)@(\S+):(\d+):(\d+))\s*$`)
+
+// jsWarnPattern and jsErrorPattern match console.warn/console.error output
+// that React Native logs without a matching logcat level
+var (
+	jsWarnPattern  = regexp.MustCompile(`^(Warning|Possible Unhandled Promise Rejection)\b`)
+	jsErrorPattern = regexp.MustCompile(`^(Error|TypeError|ReferenceError|RangeError|SyntaxError|Invariant Violation|Unhandled JS Exception)\b`)
+)
+
+// prettifyReactNative rewrites a ReactNativeJS message, decoding stack frames
+// and choosing a color that matches the console method that produced it
+func prettifyReactNative(message, level string, colorFunc func(format string, a ...any) string, sm *SourceMap) (string, func(format string, a ...any) string) {
+	if frame, ok := formatJSFrame(message, sm); ok {
+		return frame, func(format string, a ...any) string { return fmt.Sprintf(format, a...) }
+	}
+
+	switch {
+	case jsErrorPattern.MatchString(message) && level != "F":
+		colorFunc = LogLevelColors["E"]
+	case jsWarnPattern.MatchString(message) && (level == "V" || level == "D" || level == "I"):
+		colorFunc = LogLevelColors["W"]
+	}
+	return message, colorFunc
+}
+
+// formatJSFrame decodes a bundled JavaScript stack frame, resolving it through
+// the source map when one is loaded
+func formatJSFrame(message string, sm *SourceMap) (string, bool) {
+	m := jsFramePattern.FindStringSubmatch(message)
+	if m == nil {
+		return "", false
+	}
+
+	indent, fn, file, lineStr, colStr := m[1], m[2], m[3], m[4], m[5]
+	if fn == "" && m[7] != "" {
+		fn, file, lineStr, colStr = m[6], m[7], m[8], m[9]
+	}
+	if fn == "" {
+		fn = "<anonymous>"
+	}
+
+	// Strip query strings and paths from bundle URLs such as
+	// http://10.0.2.2:8081/index.bundle?platform=android&dev=true
+	file, _, _ = strings.Cut(file, "?")
+	if i := strings.LastIndexByte(file, '/'); i >= 0 {
+		file = file[i+1:]
+	}
+	location := fmt.Sprintf("%s:%s:%s", file, lineStr, colStr)
+
+	if sm != nil {
+		line, _ := strconv.Atoi(lineStr)
+		col, _ := strconv.Atoi(colStr)
+		if pos, ok := sm.Lookup(line, col); ok {
+			if pos.Name != "" {
+				fn = pos.Name
+			}
+			location = fmt.Sprintf("%s:%d:%d", pos.Source, pos.Line, pos.Column)
+		}
+	}
+
+	return fmt.Sprintf("%sat %s %s", indent, JSFrameColor("%s", fn), JSLocationColor("(%s)", location)), true
+}
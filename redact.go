@@ -0,0 +1,32 @@
+package main
+
+import "regexp"
+
+// RedactionRule masks text matching Pattern with Replacement, which may
+// reference capture groups using regexp.Expand syntax such as ${1}
+type RedactionRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// builtinRedactions masks common sensitive values for --redact
+var builtinRedactions = []RedactionRule{
+	// Email addresses
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "<email>"},
+	// Bearer and basic authorization credentials
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`), "${1} <token>"},
+	// MAC addresses
+	{regexp.MustCompile(`\b[0-9A-Fa-f]{2}(?:[:-][0-9A-Fa-f]{2}){5}\b`), "<mac>"},
+	// IMEI/MEID-like identifiers
+	{regexp.MustCompile(`\b\d{14,16}\b`), "<imei>"},
+	// GPS coordinate pairs
+	{regexp.MustCompile(`-?\b\d{1,2}\.\d{4,}\s*,\s*-?\d{1,3}\.\d{4,}\b`), "<location>"},
+}
+
+// redact applies each redaction rule to line in order
+func redact(line string, rules []RedactionRule) string {
+	for _, rule := range rules {
+		line = rule.Pattern.ReplaceAllString(line, rule.Replacement)
+	}
+	return line
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/erdichen/logcatcolor/logcat"
+)
+
+// configPollInterval is how often the configuration file is checked for changes
+const configPollInterval = time.Second
+
+// reloadedFlags are the flags of profiles and presets applied again when the
+// configuration is reloaded; each takes a value
+var reloadedFlags = []string{"t", "l", "grep", "filter", "s"}
+
+// watchConfig returns a channel that receives a value when the configuration
+// file at path changes or the process receives SIGHUP, until ctx is canceled
+func watchConfig(ctx context.Context, path string) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	trigger := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-hup:
+				trigger()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if path != "" {
+		go func() {
+			var lastMod time.Time
+			var lastSize int64
+			if info, err := os.Stat(path); err == nil {
+				lastMod, lastSize = info.ModTime(), info.Size()
+			}
+			ticker := time.NewTicker(configPollInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					continue
+				}
+				if !info.ModTime().Equal(lastMod) || info.Size() != lastSize {
+					lastMod, lastSize = info.ModTime(), info.Size()
+					trigger()
+				}
+			}
+		}()
+	}
+
+	return changed
+}
+
+// mergeChanges returns a channel that receives a value whenever one of
+// channels does
+func mergeChanges(channels ...<-chan struct{}) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	for _, c := range channels {
+		go func() {
+			for range c {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+	return changed
+}
+
+// reloadConfig re-reads the configuration file and replaces the
+// configuration-derived settings in opts, along with the filters given in
+// the profile's and presets' args
+func reloadConfig(opts LogcatOptions) (LogcatOptions, error) {
+	cfg, err := LoadConfig(opts.ConfigPath, false)
+	if err != nil {
+		return opts, err
+	}
+	var profileArgs []string
+	if opts.Profile != "" {
+		p, ok := findProfile(cfg, opts.Profile)
+		if !ok {
+			return opts, fmt.Errorf("unknown profile %q", opts.Profile)
+		}
+		cfg.ApplyProfile(p)
+		profileArgs = append(profileArgs, p.Args...)
+	}
+	for _, name := range opts.Presets {
+		if p, ok := findProfile(cfg, name); ok {
+			cfg.ApplyProfile(p)
+			profileArgs = append(profileArgs, p.Args...)
+		}
+	}
+	for _, name := range activeToggles() {
+		if p, ok := findProfile(cfg, name); ok {
+			cfg.ApplyProfile(p)
+		}
+	}
+	cfg.Tags = mergeMap(cfg.Tags, activeMutes())
+	if err := applyConfig(&opts, cfg); err != nil {
+		return opts, err
+	}
+	if opts.Profile != "" || len(opts.Presets) > 0 {
+		if err := reloadFilters(&opts, slices.Concat(profileArgs, opts.CmdArgs)); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// reloadFilters sets the -t, -l, -grep, -filter and -s filters in opts from
+// args, later flags winning as on the command line. Other flags are left
+// alone, as they take effect only at startup. -s and a -grep passed to the
+// device change what adb sends from its next restart.
+func reloadFilters(opts *LogcatOptions, args []string) error {
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var filters []string
+	fs.Func("s", "", func(s string) error {
+		filters = append(filters, s)
+		return nil
+	})
+	tag, level := fs.String("t", "", ""), fs.String("l", "", "")
+	grep, filterExpr := fs.String("grep", "", ""), fs.String("filter", "", "")
+	if err := fs.Parse(filterArgs(args)); err != nil {
+		return err
+	}
+
+	var re *regexp.Regexp
+	if *grep != "" {
+		var err error
+		if re, err = regexp.Compile(*grep); err != nil {
+			return fmt.Errorf("invalid -grep pattern: %w", err)
+		}
+	}
+	var filter logcat.Filter
+	if *filterExpr != "" {
+		var err error
+		if filter, err = logcat.ParseFilter(*filterExpr); err != nil {
+			return fmt.Errorf("invalid -filter: %w", err)
+		}
+	}
+	opts.Tag, opts.Level, opts.Grep, opts.Filter, opts.Filters = *tag, strings.ToUpper(*level), re, filter, filters
+	opts.GrepOnDevice = opts.GrepOnDevice && re != nil && canPushDownGrep(re.String())
+	return nil
+}
+
+// filterArgs returns the reloadedFlags in args with their values, dropping
+// every other flag and argument
+func filterArgs(args []string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || !slices.Contains(reloadedFlags, name) {
+			continue
+		}
+		kept = append(kept, args[i])
+		if !hasValue && i+1 < len(args) {
+			i++
+			kept = append(kept, args[i])
+		}
+	}
+	return kept
+}
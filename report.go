@@ -0,0 +1,180 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// reportTopRows is the number of tags and processes listed in text reports
+const reportTopRows = 25
+
+// reportTimeLayout formats first and last seen times in reports
+const reportTimeLayout = "01-02 15:04:05.000"
+
+// ReportHeaderColor styles the header row of text reports
+var ReportHeaderColor = color.New(color.Bold, color.Underline).SprintfFunc()
+
+// rateStats counts the lines of one tag or process
+type rateStats struct {
+	Name   string  `json:"name"`
+	Lines  int     `json:"lines"`
+	Errors int     `json:"errors"` // Lines at level E or F
+	Bytes  int64   `json:"bytes"`
+	Rate   float64 `json:"linesPerSec"`
+	First  string  `json:"first"`
+	Last   string  `json:"last"`
+	first  time.Time
+	last   time.Time
+}
+
+// add counts a line
+func (s *rateStats) add(entry logLine, size int) {
+	if s.Lines == 0 || entry.Time.Before(s.first) {
+		s.first = entry.Time
+	}
+	if s.Lines == 0 || entry.Time.After(s.last) {
+		s.last = entry.Time
+	}
+	s.Lines++
+	s.Bytes += int64(size)
+	if !levelBelow(entry.Level, "E") {
+		s.Errors++
+	}
+}
+
+// finish fills in the fields derived from the counts
+func (s *rateStats) finish() {
+	s.First, s.Last = s.first.Format(reportTimeLayout), s.last.Format(reportTimeLayout)
+	s.Rate = float64(s.Lines) / max(s.last.Sub(s.first).Seconds(), 1)
+}
+
+// RateReport counts lines, errors and bytes per tag and per process for
+// the -report printed at exit
+type RateReport struct {
+	mu     sync.Mutex
+	format string // text or json
+	sortBy string // lines, errors, bytes, rate or name
+	path   string // File to write the report to, stderr if empty
+	tags   map[string]*rateStats
+	procs  map[string]*rateStats
+	names  map[string]string // Process names by PID, from ActivityManager
+}
+
+// newRateReport returns a report in the given format sorted by sortBy
+func newRateReport(format, sortBy, path string) (*RateReport, error) {
+	if format != "text" && format != "json" {
+		return nil, fmt.Errorf("invalid -report %q, must be text or json", format)
+	}
+	if !slices.Contains([]string{"lines", "errors", "bytes", "rate", "name"}, sortBy) {
+		return nil, fmt.Errorf("invalid -report-sort %q, must be lines, errors, bytes, rate or name", sortBy)
+	}
+	return &RateReport{
+		format: format,
+		sortBy: sortBy,
+		path:   path,
+		tags:   make(map[string]*rateStats),
+		procs:  make(map[string]*rateStats),
+		names:  make(map[string]string),
+	}, nil
+}
+
+// Observe counts a line, under its tag's alias
+func (r *RateReport) Observe(line string, opts LogcatOptions) {
+	entry, ok := parseLogLine(line)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if process, pid, _, ok := parseProcessStart(entry.Message); ok {
+		r.names[pid] = process
+	}
+	for _, group := range []struct {
+		stats map[string]*rateStats
+		key   string
+	}{{r.tags, tagAlias(entry.Tag, opts)}, {r.procs, entry.PID}} {
+		s, ok := group.stats[group.key]
+		if !ok {
+			s = &rateStats{Name: group.key}
+			group.stats[group.key] = s
+		}
+		s.add(entry, len(line)+1)
+	}
+}
+
+// sorted returns the counts in report order
+func (r *RateReport) sorted(stats map[string]*rateStats) []*rateStats {
+	rows := make([]*rateStats, 0, len(stats))
+	for _, s := range stats {
+		s.finish()
+		rows = append(rows, s)
+	}
+	slices.SortFunc(rows, func(a, b *rateStats) int {
+		switch r.sortBy {
+		case "errors":
+			return cmp.Or(cmp.Compare(b.Errors, a.Errors), cmp.Compare(b.Lines, a.Lines))
+		case "bytes":
+			return cmp.Compare(b.Bytes, a.Bytes)
+		case "rate":
+			return cmp.Compare(b.Rate, a.Rate)
+		case "name":
+			return cmp.Compare(a.Name, b.Name)
+		}
+		return cmp.Or(cmp.Compare(b.Lines, a.Lines), cmp.Compare(a.Name, b.Name))
+	})
+	return rows
+}
+
+// Write writes the report to its file, or to stderr
+func (r *RateReport) Write() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for pid, s := range r.procs {
+		if name, ok := r.names[pid]; ok {
+			s.Name = pid + " " + name
+		}
+	}
+	tags, procs := r.sorted(r.tags), r.sorted(r.procs)
+
+	var w io.Writer = os.Stderr
+	if r.path != "" {
+		f, err := os.Create(r.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if r.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string][]*rateStats{"tags": tags, "processes": procs})
+	}
+	printRateTable(w, "TAG", tags)
+	printRateTable(w, "PROCESS", procs)
+	return nil
+}
+
+// printRateTable prints the top rows of a text report
+func printRateTable(w io.Writer, title string, rows []*rateStats) {
+	fmt.Fprintln(w, ReportHeaderColor("%-32s %8s %7s %10s %8s  %-18s  %-18s", title, "LINES", "ERRORS", "BYTES", "LINES/S", "FIRST", "LAST"))
+	for _, s := range rows[:min(len(rows), reportTopRows)] {
+		errors := fmt.Sprintf("%7d", s.Errors)
+		if s.Errors > 0 {
+			errors = LogLevelColors["E"]("%s", errors)
+		}
+		fmt.Fprintf(w, "%s %8d %s %10d %8.2f  %-18s  %-18s\n", padRight(truncateWidth(s.Name, 32), 32), s.Lines, errors, s.Bytes, s.Rate, s.First, s.Last)
+	}
+	if len(rows) > reportTopRows {
+		fmt.Fprintf(w, "... and %d more\n", len(rows)-reportTopRows)
+	}
+	fmt.Fprintln(w)
+}
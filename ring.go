@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ringDumpInterval limits how often a trigger pattern dumps the ring buffer
+const ringDumpInterval = 10 * time.Second
+
+// RingBuffer keeps the most recent raw lines in memory, like a flight
+// recorder, so they can be saved after something interesting happens
+type RingBuffer struct {
+	mu       sync.Mutex
+	lines    []string
+	next     int
+	full     bool
+	lastDump time.Time
+	watching sync.Once      // Installs the signal handler once
+	signals  chan os.Signal // Dump requests, handled by the stream's loop
+}
+
+// newRingBuffer returns a ring buffer holding up to size lines
+func newRingBuffer(size int) *RingBuffer {
+	return &RingBuffer{lines: make([]string, size)}
+}
+
+// Add records a line, replacing the oldest when the buffer is full
+func (r *RingBuffer) Add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	r.full = r.full || r.next == 0
+}
+
+// Lines returns the buffered lines, oldest first
+func (r *RingBuffer) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return slices.Clone(r.lines[:r.next])
+	}
+	return slices.Concat(r.lines[r.next:], r.lines[:r.next])
+}
+
+// Dump saves the buffered lines under dir as a raw capture and as the
+// colored output, returning the raw file's path
+func (r *RingBuffer) Dump(dir string, opts LogcatOptions) (string, error) {
+	lines := r.Lines()
+	base := filepath.Join(dir, "flight-"+time.Now().Format("20060102-150405.000"))
+
+	raw, err := createCapture(base + ".log")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		if err := raw.WriteLine(line, opts); err != nil {
+			raw.Close()
+			return "", err
+		}
+	}
+	if err := raw.Close(); err != nil {
+		return "", err
+	}
+
+	colored, err := os.Create(base + ".color.log")
+	if err != nil {
+		return "", err
+	}
+	defer colored.Close()
+
+	// Render as the terminal would, with line numbers, tag and day state of
+	// its own, without sending the lines to the -output sinks again or
+	// repeating bells and desktop notifications
+	defer swapRenderState()()
+	opts.Output, opts.Sinks, opts.SinksOnly, opts.Bell = colored, nil, false, ""
+	opts.Rules = slices.Clone(opts.Rules)
+	for i := range opts.Rules {
+		if opts.Rules[i].Action == ActionNotify {
+			opts.Rules[i].Action = ActionNone
+		}
+	}
+	lastTag, lastTime, lastOther := "", time.Time{}, ""
+	for _, line := range lines {
+		lastTag, lastTime, lastOther = printColoredLog(line, lastTag, lastTime, lastOther, opts)
+	}
+	return base + ".log", nil
+}
+
+// Trigger dumps the buffer because a line matched the trigger pattern,
+// at most once every ringDumpInterval
+func (r *RingBuffer) Trigger(dir string, opts LogcatOptions) {
+	r.mu.Lock()
+	if time.Since(r.lastDump) < ringDumpInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastDump = time.Now()
+	r.mu.Unlock()
+	r.dumpAndReport(dir, opts)
+}
+
+// dumpAndReport dumps the buffer and reports where it went
+func (r *RingBuffer) dumpAndReport(dir string, opts LogcatOptions) {
+	path, err := r.Dump(dir, opts)
+	if err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error dumping ring buffer: %v\n", err))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Saved the last %d lines to %s\n", len(r.Lines()), path)
+}
+
+// DumpRequests returns a channel receiving the platform's dump signal
+// (SIGUSR2), on which the stream's loop dumps the buffer between lines so
+// the dump never renders concurrently with it. The channel is nil where
+// there is no dump signal.
+func (r *RingBuffer) DumpRequests() <-chan os.Signal {
+	if len(dumpSignals) == 0 {
+		return nil
+	}
+	r.watching.Do(func() {
+		r.signals = make(chan os.Signal, 1)
+		signal.Notify(r.signals, dumpSignals...)
+	})
+	return r.signals
+}
+
+// DumpOnRequest dumps the buffer if a dump signal is pending
+func (r *RingBuffer) DumpOnRequest(requests <-chan os.Signal, dir string, opts LogcatOptions) {
+	select {
+	case <-requests:
+		r.dumpAndReport(dir, opts)
+	default:
+	}
+}
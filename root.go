@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// rootBufferSize is the logd buffer size requested when root is available
+const rootBufferSize = "16M"
+
+// anrTraceDelay gives the system time to finish writing ANR traces
+const anrTraceDelay = 5 * time.Second
+
+// RootAccess records how privileged commands run on the device
+type RootAccess struct {
+	ADBRoot bool // adbd itself runs as root
+	Su      bool // su is available to the shell user
+}
+
+// Available reports whether privileged commands can run at all
+func (r RootAccess) Available() bool {
+	return r.ADBRoot || r.Su
+}
+
+// wrap returns the device shell words running command as root
+func (r RootAccess) wrap(command string) []string {
+	if r.ADBRoot {
+		return []string{command}
+	}
+	return []string{"su", "-c", fmt.Sprintf("'%s'", command)}
+}
+
+// detectRoot checks whether adbd runs as root or su is usable
+func detectRoot(opts LogcatOptions) RootAccess {
+	var r RootAccess
+	if out, err := adbCommand(opts, "shell", "id", "-u").Output(); err == nil && strings.TrimSpace(string(out)) == "0" {
+		r.ADBRoot = true
+		return r
+	}
+	if out, err := adbCommand(opts, "shell", "su", "-c", "id -u").Output(); err == nil && strings.TrimSpace(string(out)) == "0" {
+		r.Su = true
+	}
+	return r
+}
+
+// enableRootCapture detects root and, when present, captures all buffers and
+// raises the logd buffer size so less is lost
+func enableRootCapture(opts *LogcatOptions) {
+	opts.Root = detectRoot(*opts)
+	if !opts.Root.Available() {
+		fmt.Fprintf(os.Stderr, "Root not available, capturing with default buffers\n")
+		return
+	}
+
+	if len(opts.Buffers) == 0 {
+		opts.Buffers = []string{"all"}
+	}
+	cmd := adbCommand(*opts, append([]string{"shell"}, opts.Root.wrap("logcat -b all -G "+rootBufferSize)...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["W"]("Could not raise logd buffer size: %v %s\n", err, strings.TrimSpace(string(out))))
+	}
+	fmt.Fprintf(os.Stderr, "Root available, capturing %s with %s buffers\n", strings.Join(opts.Buffers, ","), rootBufferSize)
+}
+
+// isANR reports whether a log line announces an application not responding
+func isANR(line string) bool {
+	return strings.Contains(line, "ANR in ")
+}
+
+// pullANRTraces copies /data/anr from the device into a new timestamped
+// directory under dir once the traces have been written
+func pullANRTraces(opts LogcatOptions, dir string) {
+	time.Sleep(anrTraceDelay)
+
+	dest := filepath.Join(dir, "anr-"+time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error creating %s: %v\n", dest, err))
+		return
+	}
+
+	// The shell user cannot read /data/anr, so stream it through tar as root
+	out, err := adbCommand(opts, append([]string{"exec-out"}, opts.Root.wrap("tar -cf - -C /data anr")...)...).Output()
+	if err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error pulling ANR traces: %v\n", err))
+		return
+	}
+	path := filepath.Join(dest, "anr.tar")
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error writing %s: %v\n", path, err))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "ANR traces saved to %s\n", path)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// sizePattern matches sizes such as "64M", "512K" or "1GB"
+var sizePattern = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)\s*([KMG]?)B?$`)
+
+// parseSize converts a size flag such as "64M" into bytes
+func parseSize(s string) (int64, error) {
+	m := sizePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q, expected a number of bytes with an optional K, M or G", s)
+	}
+	return int64(parseByteSize(m[1], m[2]+"B")), nil
+}
+
+// RotatingFile is a log file that is renamed to path.1, path.2 and so on
+// when it reaches its maximum size, keeping a limited number of old files
+type RotatingFile struct {
+	path    string
+	maxSize int64
+	keep    int // Old files to keep
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// openRotating opens path for appending, rotating it at maxSize bytes and
+// keeping keep old files
+func openRotating(path string, maxSize int64, keep int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, keep: keep}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the current file
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+// WriteLine appends a line, first rotating the file if the line would take
+// it past its maximum size
+func (r *RotatingFile) WriteLine(line string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(line))+1 > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := io.WriteString(r.file, line+"\n")
+	r.size += int64(n)
+	return err
+}
+
+// rotate shifts path.N to path.N+1, dropping the oldest, and starts a new
+// file
+func (r *RotatingFile) rotate() error {
+	r.file.Close()
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.keep))
+	for i := r.keep - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if r.keep > 0 {
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// Size returns the size of the current file
+func (r *RotatingFile) Size() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.size
+}
+
+// Close closes the current file
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
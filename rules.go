@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/erdichen/logcatcolor/logcat"
+)
+
+// levelOrder lists log levels from least to most severe
+const levelOrder = "VDIWEF"
+
+// RuleAction is what a highlight rule does to a matching line
+type RuleAction string
+
+const (
+	ActionNone   RuleAction = ""       // Only apply the rule's style
+	ActionHide   RuleAction = "hide"   // Drop the line
+	ActionRaise  RuleAction = "raise"  // Raise the line's level
+	ActionNotify RuleAction = "notify" // Show a desktop notification
+)
+
+// HighlightRule styles or acts on lines matching a tag, level and message pattern
+type HighlightRule struct {
+	Tag     string                               // Tag to match, empty for any tag
+	Level   string                               // Level to match, empty for any level
+	Pattern *regexp.Regexp                       // Message pattern to match, nil for any message
+	Style   func(format string, a ...any) string // Message style, nil to keep the level color
+	Action  RuleAction
+	RaiseTo string         // Level assigned by ActionRaise
+	When    *logcat.Script // Further condition, nil for none
+	Rewrite *logcat.Script // Computes the message to display, nil to keep it
+}
+
+// matchRule returns the first rule matching the line, or nil, and the
+// message to display, rewritten if the rule says so. Scripts see the groups
+// of the rule's pattern; a script that fails is reported and treated as not
+// matching or not rewriting.
+func matchRule(entry logLine, rules []HighlightRule) (*HighlightRule, string) {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Tag != "" && rule.Tag != entry.Tag {
+			continue
+		}
+		if rule.Level != "" && rule.Level != entry.Level {
+			continue
+		}
+		env := logcat.NewEnv(entry)
+		if rule.Pattern != nil {
+			groups := rule.Pattern.FindStringSubmatch(entry.Message)
+			if groups == nil {
+				continue
+			}
+			env.SetGroups(groups)
+		}
+		if rule.When != nil {
+			if ok, err := rule.When.Bool(env); err != nil {
+				reportScriptError(rule.When, err)
+				continue
+			} else if !ok {
+				continue
+			}
+		}
+		message := entry.Message
+		if rule.Rewrite != nil {
+			if text, err := rule.Rewrite.Text(env); err != nil {
+				reportScriptError(rule.Rewrite, err)
+			} else {
+				message = text
+			}
+		}
+		return rule, message
+	}
+	return nil, entry.Message
+}
+
+// reportedScripts holds the scripts whose errors were reported, so that each
+// is reported once
+var reportedScripts sync.Map
+
+// reportScriptError reports the first error of a rule script
+func reportScriptError(script *logcat.Script, err error) {
+	if _, loaded := reportedScripts.LoadOrStore(script, true); !loaded {
+		fmt.Fprint(os.Stderr, LogLevelColors["W"]("Error in rule script %v\n", err))
+	}
+}
+
+// raiseLevel returns the level one step more severe than level
+func raiseLevel(level string) string {
+	i := strings.Index(levelOrder, level)
+	if i < 0 || i == len(levelOrder)-1 {
+		return level
+	}
+	return levelOrder[i+1 : i+2]
+}
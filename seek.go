@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// seekPageLines is the number of capture lines shown per page in -seek mode
+const seekPageLines = 40
+
+// clockLayouts are the accepted forms of -from and -to
+var clockLayouts = []string{"01-02 15:04:05", "01-02 15:04", "15:04:05", "15:04"}
+
+// TimeWindow limits replayed lines to those logged between From and To
+type TimeWindow struct {
+	From, To time.Time
+	Dated    bool // From and To include the month and day
+}
+
+// parseClock parses a -from or -to value, reporting whether it has a date
+func parseClock(s string) (time.Time, bool, error) {
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, strings.Contains(layout, "-"), nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid time %q, expected HH:MM[:SS] or MM-DD HH:MM[:SS]", s)
+}
+
+// parseTimeWindow parses the -from and -to values, either of which may be
+// empty. A time without a date takes the date of the other bound.
+func parseTimeWindow(from, to string) (TimeWindow, error) {
+	var w TimeWindow
+	var fromDated, toDated bool
+	var err error
+	if from != "" {
+		if w.From, fromDated, err = parseClock(from); err != nil {
+			return w, err
+		}
+	}
+	if to != "" {
+		if w.To, toDated, err = parseClock(to); err != nil {
+			return w, err
+		}
+	}
+
+	withDate := func(t, date time.Time) time.Time {
+		return time.Date(0, date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+	switch {
+	case fromDated && !toDated && !w.To.IsZero():
+		w.To = withDate(w.To, w.From)
+	case toDated && !fromDated && !w.From.IsZero():
+		w.From = withDate(w.From, w.To)
+	}
+	w.Dated = fromDated || toDated
+	return w, nil
+}
+
+// Contains reports whether t falls inside the window. Without dates only
+// the time of day is compared.
+func (w TimeWindow) Contains(t time.Time) bool {
+	if !w.Dated {
+		t = time.Date(0, 1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+	return (w.From.IsZero() || !t.Before(w.From)) && (w.To.IsZero() || !t.After(w.To))
+}
+
+// windowLines returns a reader producing the lines of r inside the window.
+// Lines without a timestamp stay with the line before them.
+func windowLines(r io.Reader, w TimeWindow) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		out := bufio.NewWriter(pw)
+		inside := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			if t, err := parseTimestamp(line); err == nil {
+				inside = w.Contains(t)
+			}
+			if inside {
+				out.WriteString(line)
+				out.WriteByte('\n')
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(out.Flush())
+	}()
+	return pr
+}
+
+// replaySeek pages through a capture, reading navigation commands from the
+// terminal between pages: Enter for the next page, +10s/-1m to seek, e and
+// E for the next and previous error, g HH:MM:SS to jump and q to quit
+func replaySeek(r io.Reader, opts *LogcatOptions) error {
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return fmt.Errorf("-seek needs a terminal on stdin")
+	}
+
+	// Load the capture, giving continuation lines the time of the line before
+	var lines []string
+	var times []time.Time
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	last := time.Time{}
+	for scanner.Scan() {
+		line := prepareLine(scanner.Text(), *opts)
+		if t, err := parseTimestamp(line); err == nil {
+			last = t
+		}
+		lines = append(lines, line)
+		times = append(times, last)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	isError := func(i int) bool {
+		entry, ok := parseLogLine(lines[i])
+		return ok && !levelBelow(remapSeverity(entry.Level, entry.Tag, entry.Message, opts.Severities), "E")
+	}
+	seekTime := func(t time.Time) int {
+		return min(sort.Search(len(times), func(i int) bool { return !times[i].Before(t) }), len(lines)-1)
+	}
+
+	commands := bufio.NewScanner(os.Stdin)
+	pos, next := 0, 0
+	for {
+		// Show a page starting at pos
+		lastTag, lastTime, lastOther := "", time.Time{}, ""
+		next = min(pos+seekPageLines, len(lines))
+		for _, line := range lines[pos:next] {
+			lastTag, lastTime, lastOther = printColoredLog(line, lastTag, lastTime, lastOther, *opts)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s [%d-%d/%d] Enter, +/-DURATION, e/E, g TIME, q> ",
+			times[pos].Format("01-02 15:04:05"), pos+1, next, len(lines))
+		if !commands.Scan() {
+			return commands.Err()
+		}
+		cmd := strings.TrimSpace(commands.Text())
+		switch {
+		case cmd == "":
+			if next >= len(lines) {
+				return nil
+			}
+			pos = next
+		case cmd == "q":
+			return nil
+		case cmd == "e" || cmd == "E":
+			found := -1
+			if cmd == "e" {
+				for i := pos + 1; i < len(lines) && found < 0; i++ {
+					if isError(i) {
+						found = i
+					}
+				}
+			} else {
+				for i := pos - 1; i >= 0 && found < 0; i-- {
+					if isError(i) {
+						found = i
+					}
+				}
+			}
+			if found < 0 {
+				fmt.Fprintln(os.Stderr, "No more errors")
+				continue
+			}
+			pos = found
+		case strings.HasPrefix(cmd, "+") || strings.HasPrefix(cmd, "-"):
+			d, err := time.ParseDuration(cmd)
+			if err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Invalid duration %q\n", cmd))
+				continue
+			}
+			pos = seekTime(times[pos].Add(d))
+		case strings.HasPrefix(cmd, "g "):
+			t, dated, err := parseClock(strings.TrimSpace(cmd[2:]))
+			if err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("%v\n", err))
+				continue
+			}
+			if !dated {
+				t = time.Date(times[pos].Year(), times[pos].Month(), times[pos].Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+			}
+			pos = seekTime(t)
+		default:
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Unknown command %q\n", cmd))
+		}
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// errNoService is returned by the service command outside Windows
+var errNoService = errors.New("services are only available on Windows; use \"logcatcolor daemon\" under systemd or launchd instead")
+
+// runService runs as the service
+func runService(opts LogcatOptions) error {
+	return errNoService
+}
+
+// installService registers the service
+func installService(opts LogcatOptions) error {
+	return errNoService
+}
+
+// controlService starts, stops or removes the installed service
+func controlService(action string) error {
+	return errNoService
+}
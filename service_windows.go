@@ -0,0 +1,156 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceName is the name logcatcolor is installed under as a Windows service
+const serviceName = "logcatcolor"
+
+// windowsService runs the daemon for the service control manager
+type windowsService struct {
+	opts LogcatOptions
+}
+
+// Execute captures until the service is stopped or the system shuts down
+func (s *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	d, err := newDaemon(s.opts)
+	if err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error starting service: %v\n", err))
+		return true, 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case err := <-done:
+			// Stopped through the control socket, or failed to start
+			cancel()
+			if err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error in service: %v\n", err))
+				return true, 1
+			}
+			return false, 0
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				<-done
+				return false, 0
+			}
+		}
+	}
+}
+
+// runService runs as the service, logging to daemon.log in the capture
+// directory since services have no console
+func runService(opts LogcatOptions) error {
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return err
+	}
+	log, err := os.OpenFile(filepath.Join(opts.OutDir, daemonLog), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	os.Stdout, os.Stderr = log, log
+	return svc.Run(serviceName, &windowsService{opts: opts})
+}
+
+// installService registers the service to start with Windows, running this
+// executable with the flags given to "service install" and an absolute
+// -out-dir, since services start in the system directory
+func installService(opts LogcatOptions) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	outDir, err := filepath.Abs(opts.OutDir)
+	if err != nil {
+		return err
+	}
+	args := slices.Clone(os.Args[1:])
+	i := slices.Index(args, "install")
+	args = slices.Concat(args[:i], []string{"-out-dir", outDir, "run"}, args[i+1:])
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to the service manager (run as administrator): %w", err)
+	}
+	defer m.Disconnect()
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "logcatcolor capture",
+		Description: "Captures the logs of attached Android devices to rotated files",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	fmt.Fprintf(os.Stderr, "Service %s installed, capturing to %s; start it with \"logcatcolor service start\"\n", serviceName, outDir)
+	return nil
+}
+
+// controlService starts, stops or removes the installed service
+func controlService(action string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to the service manager (run as administrator): %w", err)
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", serviceName, err)
+	}
+	defer s.Close()
+
+	switch action {
+	case "start":
+		err = s.Start()
+	case "stop":
+		err = stopService(s)
+	case "uninstall":
+		stopService(s)
+		err = s.Delete()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Service %s %s\n", serviceName, map[string]string{"start": "started", "stop": "stopped", "uninstall": "removed"}[action])
+	return nil
+}
+
+// stopService asks the service to stop and waits up to 30 seconds for it
+func stopService(s *mgr.Service) error {
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		return err
+	}
+	for deadline := time.Now().Add(30 * time.Second); status.State != svc.Stopped; {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service did not stop in time")
+		}
+		time.Sleep(300 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
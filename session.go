@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session records a capture into a directory holding the raw log, device
+// properties, crash extracts, screenshots and a JSON summary
+type Session struct {
+	Name    string         `json:"name"`
+	Device  *DeviceInfo    `json:"device,omitempty"`
+	Started time.Time      `json:"started"`
+	Ended   time.Time      `json:"ended"`
+	Lines   int            `json:"lines"`
+	Levels  map[string]int `json:"levels"`
+	Crashes []SessionCrash `json:"crashes"`
+	Files   []string       `json:"files"`
+
+	dir     string
+	adb     func(args ...string) *exec.Cmd
+	log     *bufio.Writer
+	logFile *os.File
+	crashes *os.File
+	inCrash string // PID and tag of the crash being extracted, empty if none
+	mu      sync.Mutex
+	shots   sync.WaitGroup // Screenshots still being taken
+	done    sync.Once
+}
+
+// SessionCrash describes a crash, ANR or native crash seen while recording
+type SessionCrash struct {
+	Time       string `json:"time"`
+	Kind       string `json:"kind"` // "crash", "anr" or "native-crash"
+	PID        string `json:"pid"`
+	Tag        string `json:"tag"`
+	Summary    string `json:"summary"`
+	Screenshot string `json:"screenshot,omitempty"`
+}
+
+// crashKind returns the kind of crash a line starts, or "" if none
+func crashKind(entry logLine) string {
+	switch {
+	case entry.Tag == "AndroidRuntime" && strings.HasPrefix(entry.Message, "FATAL EXCEPTION"):
+		return "crash"
+	case entry.Tag == "ActivityManager" && strings.HasPrefix(entry.Message, "ANR in "):
+		return "anr"
+	case (entry.Tag == "DEBUG" || entry.Tag == "libc") && strings.Contains(entry.Message, "*** *** *** *** ***"):
+		return "native-crash"
+	}
+	return ""
+}
+
+// newSession creates the session directory and its raw log file
+func newSession(name, parent string) (*Session, error) {
+	if name == "" {
+		name = "session-" + time.Now().Format("20060102-150405")
+	}
+	s := &Session{Name: name, Levels: make(map[string]int), Crashes: []SessionCrash{}, dir: filepath.Join(parent, name)}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if s.logFile, err = s.create("logcat.log"); err != nil {
+		return nil, err
+	}
+	s.log = bufio.NewWriter(s.logFile)
+	if s.crashes, err = s.create("crashes.txt"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// create creates a file in the session directory and lists it in the summary
+func (s *Session) create(name string) (*os.File, error) {
+	s.Files = append(s.Files, name)
+	return os.Create(filepath.Join(s.dir, name))
+}
+
+// Start saves the device description and properties once a device is chosen
+func (s *Session) Start(opts LogcatOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Started = time.Now()
+	s.adb = func(args ...string) *exec.Cmd { return adbCommand(opts, args...) }
+	s.Device = opts.DeviceInfo
+	if s.Device == nil {
+		if info, err := queryDeviceInfo(opts); err == nil {
+			s.Device = &info
+		}
+	}
+	opts.DeviceInfo = s.Device
+	writeCaptureHeader(s.log, opts)
+	if out, err := s.adb("shell", "getprop").Output(); err == nil {
+		if f, err := s.create("props.txt"); err == nil {
+			f.Write(out)
+			f.Close()
+		}
+	}
+}
+
+// Observe records a raw line, extracting crashes into crashes.txt and taking
+// a screenshot when one starts
+func (s *Session) Observe(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Lines++
+	s.log.WriteString(line)
+	s.log.WriteByte('\n')
+
+	entry, ok := parseLogLine(line)
+	if !ok {
+		if s.inCrash != "" {
+			fmt.Fprintln(s.crashes, line)
+		}
+		return
+	}
+	s.Levels[entry.Level]++
+
+	// A crash continues with the lines of the same process and tag
+	key := entry.PID + " " + entry.Tag
+	if kind := crashKind(entry); kind != "" {
+		if len(s.Crashes) > 0 {
+			fmt.Fprintln(s.crashes)
+		}
+		crash := SessionCrash{Time: line[:entry.LevelIndex], Kind: kind, PID: entry.PID, Tag: entry.Tag, Summary: entry.Message}
+		crash.Time = strings.TrimSpace(strings.Join(strings.Fields(crash.Time)[:2], " "))
+		s.Crashes = append(s.Crashes, crash)
+		if s.adb != nil {
+			s.shots.Add(1)
+			go s.screenshot(len(s.Crashes) - 1)
+		}
+		s.inCrash = key
+	} else if key != s.inCrash {
+		s.inCrash = ""
+	}
+	if s.inCrash != "" {
+		fmt.Fprintln(s.crashes, line)
+	}
+
+	// The process of a Java crash and the reason for an ANR follow the first line
+	if s.inCrash == key && (strings.HasPrefix(entry.Message, "Process: ") || strings.HasPrefix(entry.Message, "Reason: ")) {
+		s.Crashes[len(s.Crashes)-1].Summary += "; " + entry.Message
+	}
+}
+
+// screenshot saves a screenshot of the device for a crash
+func (s *Session) screenshot(crash int) {
+	defer s.shots.Done()
+
+	name := fmt.Sprintf("screenshot-%d.png", crash+1)
+	out, err := s.adb("exec-out", "screencap", "-p").Output()
+	if err == nil && len(out) == 0 {
+		err = fmt.Errorf("screencap returned no image")
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(s.dir, name), out, 0o644)
+	}
+	if err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["W"]("Could not take screenshot: %v\n", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Crashes[crash].Screenshot = name
+	s.Files = append(s.Files, name)
+}
+
+// Finish flushes the log and writes summary.json
+func (s *Session) Finish() {
+	s.done.Do(func() {
+		s.shots.Wait()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.Ended = time.Now()
+		s.log.Flush()
+		s.logFile.Close()
+		s.crashes.Close()
+		s.Files = append(s.Files, "summary.json")
+
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err == nil {
+			err = os.WriteFile(filepath.Join(s.dir, "summary.json"), append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["E"]("Error writing session summary: %v\n", err))
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Session saved to %s (%d lines, %d crashes)\n", s.dir, s.Lines, len(s.Crashes))
+	})
+}
+
+// runRecord streams like watch while recording the session directory
+// parseArgs created
+func runRecord(ctx context.Context, opts *LogcatOptions) error {
+	onShutdown(opts.Session.Finish)
+	defer opts.Session.Finish()
+	return runWatch(ctx, opts)
+}
@@ -0,0 +1,29 @@
+package main
+
+import "regexp"
+
+// SeverityRule changes the level of lines matching a tag and/or message pattern
+type SeverityRule struct {
+	Tag     string         // Tag to match, empty for any tag
+	Pattern *regexp.Regexp // Message pattern to match, nil for any message
+	From    string         // Level to match, empty for any level
+	To      string         // Level to assign
+}
+
+// remapSeverity returns the level assigned by the first matching rule, or
+// the original level if no rule matches
+func remapSeverity(level, tag, message string, rules []SeverityRule) string {
+	for _, rule := range rules {
+		if rule.Tag != "" && rule.Tag != tag {
+			continue
+		}
+		if rule.From != "" && rule.From != level {
+			continue
+		}
+		if rule.Pattern != nil && !rule.Pattern.MatchString(message) {
+			continue
+		}
+		return rule.To
+	}
+	return level
+}
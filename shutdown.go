@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// ExitInterrupted is the exit code after SIGINT or SIGTERM
+const ExitInterrupted = 130
+
+// shutdownGrace is how long the pipeline may take to drain after a signal
+// before the process exits anyway
+const shutdownGrace = 2 * time.Second
+
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []func()
+	shutdownOnce  sync.Once
+	children      = make(map[*exec.Cmd]struct{})
+	interrupted   atomic.Bool
+
+	// shutdownSignals receives SIGINT and SIGTERM, and the requests to stop
+	// made as if one was received
+	shutdownSignals = make(chan os.Signal, 2)
+)
+
+// onShutdown registers f to run once before the process exits, after the
+// adb children have been stopped. Hooks run in reverse registration order.
+func onShutdown(f func()) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, f)
+}
+
+// startChild starts cmd in its own process group and tracks it so that it
+// is terminated on shutdown
+func startChild(cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	if interrupted.Load() {
+		return fmt.Errorf("interrupted")
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	children[cmd] = struct{}{}
+	return nil
+}
+
+// waitChild waits for a command started with startChild
+func waitChild(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	shutdownMu.Lock()
+	delete(children, cmd)
+	shutdownMu.Unlock()
+	return err
+}
+
+// isInterrupted reports whether a termination signal has been received
+func isInterrupted() bool {
+	return interrupted.Load()
+}
+
+// handleSignals stops the adb children on SIGINT or SIGTERM so the pipeline
+// drains and exits normally, and cancels the returned context so waits and
+// restarts stop. A second signal, or a pipeline that does not drain in time,
+// exits immediately after running the shutdown hooks.
+func handleSignals() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := shutdownSignals
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		interrupted.Store(true)
+		cancel()
+
+		shutdownMu.Lock()
+		for cmd := range children {
+			terminateProcess(cmd)
+		}
+		shutdownMu.Unlock()
+
+		select {
+		case <-signals:
+		case <-time.After(shutdownGrace):
+		}
+		exit(ExitInterrupted)
+	}()
+	return ctx
+}
+
+// requestShutdown stops the process as SIGINT does, on every platform
+func requestShutdown() {
+	select {
+	case shutdownSignals <- os.Interrupt:
+	default:
+	}
+}
+
+// runShutdownHooks runs the registered hooks once and restores the terminal
+func runShutdownHooks() {
+	shutdownOnce.Do(func() {
+		shutdownMu.Lock()
+		hooks := shutdownHooks
+		shutdownMu.Unlock()
+
+		// Reset any attributes left by a line cut off mid-way
+		if !color.NoColor {
+			fmt.Print("\x1b[0m")
+		}
+		for i := len(hooks) - 1; i >= 0; i-- {
+			hooks[i]()
+		}
+		os.Stdout.Sync()
+	})
+}
+
+// exitStatus returns the exit code for a run that ended without an error
+func exitStatus() int {
+	if isInterrupted() {
+		return ExitInterrupted
+	}
+	return 0
+}
+
+// exit runs the shutdown hooks and exits with code
+func exit(code int) {
+	runShutdownHooks()
+	os.Exit(code)
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals make logcatcolor save its ring buffer
+var dumpSignals = []os.Signal{syscall.SIGUSR2}
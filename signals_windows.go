@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// dumpSignals make logcatcolor save its ring buffer; Windows has no SIGUSR2
+var dumpSignals []os.Signal
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// adbSource is the logcat.Source for adb logcat as configured by opts. Its
+// process is stopped on shutdown and its failures are classified.
+type adbSource struct {
+	opts LogcatOptions
+}
+
+// adbStream is the output of a running adb logcat command
+type adbStream struct {
+	io.ReadCloser
+	ctx    context.Context
+	cmd    *exec.Cmd
+	stderr *stderrCapture
+	stop   func() bool // Stops terminating adb when ctx is canceled
+}
+
+// Open starts adb logcat, which is terminated when ctx is canceled
+func (s adbSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	cmd := buildAdbCommand(s.opts)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+	}
+	stderr, err := pipeStderr(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("creating stderr pipe: %w", err)
+	}
+	if err := startChild(cmd); err != nil {
+		return nil, classifyADBError(err, nil)
+	}
+	stop := context.AfterFunc(ctx, func() { terminateProcess(cmd) })
+	return &adbStream{ReadCloser: stdout, ctx: ctx, cmd: cmd, stderr: stderr, stop: stop}, nil
+}
+
+// Close waits for adb to exit and returns its classified failure, if any,
+// unless it was stopped by canceling its context
+func (s *adbStream) Close() error {
+	defer s.stop()
+	stderrLines := s.stderr.Wait()
+	if err := waitChild(s.cmd); err != nil && s.ctx.Err() == nil {
+		return classifyADBError(err, stderrLines)
+	}
+	return nil
+}
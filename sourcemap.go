@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SourceMap is a decoded version 3 JavaScript source map
+type SourceMap struct {
+	Sources []string
+	Names   []string
+	lines   [][]sourceMapSegment // Segments for each generated line, sorted by column
+}
+
+// sourceMapSegment maps a generated column to an original position
+type sourceMapSegment struct {
+	genColumn  int
+	source     int // Index into Sources, -1 if the segment has no source
+	origLine   int
+	origColumn int
+	name       int // Index into Names, -1 if the segment has no name
+}
+
+// OriginalPosition is the location in the original source for a generated position
+type OriginalPosition struct {
+	Source string
+	Line   int // 1-based
+	Column int // 1-based
+	Name   string
+}
+
+// LoadSourceMap reads and decodes a source map file
+func LoadSourceMap(path string) (*SourceMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		Version    int      `json:"version"`
+		SourceRoot string   `json:"sourceRoot"`
+		Sources    []string `json:"sources"`
+		Names      []string `json:"names"`
+		Mappings   string   `json:"mappings"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing source map %s: %w", path, err)
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d in %s", raw.Version, path)
+	}
+
+	sm := &SourceMap{Sources: raw.Sources, Names: raw.Names}
+	if raw.SourceRoot != "" {
+		for i, src := range sm.Sources {
+			sm.Sources[i] = strings.TrimSuffix(raw.SourceRoot, "/") + "/" + src
+		}
+	}
+	if err := sm.decodeMappings(raw.Mappings); err != nil {
+		return nil, fmt.Errorf("decoding mappings in %s: %w", path, err)
+	}
+	return sm, nil
+}
+
+// decodeMappings decodes the base64 VLQ "mappings" field
+func (sm *SourceMap) decodeMappings(mappings string) error {
+	var source, origLine, origColumn, name int
+
+	for _, lineMappings := range strings.Split(mappings, ";") {
+		var segments []sourceMapSegment
+		genColumn := 0
+
+		for _, group := range strings.Split(lineMappings, ",") {
+			if group == "" {
+				continue
+			}
+			fields, err := decodeVLQ(group)
+			if err != nil {
+				return err
+			}
+
+			genColumn += fields[0]
+			seg := sourceMapSegment{genColumn: genColumn, source: -1, name: -1}
+			if len(fields) >= 4 {
+				source += fields[1]
+				origLine += fields[2]
+				origColumn += fields[3]
+				seg.source, seg.origLine, seg.origColumn = source, origLine, origColumn
+			}
+			if len(fields) >= 5 {
+				name += fields[4]
+				seg.name = name
+			}
+			segments = append(segments, seg)
+		}
+
+		sort.SliceStable(segments, func(i, j int) bool { return segments[i].genColumn < segments[j].genColumn })
+		sm.lines = append(sm.lines, segments)
+	}
+	return nil
+}
+
+// decodeVLQ decodes a single comma-separated group of base64 VLQ values
+func decodeVLQ(group string) ([]int, error) {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+	var values []int
+	value, shift := 0, 0
+	for _, c := range group {
+		digit := strings.IndexRune(alphabet, c)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base64 VLQ character %q", c)
+		}
+		value += (digit & 0x1f) << shift
+		if digit&0x20 != 0 {
+			shift += 5
+			continue
+		}
+		if value&1 != 0 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("truncated base64 VLQ value in %q", group)
+	}
+	return values, nil
+}
+
+// Lookup returns the original position for a 1-based generated line and column
+func (sm *SourceMap) Lookup(line, column int) (OriginalPosition, bool) {
+	if line < 1 || line > len(sm.lines) {
+		return OriginalPosition{}, false
+	}
+	segments := sm.lines[line-1]
+
+	// Find the last segment starting at or before the column
+	i := sort.Search(len(segments), func(i int) bool { return segments[i].genColumn > column-1 }) - 1
+	if i < 0 || segments[i].source < 0 || segments[i].source >= len(sm.Sources) {
+		return OriginalPosition{}, false
+	}
+
+	seg := segments[i]
+	pos := OriginalPosition{
+		Source: sm.Sources[seg.source],
+		Line:   seg.origLine + 1,
+		Column: seg.origColumn + 1,
+	}
+	if seg.name >= 0 && seg.name < len(sm.Names) {
+		pos.Name = sm.Names[seg.name]
+	}
+	return pos, true
+}
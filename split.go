@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processRefreshInterval limits how often unknown PIDs make the splitter
+// list the device's processes again
+const processRefreshInterval = 2 * time.Second
+
+// maxSplitFiles bounds the number of split files kept open at once; the
+// least recently written is closed and reopened for appending when needed
+const maxSplitFiles = 128
+
+// unsafeFileChars matches characters not used in split file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Splitter writes lines into one file per tag or process under a directory
+type Splitter struct {
+	// ADB runs adb against the device being split, so process names can be
+	// looked up with ps; nil when reading captures
+	ADB func(args ...string) *exec.Cmd
+
+	by      string // "tag" or "process"
+	dir     string
+	keyFunc func(entry logLine) string
+	files   map[string]*splitFile
+	names   map[string]string // File names by key
+	used    map[string]bool   // File names taken, in lower case
+	lastKey string            // Key of the last parsed line, for continuation lines
+	clock   int
+}
+
+// splitFile is an open split file and when it was last written
+type splitFile struct {
+	f        *os.File
+	lastUsed int
+}
+
+// newSplitter creates a splitter writing into a split-DATE-TIME directory
+// under dir, so that runs are kept apart, splitting by "tag" or "process"
+func newSplitter(by, dir string) (*Splitter, error) {
+	dir = filepath.Join(dir, "split-"+time.Now().Format("20060102-150405.000"))
+	s := &Splitter{by: by, dir: dir, files: make(map[string]*splitFile), names: make(map[string]string), used: make(map[string]bool)}
+	switch by {
+	case "tag":
+		s.keyFunc = func(entry logLine) string { return entry.Tag }
+	case "process":
+		s.keyFunc = s.processKey()
+	default:
+		return nil, fmt.Errorf("unknown -split-by %q, must be tag or process", by)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// processKey returns a key function naming each line's process. PIDs are
+// resolved from ActivityManager start messages and, on devices, ps. When an
+// app process is started again its lines roll over to a new numbered file.
+func (s *Splitter) processKey() func(entry logLine) string {
+	names := make(map[string]string)    // Process names by PID
+	generations := make(map[string]int) // Restarts seen for each process name
+	started := make(map[string]string)  // Key of each PID's file, fixed when it starts
+	var lastRefresh time.Time
+
+	return func(entry logLine) string {
+		if entry.Tag == "ActivityManager" {
+			if process, pid, _, ok := parseProcessStart(entry.Message); ok {
+				names[pid] = process
+				key := process
+				if n, seen := generations[process]; seen {
+					key = process + "." + strconv.Itoa(n+1)
+				}
+				generations[process]++
+				started[pid] = key
+			}
+		}
+
+		if key, ok := started[entry.PID]; ok {
+			return key
+		}
+		name, ok := names[entry.PID]
+		if !ok && s.ADB != nil && time.Since(lastRefresh) >= processRefreshInterval {
+			lastRefresh = time.Now()
+			runningPids(s.ADB, nil, names)
+			name, ok = names[entry.PID]
+		}
+		if !ok {
+			return "pid-" + entry.PID
+		}
+		if _, seen := generations[name]; !seen {
+			generations[name] = 1
+		}
+		return name
+	}
+}
+
+// Write appends line to the file for its key. Lines that do not parse go to
+// the same file as the line before them.
+func (s *Splitter) Write(line string) error {
+	if entry, ok := parseLogLine(line); ok {
+		s.lastKey = s.keyFunc(entry)
+	}
+	if s.lastKey == "" {
+		return nil
+	}
+
+	file, err := s.open(s.lastKey)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(file.f, line)
+	return err
+}
+
+// open returns the open file for key, opening it and closing the least
+// recently used file if too many are open
+func (s *Splitter) open(key string) (*splitFile, error) {
+	s.clock++
+	if file, ok := s.files[key]; ok {
+		file.lastUsed = s.clock
+		return file, nil
+	}
+
+	if len(s.files) >= maxSplitFiles {
+		oldest := ""
+		for k, file := range s.files {
+			if oldest == "" || file.lastUsed < s.files[oldest].lastUsed {
+				oldest = k
+			}
+		}
+		s.files[oldest].f.Close()
+		delete(s.files, oldest)
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, s.fileName(key)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	file := &splitFile{f: f, lastUsed: s.clock}
+	s.files[key] = file
+	return file, nil
+}
+
+// fileName returns the name of key's file. Keys changed by sanitizing, or
+// differing only in case from one seen before, get a hash of the key
+// appended so that no two keys share a file.
+func (s *Splitter) fileName(key string) string {
+	if name, ok := s.names[key]; ok {
+		return name
+	}
+	name := unsafeFileChars.ReplaceAllString(key, "_")
+	if name != key || s.used[strings.ToLower(name)] {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		name = fmt.Sprintf("%s-%08x", name, h.Sum32())
+	}
+	s.used[strings.ToLower(name)] = true
+	s.names[key] = name + ".log"
+	return s.names[key]
+}
+
+// Close closes the open split files
+func (s *Splitter) Close() error {
+	var first error
+	for _, file := range s.files {
+		if err := file.f.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	s.files = make(map[string]*splitFile)
+	return first
+}
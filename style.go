@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// StyleConfig describes a text style in the configuration file
+type StyleConfig struct {
+	Fg        string `json:"fg"`        // Foreground color: a name such as "bright-red", "#RRGGBB" or 0-255
+	Bg        string `json:"bg"`        // Background color
+	Bold      bool   `json:"bold"`      // Bold text
+	Italic    bool   `json:"italic"`    // Italic text
+	Underline bool   `json:"underline"` // Underlined text
+	Dim       bool   `json:"dim"`       // Faint text
+}
+
+// defaultLevelFg is the foreground color name of each level in LogLevelColors
+var defaultLevelFg = map[string]string{
+	"V": "white",
+	"D": "blue",
+	"I": "green",
+	"W": "yellow",
+	"E": "red",
+	"F": "magenta",
+}
+
+// colorNames maps color names to their foreground attributes
+var colorNames = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+}
+
+// parseColorName converts a color name to a foreground or background attribute
+func parseColorName(name string, background bool) (color.Attribute, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	bright := false
+	for _, prefix := range []string{"bright-", "bright", "hi-", "hi"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			if _, known := colorNames[rest]; known {
+				name, bright = rest, true
+				break
+			}
+		}
+	}
+
+	attr, ok := colorNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown color %q", name)
+	}
+	if bright {
+		attr += color.FgHiBlack - color.FgBlack
+	}
+	if background {
+		attr += color.BgBlack - color.FgBlack
+	}
+	return attr, nil
+}
+
+// parseColor converts a color name, "#RRGGBB" hex value or 0-255 palette
+// index to attributes, degrading to what the terminal supports
+func parseColor(spec string, background bool) ([]color.Attribute, error) {
+	spec = strings.TrimSpace(spec)
+
+	var rgb [3]int
+	switch {
+	case strings.HasPrefix(spec, "#"):
+		v, err := strconv.ParseUint(spec[1:], 16, 32)
+		if err != nil || len(spec) != 7 {
+			return nil, fmt.Errorf("invalid hex color %q", spec)
+		}
+		rgb = [3]int{int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff)}
+		if colorDepth() >= 24 {
+			return rgbAttrs(rgb, background), nil
+		}
+	case spec != "" && strings.Trim(spec, "0123456789") == "":
+		index, err := strconv.Atoi(spec)
+		if err != nil || index > 255 {
+			return nil, fmt.Errorf("invalid color index %q", spec)
+		}
+		if index < 16 || colorDepth() >= 8 {
+			return paletteAttrs(index, background), nil
+		}
+		rgb = paletteRGB(index)
+	default:
+		attr, err := parseColorName(spec, background)
+		if err != nil {
+			return nil, err
+		}
+		return []color.Attribute{attr}, nil
+	}
+
+	// Degrade an RGB color to the nearest palette entry
+	if colorDepth() >= 8 {
+		return paletteAttrs(nearestPalette(rgb, 16, 256), background), nil
+	}
+	return paletteAttrs(nearestPalette(rgb, 0, 16), background), nil
+}
+
+// setColorMode chooses whether to color output: "always", "never", or
+// "auto" to color only a terminal, or when CI sets FORCE_COLOR or
+// CLICOLOR_FORCE. Everything colored follows it, including stderr and the
+// colored flight recorder dumps.
+func setColorMode(mode string) error {
+	switch mode {
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	case "auto":
+		for _, name := range []string{"FORCE_COLOR", "CLICOLOR_FORCE"} {
+			if v := os.Getenv(name); v != "" && v != "0" && os.Getenv("NO_COLOR") == "" {
+				color.NoColor = false
+			}
+		}
+	default:
+		return fmt.Errorf("must be auto, always or never")
+	}
+	return nil
+}
+
+// colorDepth returns the number of color bits the terminal supports: 24 for
+// truecolor, 8 for the xterm 256-color palette, or 4 for basic ANSI colors
+func colorDepth() int {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return 24
+	}
+	if os.Getenv("WT_SESSION") != "" {
+		return 24
+	}
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return 8
+	}
+	return 4
+}
+
+// rgbAttrs returns the 24-bit color attributes for rgb
+func rgbAttrs(rgb [3]int, background bool) []color.Attribute {
+	mode := color.Attribute(38)
+	if background {
+		mode = 48
+	}
+	return []color.Attribute{mode, 2, color.Attribute(rgb[0]), color.Attribute(rgb[1]), color.Attribute(rgb[2])}
+}
+
+// paletteAttrs returns the attributes selecting a 256-color palette entry,
+// using the basic ANSI attributes for the first 16 entries
+func paletteAttrs(index int, background bool) []color.Attribute {
+	var attr color.Attribute
+	switch {
+	case index < 8:
+		attr = color.FgBlack + color.Attribute(index)
+	case index < 16:
+		attr = color.FgHiBlack + color.Attribute(index-8)
+	default:
+		mode := color.Attribute(38)
+		if background {
+			mode = 48
+		}
+		return []color.Attribute{mode, 5, color.Attribute(index)}
+	}
+	if background {
+		attr += color.BgBlack - color.FgBlack
+	}
+	return []color.Attribute{attr}
+}
+
+// ansiRGB approximates the RGB values of the 16 basic ANSI colors (xterm defaults)
+var ansiRGB = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// paletteRGB returns the RGB value of an xterm 256-color palette entry
+func paletteRGB(index int) [3]int {
+	switch {
+	case index < 16:
+		return ansiRGB[index]
+	case index < 232:
+		levels := [6]int{0, 95, 135, 175, 215, 255}
+		i := index - 16
+		return [3]int{levels[i/36], levels[i/6%6], levels[i%6]}
+	default:
+		gray := 8 + 10*(index-232)
+		return [3]int{gray, gray, gray}
+	}
+}
+
+// nearestPalette returns the palette index in [from, to) closest to rgb
+func nearestPalette(rgb [3]int, from, to int) int {
+	best, bestDist := from, -1
+	for i := from; i < to; i++ {
+		p := paletteRGB(i)
+		dr, dg, db := p[0]-rgb[0], p[1]-rgb[1], p[2]-rgb[2]
+		dist := dr*dr + dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// Compile converts the style to a color function, or nil if it sets nothing
+func (s StyleConfig) Compile() (func(format string, a ...any) string, error) {
+	var attrs []color.Attribute
+	if s.Fg != "" {
+		fg, err := parseColor(s.Fg, false)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, fg...)
+	}
+	if s.Bg != "" {
+		bg, err := parseColor(s.Bg, true)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, bg...)
+	}
+	if s.Bold {
+		attrs = append(attrs, color.Bold)
+	}
+	if s.Italic {
+		attrs = append(attrs, color.Italic)
+	}
+	if s.Underline {
+		attrs = append(attrs, color.Underline)
+	}
+	if s.Dim {
+		attrs = append(attrs, color.Faint)
+	}
+
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+	return color.New(attrs...).SprintfFunc(), nil
+}
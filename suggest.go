@@ -0,0 +1,169 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// SuggestionColor styles the questions asked by -suggest-mutes
+var SuggestionColor = color.New(color.FgYellow, color.Bold).SprintfFunc()
+
+// Thresholds for -suggest-mutes: a tag is suggested when its lines at one
+// level below I reach both counts within an interval
+const (
+	suggestMinLines = 1000
+	suggestMinShare = 0.2
+)
+
+var (
+	mutesMu sync.Mutex
+	// acceptedMutes holds the tag overrides accepted from -suggest-mutes,
+	// applied over the configuration's on each reload
+	acceptedMutes = map[string]TagConfig{}
+)
+
+// activeMutes returns the tag overrides accepted from -suggest-mutes
+func activeMutes() map[string]TagConfig {
+	mutesMu.Lock()
+	defer mutesMu.Unlock()
+	return maps.Clone(acceptedMutes)
+}
+
+// MuteSuggester counts the low-level lines of each tag for -suggest-mutes and
+// offers to mute the noisiest tag every interval
+type MuteSuggester struct {
+	mu         sync.Mutex
+	configPath string
+	profile    string         // Profile the accepted mutes are saved in, "" for the top level
+	counts     map[string]int // Lines by tag and level, as "TAG\x00L"
+	total      int
+	asked      map[string]bool // Tags and levels already offered
+	pending    string          // Tag and level awaiting an answer
+}
+
+// newMuteSuggester offers mutes every interval until shutdown, saving the
+// accepted ones into profile in the configuration file at configPath
+func newMuteSuggester(interval time.Duration, configPath, profile string) *MuteSuggester {
+	s := &MuteSuggester{configPath: configPath, profile: profile, counts: make(map[string]int), asked: make(map[string]bool)}
+	go func() {
+		for range time.Tick(interval) {
+			s.suggest()
+		}
+	}()
+	return s
+}
+
+// Observe counts a line
+func (s *MuteSuggester) Observe(line string) {
+	entry, ok := parseLogLine(line)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	if levelBelow(entry.Level, "I") {
+		s.counts[entry.Tag+"\x00"+entry.Level]++
+	}
+}
+
+// suggest asks whether to mute the noisiest tag of the interval, unless a
+// question is still unanswered, and starts a new interval
+func (s *MuteSuggester) suggest() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	muted := activeMutes()
+	best := ""
+	for key, n := range s.counts {
+		tag, _, _ := strings.Cut(key, "\x00")
+		if _, ok := muted[tag]; ok || s.asked[key] || n < suggestMinLines || float64(n) < suggestMinShare*float64(s.total) {
+			continue
+		}
+		if best == "" || cmp.Or(cmp.Compare(n, s.counts[best]), cmp.Compare(best, key)) > 0 {
+			best = key
+		}
+	}
+	if s.pending == "" && best != "" {
+		tag, level, _ := strings.Cut(best, "\x00")
+		s.pending = best
+		s.asked[best] = true
+		fmt.Fprint(os.Stderr, SuggestionColor("Tag %s produced %s %s lines in the last interval (%.0f%%), mute it? [y/N] ",
+			tag, shortCount(s.counts[best]), level, 100*float64(s.counts[best])/float64(s.total)))
+	}
+	clear(s.counts)
+	s.total = 0
+}
+
+// Answer takes line as the answer to the pending suggestion, if any,
+// reporting whether it did
+func (s *MuteSuggester) Answer(line string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == "" {
+		return false
+	}
+	tag, level, _ := strings.Cut(s.pending, "\x00")
+	s.pending = ""
+	if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), "y") {
+		fmt.Fprintf(os.Stderr, "Not muting %s\n", tag)
+		return true
+	}
+
+	// Keep the tag's lines above the noisy level
+	override := TagConfig{MinLevel: string(levelOrder[strings.Index(levelOrder, level)+1])}
+	mutesMu.Lock()
+	acceptedMutes[tag] = override
+	mutesMu.Unlock()
+	if err := s.save(tag, override); err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["W"]("Muted %s below %s until exit; saving it failed: %v\n", tag, override.MinLevel, err))
+	} else if s.profile != "" {
+		fmt.Fprintf(os.Stderr, "Muted %s below %s, saved in profile %s\n", tag, override.MinLevel, s.profile)
+	} else {
+		fmt.Fprintf(os.Stderr, "Muted %s below %s, saved in %s\n", tag, override.MinLevel, s.configPath)
+	}
+	return true
+}
+
+// save records a mute in the configuration file
+func (s *MuteSuggester) save(tag string, override TagConfig) error {
+	if s.configPath == "" {
+		return fmt.Errorf("no configuration file")
+	}
+	data, err := json.Marshal(map[string]string{"minLevel": override.MinLevel})
+	if err != nil {
+		return err
+	}
+	setTag := func(tags map[string]json.RawMessage) error {
+		tags[tag] = data
+		return nil
+	}
+	return editConfig(s.configPath, func(settings map[string]json.RawMessage) error {
+		if s.profile == "" {
+			return editSetting(settings, "tags", setTag)
+		}
+		return editSetting(settings, "profiles", func(profiles map[string]json.RawMessage) error {
+			return editSetting(profiles, s.profile, func(profile map[string]json.RawMessage) error {
+				return editSetting(profile, "tags", setTag)
+			})
+		})
+	})
+}
+
+// shortCount formats n as in "12k" past a thousand
+func shortCount(n int) string {
+	switch {
+	case n >= 10000:
+		return fmt.Sprintf("%dk", n/1000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TagConfig overrides visibility, minimum level and color for one tag. In the
+// configuration file it is either an object or the string "hide" or "show".
+type TagConfig struct {
+	Hide     bool   `json:"hide"`     // Drop all lines with this tag
+	MinLevel string `json:"minLevel"` // Drop lines below this level
+	Color    string `json:"color"`    // Color of the tag column
+}
+
+// UnmarshalJSON accepts either a TagConfig object or a "hide"/"show" shorthand
+func (t *TagConfig) UnmarshalJSON(data []byte) error {
+	var shorthand string
+	if err := json.Unmarshal(data, &shorthand); err == nil {
+		switch strings.ToLower(shorthand) {
+		case "hide":
+			*t = TagConfig{Hide: true}
+		case "show":
+			*t = TagConfig{}
+		default:
+			return fmt.Errorf("invalid tag setting %q", shorthand)
+		}
+		return nil
+	}
+
+	type plain TagConfig
+	return json.Unmarshal(data, (*plain)(t))
+}
+
+// TagOverride is a compiled per-tag override
+type TagOverride struct {
+	Hide     bool
+	MinLevel string
+	Color    func(format string, a ...any) string // Tag column color, nil for the default
+}
+
+// levelBelow reports whether level is less severe than minLevel
+func levelBelow(level, minLevel string) bool {
+	if minLevel == "" {
+		return false
+	}
+	return strings.Index(levelOrder, level) < strings.Index(levelOrder, minLevel)
+}
+
+// tagAlias returns the name shown for tag: its alias from the configuration,
+// or the tag itself
+func tagAlias(tag string, opts LogcatOptions) string {
+	if alias, ok := opts.Aliases[tag]; ok {
+		return alias
+	}
+	return tag
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// traceSyncInterval is how often -trace-sync writes a clock sync marker
+const traceSyncInterval = 10 * time.Second
+
+// traceMinSection is the shortest trace section annotated without
+// -trace-sections, about one frame
+const traceMinSection = 16 * time.Millisecond
+
+// traceSyncScript writes the same realtime clock sync marker to the kernel
+// trace buffer, where atrace and perfetto record it, and to logcat
+const traceSyncScript = `t=$(($(date +%s%N) / 1000000)); ` +
+	`for f in /sys/kernel/tracing/trace_marker /sys/kernel/debug/tracing/trace_marker; do ` +
+	`[ -w $f ] && echo "trace_event_clock_sync: realtime_ts=$t" > $f && break; done; ` +
+	`log -t logcatcolor "trace_event_clock_sync: realtime_ts=$t"`
+
+// traceMarker matches a trace_marker write in a systrace or ftrace text
+// trace, capturing the thread ID, the timestamp in seconds and the marker
+var traceMarker = regexp.MustCompile(`^\s*.*-(\d+)\s+(?:\(\s*[\d-]+\)\s+)?\[\d+\]\s+(?:\S+\s+)?(\d+\.\d+): tracing_mark_write: (.*)$`)
+
+// traceClockSync matches the realtime clock sync marker in a trace
+var traceClockSync = regexp.MustCompile(`trace_event_clock_sync: realtime_ts=(\d+)`)
+
+// traceEvent is an annotation shown in the log stream at Time
+type traceEvent struct {
+	Time time.Time
+	Text string
+}
+
+// openSection is a trace section whose end has not been read yet
+type openSection struct {
+	name  string
+	begin float64 // Trace seconds
+}
+
+// loadTraceEvents reads the sections of a systrace or ftrace text trace,
+// such as the output of atrace or of perfetto's traceconv systrace, and
+// returns annotations for their beginnings and ends in logcat time. The
+// trace's clock is converted with its last realtime clock sync marker, as
+// written by -trace-sync or atrace, into the host's time zone. Sections
+// matching pattern are kept, or those lasting traceMinSection if it is nil.
+func loadTraceEvents(path string, pattern *regexp.Regexp) ([]traceEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	type section struct {
+		name       string
+		tid        string
+		begin, end float64
+	}
+	var sections []section
+	open := make(map[string][]openSection) // By thread ID
+	offset, synced := 0.0, false           // Realtime seconds minus trace seconds
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		m := traceMarker.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		tid, marker := m[1], m[3]
+		ts, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		if sync := traceClockSync.FindStringSubmatch(marker); sync != nil {
+			realtime, _ := strconv.ParseFloat(sync[1], 64)
+			offset, synced = realtime/1000-ts, true
+			continue
+		}
+		// B|pid|name begins a section on the thread, E or E|pid ends the
+		// innermost one
+		switch fields := strings.SplitN(marker, "|", 3); {
+		case fields[0] == "B" && len(fields) == 3:
+			open[tid] = append(open[tid], openSection{name: fields[2], begin: ts})
+		case fields[0] == "E" && len(open[tid]) > 0:
+			s := open[tid][len(open[tid])-1]
+			open[tid] = open[tid][:len(open[tid])-1]
+			sections = append(sections, section{s.name, tid, s.begin, ts})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !synced {
+		return nil, fmt.Errorf("%s has no trace_event_clock_sync realtime_ts marker; capture it with -trace-sync", path)
+	}
+
+	logcatTime := func(ts float64) time.Time {
+		t := time.UnixMicro(int64((ts + offset) * 1e6)).Local()
+		return time.Date(0, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+	var events []traceEvent
+	for _, s := range sections {
+		d := time.Duration((s.end - s.begin) * float64(time.Second)).Round(100 * time.Microsecond)
+		if pattern != nil && !pattern.MatchString(s.name) || pattern == nil && d < traceMinSection {
+			continue
+		}
+		events = append(events,
+			traceEvent{logcatTime(s.begin), fmt.Sprintf("--------- trace begin %s (tid %s, %v)", s.name, s.tid, d)},
+			traceEvent{logcatTime(s.end), fmt.Sprintf("--------- trace end %s (tid %s)", s.name, s.tid)})
+	}
+	slices.SortStableFunc(events, func(a, b traceEvent) int { return a.Time.Compare(b.Time) })
+	return events, nil
+}
+
+// traceAnnotations returns a reader producing the lines of r with the
+// trace events inserted before the first line logged after them
+func traceAnnotations(r io.Reader, events []traceEvent) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		out := bufio.NewWriter(pw)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if t, err := parseTimestamp(line); err == nil {
+				for len(events) > 0 && !events[0].Time.After(t) {
+					out.WriteString(events[0].Text)
+					out.WriteByte('\n')
+					events = events[1:]
+				}
+			}
+			out.WriteString(line)
+			out.WriteByte('\n')
+		}
+		if err := scanner.Err(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(out.Flush())
+	}()
+	return pr
+}
+
+// startTraceSync writes a clock sync marker to the device's trace buffer
+// and logcat now and every traceSyncInterval until ctx is canceled, so that
+// traces captured meanwhile can be aligned with the log by -trace
+func startTraceSync(ctx context.Context, opts LogcatOptions) {
+	go func() {
+		for {
+			if out, err := adbCommand(opts, "shell", traceSyncScript).CombinedOutput(); err != nil {
+				fmt.Fprint(os.Stderr, LogLevelColors["W"]("Writing a trace clock sync marker failed: %v %s\n", err, strings.TrimSpace(string(out))))
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(traceSyncInterval):
+			}
+		}
+	}()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	imagecolor "image/color"
+	"image/png"
+	"runtime"
+	"sync"
+)
+
+// Colors of the -tray icon while disconnected, while streaming and with
+// unread errors
+var (
+	trayDisconnected = imagecolor.RGBA{0x9e, 0x9e, 0x9e, 0xff}
+	trayStreaming    = imagecolor.RGBA{0x43, 0xa0, 0x47, 0xff}
+	trayErrors       = imagecolor.RGBA{0xe5, 0x39, 0x35, 0xff}
+)
+
+// trayIconSize is the width and height of the -tray icon in pixels
+const trayIconSize = 32
+
+// TrayStatus is what the -tray icon shows: the device being streamed from
+// and the errors logged since they were last marked read
+type TrayStatus struct {
+	mu      sync.Mutex
+	device  string // Serial of the device streamed from, empty while disconnected
+	unread  int
+	changed chan struct{}
+}
+
+// newTrayStatus returns the status of a disconnected stream
+func newTrayStatus() *TrayStatus {
+	return &TrayStatus{changed: make(chan struct{}, 1)}
+}
+
+// SetDevice records the device streamed from, or "" once the stream ended
+func (t *TrayStatus) SetDevice(serial string) {
+	t.mu.Lock()
+	t.device = serial
+	t.mu.Unlock()
+	t.notify()
+}
+
+// Observe counts line if it is an error or fatal line
+func (t *TrayStatus) Observe(line string) {
+	entry, ok := parseLogLine(line)
+	if !ok || levelBelow(entry.Level, "E") {
+		return
+	}
+	t.mu.Lock()
+	t.unread++
+	t.mu.Unlock()
+	t.notify()
+}
+
+// MarkRead resets the count of unread errors
+func (t *TrayStatus) MarkRead() {
+	t.mu.Lock()
+	t.unread = 0
+	t.mu.Unlock()
+	t.notify()
+}
+
+// notify wakes the tray to show the new status
+func (t *TrayStatus) notify() {
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+}
+
+// describe returns the status as the tray's tooltip and the icon's color
+func (t *TrayStatus) describe() (string, imagecolor.RGBA) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	text, c := "Disconnected", trayDisconnected
+	if t.device != "" {
+		text, c = "Streaming from "+t.device, trayStreaming
+	}
+	if t.unread > 0 {
+		text += fmt.Sprintf(", %d unread errors", t.unread)
+		c = trayErrors
+	}
+	return text, c
+}
+
+// unreadCount returns the number of unread errors
+func (t *TrayStatus) unreadCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.unread
+}
+
+// trayIcon draws the -tray icon, a dot of color c, as a PNG or, on Windows,
+// as an icon file holding the PNG
+func trayIcon(c imagecolor.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, trayIconSize, trayIconSize))
+	center, radius := trayIconSize/2, trayIconSize/2-2
+	for y := range trayIconSize {
+		for x := range trayIconSize {
+			if dx, dy := x-center, y-center; dx*dx+dy*dy <= radius*radius {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+	var data bytes.Buffer
+	png.Encode(&data, img)
+	if runtime.GOOS != "windows" {
+		return data.Bytes()
+	}
+
+	// ICONDIR, then one ICONDIRENTRY pointing at the PNG after them
+	var ico bytes.Buffer
+	binary.Write(&ico, binary.LittleEndian, []uint16{0, 1, 1})
+	ico.Write([]byte{trayIconSize, trayIconSize, 0, 0})
+	binary.Write(&ico, binary.LittleEndian, []uint16{1, 32})
+	binary.Write(&ico, binary.LittleEndian, []uint32{uint32(data.Len()), 6 + 16})
+	ico.Write(data.Bytes())
+	return ico.Bytes()
+}
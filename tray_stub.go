@@ -0,0 +1,15 @@
+//go:build !linux && !windows && (!darwin || !cgo)
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// runWithTray runs run without a tray icon, which is only supported on
+// Linux, Windows and, in builds with cgo, macOS
+func runWithTray(status *TrayStatus, run func()) {
+	fmt.Fprint(os.Stderr, LogLevelColors["W"]("-tray is not available on this platform or in this build; running without it\n"))
+	run()
+}
@@ -0,0 +1,45 @@
+//go:build linux || windows || (darwin && cgo)
+
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/systray"
+)
+
+// runWithTray shows the -tray icon and menu while run runs, keeping the
+// calling goroutine, which must be the main one on macOS, for the tray
+func runWithTray(status *TrayStatus, run func()) {
+	systray.Run(func() {
+		systray.SetIcon(trayIcon(trayDisconnected))
+		systray.SetTooltip("logcatcolor")
+		state := systray.AddMenuItem("Disconnected", "")
+		state.Disable()
+		markRead := systray.AddMenuItem("Mark errors read", "Reset the count of unread errors")
+		systray.AddSeparator()
+		quit := systray.AddMenuItem("Quit", "Stop logcatcolor")
+
+		go func() {
+			for {
+				select {
+				case <-status.changed:
+					text, c := status.describe()
+					state.SetTitle(text)
+					systray.SetTooltip("logcatcolor: " + text)
+					systray.SetIcon(trayIcon(c))
+					if n := status.unreadCount(); n > 0 {
+						systray.SetTitle(fmt.Sprint(n))
+					} else {
+						systray.SetTitle("")
+					}
+				case <-markRead.ClickedCh:
+					status.MarkRead()
+				case <-quit.ClickedCh:
+					requestShutdown()
+				}
+			}
+		}()
+		go run()
+	}, nil)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// perUserRange is the number of UIDs reserved for each Android user
+const perUserRange = 100000
+
+// firstAppID and firstIsolatedID start the ranges of application and
+// isolated process app IDs
+const (
+	firstAppID      = 10000
+	firstIsolatedID = 99000
+)
+
+// aidNames maps the names logcat prints for fixed Android IDs to their values
+var aidNames = map[string]int{
+	"root": 0, "system": 1000, "radio": 1001, "bluetooth": 1002, "graphics": 1003,
+	"input": 1004, "audio": 1005, "camera": 1006, "log": 1007, "compass": 1008,
+	"mount": 1009, "wifi": 1010, "adb": 1011, "install": 1012, "media": 1013,
+	"dhcp": 1014, "sdcard_rw": 1015, "vpn": 1016, "keystore": 1017, "usb": 1018,
+	"drm": 1019, "mdnsr": 1020, "gps": 1021, "media_rw": 1023, "mtp": 1024,
+	"nfc": 1027, "shell": 2000, "cache": 2001, "diag": 2002, "nobody": 9999,
+}
+
+// userUIDPattern matches per-user names such as "u0_a123", "u10_i4" and "u10_system"
+var userUIDPattern = regexp.MustCompile(`^u(\d+)_(?:a(\d+)|i(\d+)|(\w+))$`)
+
+// parseUID converts a UID as printed by logcat -v uid into its number
+func parseUID(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, true
+	}
+	if n, ok := aidNames[s]; ok {
+		return n, true
+	}
+	m := userUIDPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	user, _ := strconv.Atoi(m[1])
+	switch {
+	case m[2] != "":
+		app, _ := strconv.Atoi(m[2])
+		return user*perUserRange + firstAppID + app, true
+	case m[3] != "":
+		isolated, _ := strconv.Atoi(m[3])
+		return user*perUserRange + firstIsolatedID + isolated, true
+	}
+	aid, ok := aidNames[m[4]]
+	return user*perUserRange + aid, ok
+}
+
+// matchesUID reports whether a line's UID passes the -uid, -user and
+// -apps-only filters. Filters may be UIDs, app IDs matching every user, or
+// package names.
+func matchesUID(uidText string, opts LogcatOptions) bool {
+	if len(opts.UIDFilters) == 0 && opts.User < 0 && !opts.AppsOnly {
+		return true
+	}
+	uid, ok := parseUID(uidText)
+	if !ok {
+		return false
+	}
+	if opts.User >= 0 && uid/perUserRange != opts.User {
+		return false
+	}
+	if opts.AppsOnly && !opts.AppIDs[uid%perUserRange] {
+		return false
+	}
+	if len(opts.UIDFilters) == 0 {
+		return true
+	}
+	for _, filter := range opts.UIDFilters {
+		if n, err := strconv.Atoi(filter); err == nil {
+			if uid == n || n < perUserRange && uid%perUserRange == n {
+				return true
+			}
+		} else if opts.UIDNames[uid%perUserRange] == filter {
+			return true
+		}
+	}
+	return false
+}
+
+// uidLabel returns the package name for an application UID, or the UID as
+// logcat printed it
+func uidLabel(uidText string, names map[int]string) string {
+	if uid, ok := parseUID(uidText); ok && uid%perUserRange >= firstAppID {
+		if name, ok := names[uid%perUserRange]; ok {
+			return name
+		}
+	}
+	return uidText
+}
+
+// loadPackageUIDs maps app IDs to package names using pm list packages -U,
+// with extra options such as -3 for third-party packages only. With -user
+// the packages are those of that user, which work profiles install apart.
+func loadPackageUIDs(opts LogcatOptions, extra ...string) (map[int]string, error) {
+	args := append([]string{"shell", "pm", "list", "packages", "-U"}, extra...)
+	if opts.User >= 0 {
+		args = append(args, "--user", strconv.Itoa(opts.User))
+	}
+	out, err := adbCommand(opts, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines look like "package:com.example.app uid:10123", with several
+	// comma-separated UIDs on devices with more than one user
+	names := make(map[int]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		pkg, uids, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " uid:")
+		if !ok {
+			continue
+		}
+		pkg = strings.TrimPrefix(pkg, "package:")
+		for _, s := range strings.Split(uids, ",") {
+			if uid, err := strconv.Atoi(s); err == nil {
+				names[uid%perUserRange] = pkg
+			}
+		}
+	}
+	return names, nil
+}
+
+// supportsUIDFormat reports whether the device's logcat accepts -v uid
+func supportsUIDFormat(opts LogcatOptions) bool {
+	_, err := adbCommand(opts, "logcat", "-d", "-t", "1", "-v", "uid").Output()
+	return err == nil
+}
+
+// prepareUIDColumn checks that the device can print UIDs and loads the
+// package names to show for them, disabling the column if it cannot
+func prepareUIDColumn(opts *LogcatOptions) {
+	if !supportsUIDFormat(*opts) {
+		fmt.Fprint(os.Stderr, LogLevelColors["W"]("This device's logcat does not support -v uid; UID column and filters are disabled\n"))
+		opts.UIDColumn, opts.UIDFilters, opts.User, opts.AppsOnly = false, nil, -1, false
+		return
+	}
+	names, err := loadPackageUIDs(*opts)
+	if err != nil {
+		fmt.Fprint(os.Stderr, LogLevelColors["W"]("Could not list package UIDs: %v\n", err))
+	} else {
+		opts.UIDNames = names
+	}
+
+	if opts.AppsOnly {
+		apps, err := loadPackageUIDs(*opts, "-3")
+		if err != nil {
+			fmt.Fprint(os.Stderr, LogLevelColors["W"]("Could not list third-party packages; showing all processes: %v\n", err))
+			opts.AppsOnly = false
+			return
+		}
+		opts.AppIDs = make(map[int]bool, len(apps))
+		for id := range apps {
+			opts.AppIDs[id] = true
+		}
+	}
+}
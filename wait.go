@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// spinnerFrames are drawn in turn while waiting
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// waitForDevice blocks until the selected device is attached or ctx is
+// canceled, showing a spinner on terminals
+func waitForDevice(ctx context.Context, opts LogcatOptions) error {
+	cmd := adbCommand(opts, "wait-for-device")
+	stderr, err := pipeStderr(cmd)
+	if err != nil {
+		return err
+	}
+	if err := startChild(cmd); err != nil {
+		return classifyADBError(err, nil)
+	}
+	stop := context.AfterFunc(ctx, func() { terminateProcess(cmd) })
+	defer stop()
+
+	done, cleared := make(chan struct{}), make(chan struct{})
+	if isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()) {
+		go func() {
+			defer close(cleared)
+			ticker := time.NewTicker(100 * time.Millisecond)
+			defer ticker.Stop()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					fmt.Fprint(os.Stderr, "\r\x1b[K")
+					return
+				case <-ticker.C:
+					fmt.Fprintf(os.Stderr, "\r%s Waiting for device...", ADBNoticeColor("%s", spinnerFrames[i%len(spinnerFrames)]))
+				}
+			}
+		}()
+	} else {
+		fmt.Fprintln(os.Stderr, "Waiting for device...")
+		close(cleared)
+	}
+
+	stderrLines := stderr.Wait()
+	err = waitChild(cmd)
+	close(done)
+	<-cleared
+	if err != nil && ctx.Err() == nil {
+		return classifyADBError(err, stderrLines)
+	}
+	return nil
+}
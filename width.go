@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// displayWidth returns the number of terminal columns s occupies, counting
+// East Asian wide characters and emoji as two and combining marks as zero
+func displayWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// truncateWidth shortens s to at most width columns
+func truncateWidth(s string, width int) string {
+	return runewidth.Truncate(s, width, "")
+}
+
+// padLeft right-aligns s in a column of width columns
+func padLeft(s string, width int) string {
+	return strings.Repeat(" ", max(width-displayWidth(s), 0)) + s
+}
+
+// padRight left-aligns s in a column of width columns
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-displayWidth(s), 0))
+}